@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The layouts of data files: fields separated by a delimiter (with csv quoting), by runs of
+// spaces and tabs (with double quotes around fields that hold spaces), or in fixed-width columns.
+const (
+	formatDelimited  = "csv"
+	formatWhitespace = "whitespace"
+	formatFixed      = "fixed"
+)
+
+// Whether the first line of a data file names the columns. Auto takes it for a header if any of
+// its fields isn't a number.
+const (
+	headerAuto = "auto"
+	headerYes  = "yes"
+	headerNo   = "no"
+)
+
+// What to do with a row that can't be split or parsed: fail with its error, skip it, or skip it
+// and collect its error to report it with the others.
+const (
+	badRowsFail    = "fail"
+	badRowsSkip    = "skip"
+	badRowsCollect = "collect"
+)
+
+// dataFormat says how to split the lines of a data file into fields.
+type dataFormat struct {
+	Layout    string // formatDelimited, formatWhitespace or formatFixed
+	Delimiter string // of formatDelimited, a single character
+	Widths    []int  // of the formatFixed columns, inferred from the columns blank on every line if nil
+	Header    string // headerAuto, headerYes or headerNo
+	Comment   string // lines starting with it are ignored, none if empty
+	BadRows   string // badRowsFail (the default), badRowsSkip or badRowsCollect
+}
+
+// lineError is a problem with a line of a data file.
+type lineError struct {
+	File string
+	Line int    // counting from 1
+	Text string // the whole line
+	Err  error
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v in %q", e.File, e.Line, e.Err, e.Text)
+}
+
+// row is the fields of a line of a data file.
+type row struct {
+	Line   int
+	Text   string
+	Fields []string
+}
+
+// table is what readData makes of a data file. Rows dropped by the skip and collect policies
+// are taken out of Rows as they are found, with their errors in Bad for the collect policy.
+type table struct {
+	File    string
+	Header  []string
+	Rows    []row
+	Bad     []*lineError
+	badRows string
+}
+
+// readData reads the data file ("-" meaning stdin) as fields, skipping blank lines and comments.
+// Without a header the columns are named by their index.
+func readData(filename string, format dataFormat) (*table, error) {
+	switch format.Layout {
+	case formatDelimited:
+		if len([]rune(format.Delimiter)) != 1 {
+			return nil, fmt.Errorf("delimiter %q is not a single character", format.Delimiter)
+		}
+	case formatWhitespace, formatFixed:
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected %s, %s or %s", format.Layout, formatDelimited, formatWhitespace, formatFixed)
+	}
+	switch format.Header {
+	case headerAuto, headerYes, headerNo:
+	default:
+		return nil, fmt.Errorf("unknown header %q, expected %s, %s or %s", format.Header, headerAuto, headerYes, headerNo)
+	}
+	t := &table{File: filename, badRows: format.BadRows}
+	switch format.BadRows {
+	case "":
+		t.badRows = badRowsFail
+	case badRowsFail, badRowsSkip, badRowsCollect:
+	default:
+		return nil, fmt.Errorf("unknown bad rows policy %q, expected %s, %s or %s", format.BadRows, badRowsFail, badRowsSkip, badRowsCollect)
+	}
+
+	var in io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// collect the lines that aren't blank or comments
+	var lines []row
+	scanner := bufio.NewScanner(in)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || format.Comment != "" && strings.HasPrefix(trimmed, format.Comment) {
+			continue
+		}
+		lines = append(lines, row{Line: n, Text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%s: empty file", filename)
+	}
+
+	// split each line into fields
+	widths := format.Widths
+	if format.Layout == formatFixed && widths == nil {
+		texts := make([]string, len(lines))
+		for i, line := range lines {
+			texts[i] = line.Text
+		}
+		widths = inferWidths(texts)
+	}
+	for i, line := range lines {
+		var err error
+		switch format.Layout {
+		case formatDelimited:
+			line.Fields, err = splitDelimited(line.Text, []rune(format.Delimiter)[0])
+		case formatWhitespace:
+			line.Fields, err = splitWhitespace(line.Text)
+		case formatFixed:
+			line.Fields = splitFixed(line.Text, widths)
+		}
+		if err != nil {
+			lineErr := &lineError{File: filename, Line: line.Line, Text: line.Text, Err: err}
+			if i == 0 && format.Header != headerNo {
+				return nil, lineErr // even the header, or what may be one, is broken
+			}
+			if err := t.drop(lineErr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		t.Rows = append(t.Rows, line)
+	}
+
+	if len(t.Rows) == 0 {
+		return nil, fmt.Errorf("%s: no line could be split into fields", filename)
+	}
+
+	// the header names the columns
+	hasHeader := format.Header == headerYes
+	if format.Header == headerAuto {
+		for _, field := range t.Rows[0].Fields {
+			if _, err := strconv.ParseFloat(field, 64); err != nil {
+				hasHeader = true
+			}
+		}
+	}
+	if hasHeader {
+		t.Header, t.Rows = t.Rows[0].Fields, t.Rows[1:]
+		return t, nil
+	}
+	t.Header = make([]string, len(t.Rows[0].Fields))
+	for i := range t.Header {
+		t.Header[i] = strconv.Itoa(i)
+	}
+	return t, nil
+}
+
+// drop applies the bad rows policy to a row with an error, returning the error if it fails.
+func (t *table) drop(err *lineError) error {
+	switch t.badRows {
+	case badRowsSkip:
+		return nil
+	case badRowsCollect:
+		t.Bad = append(t.Bad, err)
+		return nil
+	}
+	return err
+}
+
+// splitDelimited splits the line on the delimiter, fields may be quoted as in csv.
+func splitDelimited(line string, delimiter rune) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	if err != nil {
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			err = parseErr.Err // the line number is the reader's own
+		}
+		return nil, err
+	}
+	return fields, nil
+}
+
+// splitWhitespace splits the line on runs of spaces and tabs. A field in double quotes may hold
+// spaces, and two double quotes stand for one inside it.
+func splitWhitespace(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			var field strings.Builder
+			for i++; ; i++ {
+				if i == len(line) {
+					return nil, fmt.Errorf("unterminated quoted field")
+				}
+				if line[i] == '"' {
+					if i+1 < len(line) && line[i+1] == '"' {
+						i++
+					} else {
+						break
+					}
+				}
+				field.WriteByte(line[i])
+			}
+			i++
+			if i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				return nil, fmt.Errorf("unexpected %q after quoted field", line[i])
+			}
+			fields = append(fields, field.String())
+		default:
+			end := strings.IndexAny(line[i:], " \t")
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+		}
+	}
+	return fields, nil
+}
+
+// inferWidths finds the widths of fixed-width columns as runs of characters that are blank on
+// every line, each column ending where the next one starts. The last column takes the rest.
+func inferWidths(lines []string) []int {
+	var used []bool
+	for _, line := range lines {
+		for i, c := range line {
+			for len(used) <= i {
+				used = append(used, false)
+			}
+			if c != ' ' && c != '\t' {
+				used[i] = true
+			}
+		}
+	}
+
+	var widths []int
+	start := 0
+	for i := 1; i < len(used); i++ {
+		if used[i] && !used[i-1] {
+			widths = append(widths, i-start)
+			start = i
+		}
+	}
+	return widths
+}
+
+// splitFixed cuts the line into columns of the given widths and a last one with the rest,
+// trimming the spaces around every field.
+func splitFixed(line string, widths []int) []string {
+	fields := make([]string, 0, len(widths)+1)
+	for _, width := range widths {
+		if width > len(line) {
+			width = len(line)
+		}
+		fields = append(fields, strings.TrimSpace(line[:width]))
+		line = line[width:]
+	}
+	return append(fields, strings.TrimSpace(line))
+}
+
+// columnIndex finds the named column of the header, or the column at the given index.
+func columnIndex(header []string, name string) (int, error) {
+	for i, h := range header {
+		if h == name {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(header) {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no column %q in %v", name, header)
+}
+
+// parse parses the given columns of every row as floats. Rows with a missing column or
+// something else than a number in one are handled by the bad rows policy, a dropped row
+// leaving the table for good so that columns parsed later line up.
+func (t *table) parse(indices []int) ([][]float64, error) {
+	var result [][]float64
+	var kept []row
+	for _, r := range t.Rows {
+		values, err := parseFields(r.Fields, indices)
+		if err != nil {
+			if err := t.drop(&lineError{File: t.File, Line: r.Line, Text: r.Text, Err: err}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		result = append(result, values)
+		kept = append(kept, r)
+	}
+	t.Rows = kept
+	return result, nil
+}
+
+// parseFields parses the fields at the given indices as floats.
+func parseFields(fields []string, indices []int) ([]float64, error) {
+	values := make([]float64, len(indices))
+	for j, index := range indices {
+		if index >= len(fields) {
+			return nil, fmt.Errorf("missing column %d of %d", index+1, len(fields))
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(fields[index]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %q is not a number", index+1, fields[index])
+		}
+		values[j] = x
+	}
+	return values, nil
+}
+
+// numericColumn checks if some row has a number in the column, rows without one being left
+// to the bad rows policy.
+func (t *table) numericColumn(index int) bool {
+	for _, r := range t.Rows {
+		if _, err := parseFields(r.Fields, []int{index}); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFlags defines the flags describing the format of a data file on the flag set, and returns
+// a function making a dataFormat of their values once parsed.
+func formatFlags(fs *flag.FlagSet) func() (dataFormat, error) {
+	layout := fs.String("format", formatDelimited, "layout of the data file: csv, whitespace or fixed")
+	delimiter := fs.String("delimiter", ",", "field delimiter of the csv format")
+	widths := fs.String("widths", "", "comma separated widths of the fixed format columns, inferred if empty")
+	header := fs.String("header", headerAuto, "whether the first line names the columns: auto, yes or no")
+	comment := fs.String("comment", "", "prefix of the lines to ignore, such as #")
+	badRows := fs.String("bad-rows", badRowsFail, "what to do with rows that can't be parsed: fail, skip, or collect to list them")
+
+	return func() (dataFormat, error) {
+		format := dataFormat{Layout: *layout, Delimiter: *delimiter, Header: *header, Comment: *comment, BadRows: *badRows}
+		if *widths != "" {
+			for _, w := range strings.Split(*widths, ",") {
+				width, err := strconv.Atoi(strings.TrimSpace(w))
+				if err != nil || width <= 0 {
+					return dataFormat{}, fmt.Errorf("invalid column width %q", w)
+				}
+				format.Widths = append(format.Widths, width)
+			}
+		}
+		return format, nil
+	}
+}
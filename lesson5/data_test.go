@@ -0,0 +1,235 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFile writes the content to a file in a new temporary directory, removed by the returned func.
+func writeFile(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "lr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "data.txt")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestReadData(t *testing.T) {
+	var tests = []struct {
+		content        string
+		format         dataFormat
+		expectedHeader []string
+		expectedRows   [][]string
+	}{
+		// csv with a header and a quoted field
+		{
+			"Country,Life expectancy,BMI\n\"Congo, Rep.\",58.8,21.87\nLaos,65.3,21.07\n",
+			dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto},
+			[]string{"Country", "Life expectancy", "BMI"},
+			[][]string{{"Congo, Rep.", "58.8", "21.87"}, {"Laos", "65.3", "21.07"}},
+		},
+		// numbers only, the columns are named by index
+		{
+			"1;2\n3;4\n",
+			dataFormat{Layout: formatDelimited, Delimiter: ";", Header: headerAuto},
+			[]string{"0", "1"},
+			[][]string{{"1", "2"}, {"3", "4"}},
+		},
+		// a header of numbers
+		{
+			"2017,2018\n3,4\n",
+			dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerYes},
+			[]string{"2017", "2018"},
+			[][]string{{"3", "4"}},
+		},
+		// runs of spaces and tabs, with comments and blank lines
+		{
+			"# brains and bodies\nBrain  Body\n  3.385 \t 44.5\n# more\n\n  \n0.48   15.5\n\n",
+			dataFormat{Layout: formatWhitespace, Header: headerAuto, Comment: "#"},
+			[]string{"Brain", "Body"},
+			[][]string{{"3.385", "44.5"}, {"0.48", "15.5"}},
+		},
+		// quoted fields with spaces and quotes
+		{
+			"name value\n\"New York\" 3\n\"say \"\"hi\"\"\" 4\n",
+			dataFormat{Layout: formatWhitespace, Header: headerAuto},
+			[]string{"name", "value"},
+			[][]string{{"New York", "3"}, {"say \"hi\"", "4"}},
+		},
+		// windows line endings
+		{
+			"x,y\r\n1,2\r\n",
+			dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto},
+			[]string{"x", "y"},
+			[][]string{{"1", "2"}},
+		},
+		// fixed width columns, inferred
+		{
+			"Brain        Body\n    3.385    44.500\n  465.000   423.000\n",
+			dataFormat{Layout: formatFixed, Header: headerAuto},
+			[]string{"Brain", "Body"},
+			[][]string{{"3.385", "44.500"}, {"465.000", "423.000"}},
+		},
+		// fixed width columns that touch
+		{
+			"ab 12345\ncd 67890\n",
+			dataFormat{Layout: formatFixed, Widths: []int{3, 2}, Header: headerNo},
+			[]string{"0", "1", "2"},
+			[][]string{{"ab", "12", "345"}, {"cd", "67", "890"}},
+		},
+	}
+
+	for _, test := range tests {
+		path, cleanup := writeFile(t, test.content)
+		data, err := readData(path, test.format)
+		cleanup()
+		if err != nil {
+			t.Errorf("readData(%q, %+v) unexpected error: %v", test.content, test.format, err)
+			continue
+		}
+		if rows := fields(data); !reflect.DeepEqual(test.expectedHeader, data.Header) || !reflect.DeepEqual(test.expectedRows, rows) {
+			t.Errorf("readData(%q, %+v): expected %q %q, actual %q %q", test.content, test.format,
+				test.expectedHeader, test.expectedRows, data.Header, rows)
+		}
+	}
+}
+
+func TestReadDataErrors(t *testing.T) {
+	var tests = []struct {
+		content string
+		format  dataFormat
+	}{
+		{"", dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto}},
+		{"# only comments\n", dataFormat{Layout: formatWhitespace, Header: headerAuto, Comment: "#"}},
+		{"1,2\n", dataFormat{Layout: formatDelimited, Delimiter: ", ", Header: headerAuto}},
+		{"1,2\n", dataFormat{Layout: "json", Header: headerAuto}},
+		{"1,2\n", dataFormat{Layout: formatDelimited, Delimiter: ",", Header: "maybe"}},
+		{"1,2\n", dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto, BadRows: "ignore"}},
+		{"a,\"b\n1,2\n", dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto, BadRows: badRowsSkip}},
+		{"a,\"b\n", dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerNo, BadRows: badRowsSkip}},
+	}
+
+	for _, test := range tests {
+		path, cleanup := writeFile(t, test.content)
+		_, err := readData(path, test.format)
+		cleanup()
+		if err == nil {
+			t.Errorf("readData(%q, %+v): expected err != nil", test.content, test.format)
+		}
+	}
+}
+
+func TestReadBrainBody(t *testing.T) {
+	for _, layout := range []string{formatFixed, formatWhitespace} {
+		data, err := readData("brain_body.txt", dataFormat{Layout: layout, Header: headerAuto})
+		if err != nil {
+			t.Fatalf("readData(brain_body.txt, %s) unexpected error: %v", layout, err)
+		}
+		if !reflect.DeepEqual([]string{"Brain", "Body"}, data.Header) || len(data.Rows) != 62 {
+			t.Errorf("readData(brain_body.txt, %s): expected [Brain Body] and 62 rows, actual %v and %d", layout, data.Header, len(data.Rows))
+		}
+		brain, err := columnIndex(data.Header, "Brain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := data.parse([]int{brain}); err != nil {
+			t.Errorf("parse(brain_body.txt, %s) unexpected error: %v", layout, err)
+		}
+	}
+}
+
+func TestReadDataBadRows(t *testing.T) {
+	content := "x,y\n1,2\n\n3\n4,\"5\n6,seven\n8,9\n"
+	path, cleanup := writeFile(t, content)
+	defer cleanup()
+	format := dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto}
+
+	// fail on the first bad line, saying where it is and what it holds
+	format.BadRows = badRowsFail
+	_, err := readData(path, format)
+	lineErr, ok := err.(*lineError)
+	if !ok || lineErr.Line != 5 || lineErr.Text != "4,\"5" {
+		t.Errorf("readData(%q, fail): expected an error on line 5 of 4,\"5, actual %v", content, err)
+	}
+
+	// the other bad lines only show up when parsing
+	for _, policy := range []string{badRowsSkip, badRowsCollect} {
+		format.BadRows = policy
+		data, err := readData(path, format)
+		if err != nil {
+			t.Fatalf("readData(%q, %s) unexpected error: %v", content, policy, err)
+		}
+		values, err := data.parse([]int{0, 1})
+		if err != nil {
+			t.Fatalf("parse(%q, %s) unexpected error: %v", content, policy, err)
+		}
+		if expected := [][]float64{{1, 2}, {8, 9}}; !reflect.DeepEqual(expected, values) {
+			t.Errorf("parse(%q, %s): expected %v, actual %v", content, policy, expected, values)
+		}
+		var lines []int
+		for _, err := range data.Bad {
+			lines = append(lines, err.Line)
+		}
+		if expected := []int{5, 4, 6}; policy == badRowsCollect && !reflect.DeepEqual(expected, lines) {
+			t.Errorf("parse(%q, collect): expected bad lines %v, actual %v", content, expected, lines)
+		} else if policy == badRowsSkip && lines != nil {
+			t.Errorf("parse(%q, skip): expected no bad lines, actual %v", content, lines)
+		}
+	}
+
+	// a row dropped by one parse is gone for the next
+	format.BadRows = badRowsSkip
+	data, _ := readData(path, format)
+	x, _ := data.parse([]int{0})
+	y, _ := data.parse([]int{1})
+	if len(x) != 4 || len(y) != 2 || len(data.Rows) != 2 {
+		t.Errorf("parse: expected 4 rows with an x, then 2 with a y, actual %v %v", x, y)
+	}
+
+	path, cleanup = writeFile(t, "x,y\n1,2\n6,seven\n")
+	defer cleanup()
+	format.BadRows = badRowsFail
+	data, _ = readData(path, format)
+	if _, err := data.parse([]int{0, 1}); err == nil || err.Error() != data.File+":3: column 2: \"seven\" is not a number in \"6,seven\"" {
+		t.Errorf("parse: expected an error on line 3, actual %v", err)
+	}
+
+	// without a header the first line is a row like the others
+	path, cleanup = writeFile(t, "4,\"5\n1,2\n3,4\n")
+	defer cleanup()
+	format.Header = headerNo
+	for _, policy := range []string{badRowsSkip, badRowsCollect} {
+		format.BadRows = policy
+		data, err := readData(path, format)
+		if err != nil {
+			t.Fatalf("readData(no header, %s) unexpected error: %v", policy, err)
+		}
+		if expected := [][]string{{"1", "2"}, {"3", "4"}}; !reflect.DeepEqual(expected, fields(data)) {
+			t.Errorf("readData(no header, %s): expected %v, actual %v", policy, expected, fields(data))
+		}
+		if policy == badRowsCollect && (len(data.Bad) != 1 || data.Bad[0].Line != 1) {
+			t.Errorf("readData(no header, collect): expected line 1 to be bad, actual %v", data.Bad)
+		}
+	}
+	format.BadRows = badRowsFail
+	if _, err := readData(path, format); err == nil {
+		t.Errorf("readData(no header, fail): expected err != nil")
+	}
+}
+
+// fields returns the fields of every row of the table.
+func fields(data *table) [][]string {
+	var result [][]string
+	for _, r := range data.Rows {
+		result = append(result, r.Fields)
+	}
+	return result
+}
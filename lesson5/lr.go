@@ -1,121 +1,445 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
-	"strconv"
 	"strings"
+	"time"
+
+	"../lesson7/ml"
+)
+
+// modelFile is where the fitted model is saved.
+const modelFile = "model.json"
+
+// The model is fitted by gradient descent or by solving the normal equations through QR, or
+// robustly against outliers by minimizing the Huber loss or the absolute deviations, or by
+// fitting the inliers of RANSAC.
+const (
+	solverGD     = "gd"
+	solverQR     = "qr"
+	solverHuber  = "huber"
+	solverLAD    = "lad"
+	solverRANSAC = "ransac"
 )
 
-type point struct {
-	x, y float64
+// predict computes x·w + b for every row of x, as a column.
+func predict(b float64, w, x [][]float64) [][]float64 {
+	return ml.AddRow(ml.Dot(x, w), []float64{b})
 }
 
-func readCsv(filename string, delimiter string) ([]point, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// computeError returns the mean squared error of the predictions against the targets y.
+func computeError(b float64, w, x, y [][]float64) float64 {
+	var regError float64
+
+	// sum all the errors
+	for _, row := range ml.Sub(predict(b, w, x), y) {
+		regError += math.Pow(row[0], 2)
 	}
-	defer f.Close()
 
-	// parse each line as a data point
-	var points []point
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.Split(scanner.Text(), delimiter)
+	// return the average error
+	return regError / float64(len(x))
+}
 
-		// parse x as float
-		x, err := strconv.ParseFloat(line[0], 64)
-		if err != nil {
-			return nil, err
-		}
+// gradientDescent takes up to numIterations steps, fewer if the convergence criteria (if any) are
+// met first or the error diverges, and returns where it ended and why it stopped there.
+func gradientDescent(x, y [][]float64, b float64, w [][]float64, schedule *ml.Schedule, penalty *ml.Penalty, numIterations int, convergence *ml.Convergence) (newB float64, newW [][]float64, result ml.FitResult) {
+	start := time.Now()
+	if convergence != nil {
+		convergence.Start()
+	}
+	result.Reason = ml.StopMaxSteps
+	for result.Steps < numIterations {
+		// update b and w with better values
+		gradientB, gradientW := gradient(b, w, x, y, penalty)
+		newB, newW = stepGradient(b, w, gradientB, gradientW, schedule.LearnRate(result.Steps))
+		result.Steps++
 
-		// parse y as float
-		y, err := strconv.ParseFloat(line[1], 64)
-		if err != nil {
-			return nil, err
+		// and stop once they settle, or blow up
+		var gradNorm, change float64
+		if convergence != nil {
+			gradNorm = ml.Norm(append([][]float64{{gradientB}}, gradientW...))
+			change = ml.RelativeChange(append([][]float64{{b}}, w...), append([][]float64{{newB}}, newW...))
+		}
+		b, w = newB, newW
+		if reason := convergence.Check(computeError(b, w, x, y)+penalty.Loss(w), gradNorm, change); reason != "" {
+			result.Reason = reason
+			break
 		}
-
-		// create a new point
-		pt := point{x, y}
-		points = append(points, pt)
 	}
-
-	return points, scanner.Err()
+	result.Loss, result.Elapsed = computeError(b, w, x, y), time.Since(start)
+	return b, w, result
 }
 
-func computeError(b, m float64, points []point) float64 {
-	var regError float64
+// gradient returns the partial derivatives of the mean squared error plus the penalty on the
+// weights with respect to b and w, the intercept is not regularized.
+func gradient(b float64, w, x, y [][]float64, penalty *ml.Penalty) (gradientB float64, gradientW [][]float64) {
+	var n = float64(len(x))
 
-	// sum all the errors
-	for i := 0; i < len(points); i++ {
-		initialX := points[i].x
-		initialY := points[i].y
-		predictedY := m*initialX + b
-		regError += math.Pow(initialY-predictedY, 2)
-	}
+	residuals := ml.Sub(predict(b, w, x), y)
+	gradientB = 2.0 / n * ml.ColumnSums(residuals)[0]
+	gradientW = ml.Scale(ml.Dot(ml.T(x), residuals), 2.0/n)
+	gradientW = ml.Add(gradientW, penalty.Grad(w))
+	return
+}
 
-	// return the average error
-	return regError / float64(len(points))
+// stepGradient takes one step of the given size against the gradient.
+func stepGradient(b float64, w [][]float64, gradientB float64, gradientW [][]float64, learningRate float64) (newB float64, newW [][]float64) {
+	newB = b - learningRate*gradientB
+	newW = ml.Sub(w, ml.Scale(gradientW, learningRate))
+	return
 }
 
-func gradientDescent(points []point, b, m float64, learningRate float64, numIterations int) (newB, newM float64) {
-	for i := 0; i < numIterations; i++ {
-		// update b and m with better values
-		b, m = stepGradient(b, m, points, learningRate)
+// solveLeastSquares finds the b and w that gradient descent converges to, exactly. The mean
+// squared error plus L2·|w|²/2 has the same minimum as the sum of squares plus n·L2/2·|w|².
+func solveLeastSquares(x, y [][]float64, penalty *ml.Penalty) (b float64, w [][]float64, err error) {
+	if penalty.L1 != 0 {
+		return 0, nil, fmt.Errorf("the L1 penalty has no closed form solution, use -solver %s", solverGD)
 	}
-	return b, m
+	w, intercepts, err := ml.FitLinear(x, y, float64(len(x))*penalty.L2/2)
+	if err != nil {
+		return 0, nil, err
+	}
+	return intercepts[0], w, nil
 }
 
-func stepGradient(b, m float64, points []point, learningRate float64) (newB, newM float64) {
-	var n = float64(len(points))
-
-	var gradientB float64
-	var gradientM float64
+// fitBasis makes the basis expanding the features into a polynomial of the given degree, or a
+// spline with knots at quantiles of every feature, after an optional transform of every feature.
+// It returns nil if the features are to be used as they are.
+func fitBasis(x [][]float64, transform string, degree, numKnots int) (*ml.Basis, error) {
+	if degree < 0 || numKnots < 0 {
+		return nil, fmt.Errorf("negative degree %d or number of knots %d", degree, numKnots)
+	}
+	if degree == 0 {
+		degree = 1
+		if numKnots > 0 {
+			degree = 3
+		}
+	}
+	if transform == "" && degree == 1 && numKnots == 0 {
+		return nil, nil
+	}
 
-	// compute direction with respect to b and m
-	for i := 0; i < len(points); i++ {
-		x := points[i].x
-		y := points[i].y
-		calculatedY := m*x + b
-		// partial derivatives of the error function (whatever that is)
-		gradientB += -(2.0 / n) * (y - calculatedY)
-		gradientM += -(2.0 / n) * x * (y - calculatedY)
+	basis := &ml.Basis{Degree: degree}
+	for j, column := range ml.T(x) {
+		if transform != "" {
+			t, err := ml.NewTransform(transform, column)
+			if err != nil {
+				return nil, fmt.Errorf("feature %d: %v", j+1, err)
+			}
+			basis.Transforms = append(basis.Transforms, t)
+			column, _ = t.Apply(column)
+		}
+		if numKnots > 0 {
+			basis.Knots = append(basis.Knots, ml.SplineKnots(column, numKnots))
+		}
 	}
+	return basis, nil
+}
 
-	// new values
-	newB = b - learningRate*gradientB
-	newM = m - learningRate*gradientM
+// printSummary prints the coefficients of the regression summary as a table, then its fit statistics.
+func printSummary(out io.Writer, s *ml.RegressionSummary) {
+	lower, upper := fmt.Sprintf("[%.4g", (1-s.Level)/2), fmt.Sprintf("%.4g]", (1+s.Level)/2)
+	fmt.Fprintf(out, "%-16s %12s %12s %9s %9s %12s %12s\n", "", "coef", "std err", "t", "P>|t|", lower, upper)
+	for _, c := range s.Coefficients {
+		fmt.Fprintf(out, "%-16s %12.6g %12.6g %9.3f %9.3g %12.6g %12.6g\n", c.Name, c.Value, c.StdErr, c.T, c.P, c.Lower, c.Upper)
+	}
+	fmt.Fprintf(out, "R-squared: %.4f, adjusted: %.4f\n", s.RSquared, s.AdjRSquared)
+	fmt.Fprintf(out, "F-statistic: %.6g on %d and %d degrees of freedom, p-value: %.4g\n", s.F, s.DFModel, s.DFResid, s.FP)
+	fmt.Fprintf(out, "Durbin-Watson: %.4f\n", s.DurbinWatson)
+	fmt.Fprintf(out, "Jarque-Bera: %.4f, p-value: %.4g (skewness %.4f, kurtosis %.4f)\n", s.JarqueBera, s.JarqueBeraP, s.Skewness, s.Kurtosis)
+}
 
-	return
+// fail reports an error in the data or the files lr was given and exits.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "lr:", err)
+	os.Exit(1)
 }
 
 func main() {
+	// lr predict applies a saved model, lr alone fits one
+	if len(os.Args) > 1 && os.Args[1] == "predict" {
+		if err := predictCommand(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "lr predict:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// hyperparameters
+	dataFile := flag.String("data", "data.csv", "data file of the feature and target columns")
+	readFormat := formatFlags(flag.CommandLine)
+	modelPath := flag.String("model", modelFile, "file to save the fitted model to")
+	target := flag.String("target", "", "name or index of the target column (default the last one)")
+	features := flag.String("features", "", "comma separated names or indices of the feature columns (default the other numeric ones)")
+	solver := flag.String("solver", solverGD, "gd to fit by gradient descent, qr to solve the least squares exactly, or huber, lad or ransac to fit robustly against outliers")
+	delta := flag.Float64("delta", ml.HuberDelta, "residual in robust standard deviations beyond which the huber loss is linear")
+	threshold := flag.Float64("threshold", 0, "largest absolute residual of a ransac inlier (default the median absolute deviation of the target)")
+	trials := flag.Int("trials", 100, "number of random samples ransac tries")
+	seed := flag.Uint64("seed", 1, "seed of the ransac samples")
+	summary := flag.Bool("summary", true, "print the standard errors, p-values and confidence intervals of the coefficients and residual diagnostics, skipped for penalized fits and gradient descent that has not converged")
+	level := flag.Float64("level", 0.95, "confidence level of the intervals of the summary")
+	degree := flag.Int("degree", 0, "degree of the polynomial of every feature, or of the pieces of a spline (default 1, or 3 with -knots)")
+	numKnots := flag.Int("knots", 0, "number of knots of a regression spline of every feature, at quantiles of its values")
+	transform := flag.String("transform", "", "transform of the features before expanding them: log or box_cox")
+	targetTransform := flag.String("target-transform", "", "transform of the target: log or box_cox, predictions being transformed back")
+	standardize := flag.Bool("standardize", false, "standardize the features before fitting")
+	learningRate := flag.Float64("lr", 0.0001, "learning rate, or peak learning rate of a schedule")
+	numIterations := flag.Int("iterations", 1000, "number of gradient descent steps")
+	scheduleType := flag.String("schedule", ml.ScheduleConstant, "learning rate schedule: constant, step, exponential, cosine or one_cycle")
+	warmup := flag.Int("warmup", 0, "iterations of linear learning rate warmup")
+	decayStep := flag.Int("decay-step", 0, "iterations between step decays, or of the first cosine cycle")
+	gamma := flag.Float64("gamma", 0, "decay factor of the step and exponential schedules")
+	minRate := flag.Float64("min-lr", 0, "learning rate at the end of a cosine or one_cycle schedule")
+	l1 := flag.Float64("l1", 0, "L1 penalty on the weights")
+	l2 := flag.Float64("l2", 0, "L2 penalty on the weights")
+	gradNorm := flag.Float64("grad-norm", 0, "stop gradient descent once the norm of the gradient is at most this")
+	lossChange := flag.Float64("loss-change", 0, "stop gradient descent once the error changes by at most this fraction in a step")
+	paramChange := flag.Float64("param-change", 0, "stop gradient descent once b and w change by at most this fraction of their norm in a step")
+	timeLimit := flag.Duration("time-limit", 0, "stop gradient descent once it has taken this long, such as 10s")
+	convergencePatience := flag.Int("convergence-patience", 0, "steps in a row the error or parameter change has to stay small, 1 if 0")
+	flag.Parse()
+
+	schedule := &ml.Schedule{
+		Type:    *scheduleType,
+		Rate:    *learningRate,
+		Warmup:  *warmup,
+		Step:    *decayStep,
+		Gamma:   *gamma,
+		MinRate: *minRate,
+		Epochs:  *numIterations,
+	}
+	penalty := &ml.Penalty{L1: *l1, L2: *l2}
+	var convergence *ml.Convergence
+	if *gradNorm != 0 || *lossChange != 0 || *paramChange != 0 || *timeLimit != 0 {
+		convergence = &ml.Convergence{
+			GradNorm:    *gradNorm,
+			LossChange:  *lossChange,
+			ParamChange: *paramChange,
+			TimeLimit:   *timeLimit,
+			Patience:    *convergencePatience,
+		}
+	}
+	for _, err := range []error{schedule.Validate(), penalty.Validate(), convergence.Validate()} {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
 	// collect data
-	points, err := readCsv("data.csv", ",")
+	format, err := readFormat()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	data, err := readData(*dataFile, format)
 	if err != nil {
-		panic(err)
+		fail(err)
+	}
+	header := data.Header
+	targetIndex := len(header) - 1
+	if *target != "" {
+		if targetIndex, err = columnIndex(header, *target); err != nil {
+			fail(err)
+		}
+	}
+	var featureIndices []int
+	if *features == "" {
+		for i := range header {
+			if i != targetIndex && data.numericColumn(i) {
+				featureIndices = append(featureIndices, i)
+			}
+		}
+	} else {
+		for _, name := range strings.Split(*features, ",") {
+			i, err := columnIndex(header, strings.TrimSpace(name))
+			if err != nil {
+				fail(err)
+			}
+			featureIndices = append(featureIndices, i)
+		}
+	}
+	if len(featureIndices) == 0 {
+		fail(fmt.Errorf("%s: no numeric feature columns", *dataFile))
 	}
 
-	// hyperparameters
-	var learningRate = 0.0001
-	var numIterations = 1000
-	var initialB float64
-	var initialM float64
+	// parse the features and the target together, so that a bad row loses both
+	values, err := data.parse(append(featureIndices, targetIndex))
+	if err != nil {
+		fail(err)
+	}
+	for _, err := range data.Bad {
+		fmt.Fprintln(os.Stderr, "skipped", err)
+	}
+	if len(values) == 0 {
+		fail(fmt.Errorf("%s: no rows to fit", *dataFile))
+	}
+	x := make([][]float64, len(values))
+	y := make([][]float64, len(values))
+	for i, v := range values {
+		x[i], y[i] = v[:len(featureIndices)], v[len(featureIndices):]
+	}
+
+	// expand the features into transforms, powers and splines of them, and transform the target
+	columns := make([]ml.Column, len(featureIndices))
+	for i, index := range featureIndices {
+		columns[i].Name = header[index]
+	}
+	basis, err := fitBasis(x, *transform, *degree, *numKnots)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	names := ml.FeatureNames(columns)
+	if basis != nil {
+		if x, err = basis.Expand(x); err != nil {
+			fail(err)
+		}
+		for j, t := range basis.Transforms {
+			if t.Type == ml.TransformBoxCox {
+				fmt.Printf("box_cox transform of %s with lambda=%.4g\n", columns[j].Name, t.Lambda)
+			}
+		}
+		names = basis.Names(names)
+	}
+	var yTransform *ml.Transform
+	if *targetTransform != "" {
+		if yTransform, err = ml.NewTransform(*targetTransform, ml.T(y)[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		transformed, _ := yTransform.Apply(ml.T(y)[0])
+		y = ml.T([][]float64{transformed})
+		if yTransform.Type == ml.TransformBoxCox {
+			fmt.Printf("box_cox transform of %s with lambda=%.4g\n", header[targetIndex], yTransform.Lambda)
+		}
+	}
+
+	var scaler *ml.Scaler
+	if *standardize {
+		scaler = ml.FitScaler(x)
+		x = scaler.Transform(x)
+	}
 
 	// train model
-	initialError := computeError(initialB, initialM, points)
-	fmt.Printf("starting gradient descent at b=%v m=%v error=%v\n", initialB, initialM, initialError)
-	b, m := gradientDescent(points, initialB, initialM, learningRate, numIterations)
-	finalError := computeError(b, m, points)
-	fmt.Printf("ending point at b=%v m=%v error=%v after %v iterations\n", b, m, finalError, numIterations)
-
-	// evaluate model
-	testValues := []float64{2, 10, 20, 30, 50, 60, 80, 100, 200}
-	for i := 0; i < len(testValues); i++ {
-		x := testValues[i]
-		fmt.Printf("%vhours => %v\n", x, m*x+b)
+	var b float64
+	var w [][]float64
+	var finalError float64
+	var unconverged string // why gradient descent stopped short of the least squares solution
+	fitX, fitY := x, y     // the rows the model is fitted to
+	info := ml.TrainingInfo{TrainedAt: time.Now().UTC()}
+	switch *solver {
+	case solverGD:
+		// start with a zero intercept and weight for every feature
+		var initialB float64
+		initialW := ml.FilledMatrix(len(x[0]), 1, 0)
+
+		initialError := computeError(initialB, initialW, x, y)
+		fmt.Printf("starting gradient descent at b=%v w=%v error=%v\n", initialB, ml.T(initialW)[0], initialError)
+		var result ml.FitResult
+		b, w, result = gradientDescent(x, y, initialB, initialW, schedule, penalty, *numIterations, convergence)
+		finalError = result.Loss
+		fmt.Printf("ending point at b=%v w=%v error=%v after %v iterations\n", b, ml.T(w)[0], finalError, result.Steps)
+		if convergence != nil {
+			fmt.Printf("stopped by %v in %v\n", result.Reason, result.Elapsed.Round(time.Millisecond))
+		}
+		info.Epochs, info.LearnRate = result.Steps, *learningRate
+		if result.Reason == ml.StopDiverged {
+			fail(fmt.Errorf("gradient descent diverged after %v iterations, lower -lr or set -standardize", result.Steps))
+		}
+		if !result.Converged() {
+			unconverged = result.Reason
+		}
+	case solverQR:
+		if b, w, err = solveLeastSquares(x, y, penalty); err != nil {
+			fail(err)
+		}
+		finalError = computeError(b, w, x, y)
+		fmt.Printf("least squares solution at b=%v w=%v error=%v\n", b, ml.T(w)[0], finalError)
+	case solverHuber, solverLAD, solverRANSAC:
+		if penalty.L1 != 0 || penalty.L2 != 0 {
+			fmt.Fprintf(os.Stderr, "the %s solver takes no penalty, use -solver %s or %s\n", *solver, solverGD, solverQR)
+			os.Exit(2)
+		}
+		switch *solver {
+		case solverHuber:
+			w, b, err = ml.FitHuber(x, y, *delta)
+		case solverLAD:
+			w, b, err = ml.FitLAD(x, y)
+		case solverRANSAC:
+			ransac := &ml.RANSAC{Threshold: *threshold, Iterations: *trials, Seed: *seed}
+			var inliers []bool
+			if w, b, inliers, err = ransac.Fit(x, y); err != nil {
+				break
+			}
+			fitX, fitY = nil, nil
+			for i, in := range inliers {
+				if in {
+					fitX, fitY = append(fitX, x[i]), append(fitY, y[i])
+				}
+			}
+			fmt.Printf("ransac kept %d inliers of %d rows\n", len(fitX), len(x))
+		}
+		if err != nil {
+			fail(err)
+		}
+		finalError = computeError(b, w, x, y)
+		fmt.Printf("%s solution at b=%v w=%v error=%v\n", *solver, b, ml.T(w)[0], finalError)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown solver %q, expected %s, %s, %s, %s or %s\n", *solver, solverGD, solverQR, solverHuber, solverLAD, solverRANSAC)
+		os.Exit(2)
+	}
+	info.Loss = finalError
+
+	// the spread of the residuals gives the prediction intervals, those of the inliers for
+	// ransac; the weights of huber and lad, like penalized weights and gradient descent that
+	// didn't converge, don't fit the least squares theory behind them
+	var stats *ml.LinearStats
+	switch {
+	case *solver == solverHuber || *solver == solverLAD:
+		fmt.Fprintf(os.Stderr, "no prediction intervals or summary for the %s solver\n", *solver)
+	case penalty.L1 != 0 || penalty.L2 != 0:
+		fmt.Fprintln(os.Stderr, "no prediction intervals or summary: the -l1 and -l2 penalties shrink the coefficients away from the least squares solution")
+	case unconverged != "":
+		fmt.Fprintf(os.Stderr, "no prediction intervals or summary: gradient descent stopped by %s before converging, as it always does without a criterion, set -grad-norm, -loss-change or -param-change, or use -solver %s\n", unconverged, solverQR)
+	default:
+		if stats, err = ml.NewLinearStats(fitX, ml.Sub(predict(b, w, fitX), fitY)); err != nil {
+			fmt.Fprintln(os.Stderr, "no prediction intervals:", err)
+		}
+	}
+
+	// how sure the fit is of every coefficient, and whether the residuals look like noise
+	if *summary && stats != nil {
+		s, err := ml.SummarizeLinear(fitX, fitY, w, b, names, *level)
+		if err != nil {
+			fail(err)
+		}
+		if scaler != nil {
+			fmt.Println("coefficients of the standardized features:")
+		}
+		printSummary(os.Stdout, s)
+	}
+
+	// save the fitted hyperplane as a single linear layer
+	model := &ml.Model{
+		Network: ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: w, Bias: []float64{b}, Activation: ml.ActivationLinear},
+		}},
+		Columns:         columns,
+		Basis:           basis,
+		Scaler:          scaler,
+		Features:        ml.FeatureNames(columns),
+		Targets:         []string{header[targetIndex]},
+		TargetTransform: yTransform,
+		Stats:           stats,
+		Training:        info,
+	}
+	if err := ml.SaveModel(*modelPath, model); err != nil {
+		fail(err)
 	}
+	fmt.Printf("model saved to %v\n", *modelPath)
 }
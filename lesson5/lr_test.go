@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"../lesson7/ml"
+)
+
+// readXY reads the x and y columns of data.csv.
+func readXY(t *testing.T) (x, y [][]float64) {
+	data, err := readData("data.csv", dataFormat{Layout: formatDelimited, Delimiter: ",", Header: headerAuto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, err = data.parse([]int{0}); err != nil {
+		t.Fatal(err)
+	}
+	if y, err = data.parse([]int{1}); err != nil {
+		t.Fatal(err)
+	}
+	return x, y
+}
+
+func TestSolveLeastSquares(t *testing.T) {
+	x, y := readXY(t)
+	x = ml.FitScaler(x).Transform(x)
+
+	// gradient descent on standardized data converges to the exact solution
+	for _, penalty := range []*ml.Penalty{nil, {L2: 0.5}} {
+		schedule := &ml.Schedule{Type: ml.ScheduleConstant, Rate: 0.1}
+		b, w, _ := gradientDescent(x, y, 0, [][]float64{{0}}, schedule, penalty, 2000, nil)
+		if penalty == nil {
+			penalty = &ml.Penalty{}
+		}
+		expectedB, expectedW, err := solveLeastSquares(x, y, penalty)
+		if err != nil {
+			t.Fatal("solveLeastSquares unexpected error:", err)
+		}
+		if math.Abs(b-expectedB) > 1e-9 || !ml.MatrixAlmostEquals(expectedW, w, 1e-9) {
+			t.Errorf("gradientDescent(%+v): expected b=%v w=%v, actual b=%v w=%v", *penalty, expectedB, expectedW, b, w)
+		}
+	}
+}
+
+func TestGradientDescentConvergence(t *testing.T) {
+	x, y := readXY(t)
+	x = ml.FitScaler(x).Transform(x)
+	schedule := &ml.Schedule{Type: ml.ScheduleConstant, Rate: 0.1}
+
+	// without criteria it takes every step, with them it stops once the fit settles
+	_, _, result := gradientDescent(x, y, 0, [][]float64{{0}}, schedule, nil, 500, nil)
+	if result.Reason != ml.StopMaxSteps || result.Steps != 500 {
+		t.Errorf("gradientDescent: expected max_steps after 500, actual %v after %v", result.Reason, result.Steps)
+	}
+	// nor past the step its error blows up in, criteria or not
+	for _, convergence := range []*ml.Convergence{nil, {ParamChange: 1e-9}} {
+		tooLarge := &ml.Schedule{Type: ml.ScheduleConstant, Rate: 10}
+		_, _, result := gradientDescent(x, y, 0, [][]float64{{0}}, tooLarge, nil, 2000, convergence)
+		if result.Reason != ml.StopDiverged || result.Steps >= 2000 {
+			t.Errorf("gradientDescent(rate 10, %+v): expected diverged before 2000 steps, actual %v after %v", convergence, result.Reason, result.Steps)
+		}
+	}
+
+	expectedB, expectedW, err := solveLeastSquares(x, y, &ml.Penalty{})
+	if err != nil {
+		t.Fatal("solveLeastSquares unexpected error:", err)
+	}
+	for _, c := range []struct {
+		convergence *ml.Convergence
+		reason      string
+	}{
+		{&ml.Convergence{GradNorm: 1e-6}, ml.StopGradNorm},
+		{&ml.Convergence{LossChange: 1e-12, Patience: 3}, ml.StopLossChange},
+		{&ml.Convergence{ParamChange: 1e-9}, ml.StopParamChange},
+	} {
+		b, w, result := gradientDescent(x, y, 0, [][]float64{{0}}, schedule, nil, 2000, c.convergence)
+		if result.Reason != c.reason || result.Steps >= 2000 {
+			t.Errorf("gradientDescent(%+v): expected %v before 2000 steps, actual %v after %v", *c.convergence, c.reason, result.Reason, result.Steps)
+		}
+		if math.Abs(b-expectedB) > 1e-3 || !ml.MatrixAlmostEquals(expectedW, w, 1e-3) {
+			t.Errorf("gradientDescent(%+v): expected b=%v w=%v, actual b=%v w=%v", *c.convergence, expectedB, expectedW, b, w)
+		}
+		if result.Loss != computeError(b, w, x, y) {
+			t.Errorf("gradientDescent(%+v): expected loss %v, actual %v", *c.convergence, computeError(b, w, x, y), result.Loss)
+		}
+	}
+}
+
+func TestGradientDescentMultivariate(t *testing.T) {
+	// three features on different scales, y = 3 + 2·x1 - x2 + 0.5·x3 exactly
+	var x, y [][]float64
+	for i := 0; i < 20; i++ {
+		row := []float64{float64(i), float64(i * 7 % 5), 10 * math.Sin(float64(i))}
+		x, y = append(x, row), append(y, []float64{3 + 2*row[0] - row[1] + 0.5*row[2]})
+	}
+	b, w, err := solveLeastSquares(x, y, &ml.Penalty{})
+	if expected := [][]float64{{2}, {-1}, {0.5}}; err != nil || math.Abs(b-3) > 1e-9 || !ml.MatrixAlmostEquals(expected, w, 1e-9) {
+		t.Errorf("solveLeastSquares: expected b=3 w=%v, actual b=%v w=%v (err %v)", expected, b, w, err)
+	}
+
+	// with some noise, gradient descent on the standardized features ends at the same fit
+	for i := range y {
+		y[i][0] += 0.5 * math.Cos(3*float64(i))
+	}
+	x = ml.FitScaler(x).Transform(x)
+	for _, penalty := range []*ml.Penalty{nil, {L2: 0.5}} {
+		schedule := &ml.Schedule{Type: ml.ScheduleConstant, Rate: 0.1}
+		b, w, _ := gradientDescent(x, y, 0, ml.FilledMatrix(3, 1, 0), schedule, penalty, 5000, nil)
+		if penalty == nil {
+			penalty = &ml.Penalty{}
+		}
+		expectedB, expectedW, err := solveLeastSquares(x, y, penalty)
+		if err != nil {
+			t.Fatal("solveLeastSquares unexpected error:", err)
+		}
+		if math.Abs(b-expectedB) > 1e-9 || !ml.MatrixAlmostEquals(expectedW, w, 1e-9) {
+			t.Errorf("gradientDescent(%+v): expected b=%v w=%v, actual b=%v w=%v", *penalty, expectedB, expectedW, b, w)
+		}
+	}
+}
+
+func TestSolveLeastSquaresUnscaled(t *testing.T) {
+	x, y := readXY(t)
+
+	// the default gradient descent stops short of the least squares error
+	schedule := &ml.Schedule{Type: ml.ScheduleConstant, Rate: 0.0001}
+	b, w, _ := gradientDescent(x, y, 0, [][]float64{{0}}, schedule, nil, 1000, nil)
+	exactB, exactW, err := solveLeastSquares(x, y, &ml.Penalty{})
+	if err != nil {
+		t.Fatal("solveLeastSquares unexpected error:", err)
+	}
+	if gd, exact := computeError(b, w, x, y), computeError(exactB, exactW, x, y); exact >= gd {
+		t.Errorf("solveLeastSquares: expected error below gradient descent's %v, actual %v", gd, exact)
+	}
+
+	// the slope and intercept make the residuals sum to 0 and be orthogonal to x
+	residuals := ml.Sub(predict(exactB, exactW, x), y)
+	if sum := ml.ColumnSums(residuals)[0]; math.Abs(sum) > 1e-8 {
+		t.Errorf("solveLeastSquares: expected residuals summing to 0, actual %v", sum)
+	}
+	if dot := ml.Dot(ml.T(x), residuals)[0][0]; math.Abs(dot) > 1e-6 {
+		t.Errorf("solveLeastSquares: expected residuals orthogonal to x, actual %v", dot)
+	}
+
+	if _, _, err := solveLeastSquares(x, y, &ml.Penalty{L1: 1}); err == nil {
+		t.Errorf("solveLeastSquares: expected err != nil for an L1 penalty")
+	}
+}
+
+func TestFitBasis(t *testing.T) {
+	x := [][]float64{{1, 10}, {2, 20}, {3, 30}, {4, 40}, {5, 50}}
+
+	if basis, err := fitBasis(x, "", 0, 0); basis != nil || err != nil {
+		t.Errorf("fitBasis without a transform, degree or knots: expected nil, actual %+v (err %v)", basis, err)
+	}
+	basis, err := fitBasis(x, "", 2, 0)
+	if err != nil || basis.Degree != 2 || basis.Knots != nil || basis.Transforms != nil {
+		t.Errorf("fitBasis(degree 2): expected a quadratic, actual %+v (err %v)", basis, err)
+	}
+
+	// a spline is cubic unless told otherwise, with knots of the transformed features
+	basis, err = fitBasis(x, ml.TransformLog, 0, 1)
+	if err != nil {
+		t.Fatal("fitBasis(log, 1 knot) unexpected error:", err)
+	}
+	expected := [][]float64{{math.Log(3)}, {math.Log(30)}}
+	if basis.Degree != 3 || len(basis.Transforms) != 2 || !ml.MatrixAlmostEquals(expected, basis.Knots, 1e-12) {
+		t.Errorf("fitBasis(log, 1 knot): expected a cubic spline with knots %v, actual %+v", expected, basis)
+	}
+
+	if _, err := fitBasis([][]float64{{1}, {0}}, ml.TransformBoxCox, 0, 0); err == nil {
+		t.Errorf("fitBasis(box_cox) of 0: expected err != nil")
+	}
+	if _, err := fitBasis(x, "", -1, 0); err == nil {
+		t.Errorf("fitBasis(degree -1): expected err != nil")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"../lesson7/ml"
+)
+
+// predictCommand writes the predictions of a saved model for feature values read from a file or
+// stdin, as csv with a header row, optionally followed by the bounds of the prediction intervals.
+func predictCommand(args []string) error {
+	fs := flag.NewFlagSet("predict", flag.ExitOnError)
+	modelPath := fs.String("model", modelFile, "model saved by fitting")
+	dataFile := fs.String("data", "-", "data file of the feature columns, - for stdin")
+	readFormat := formatFlags(fs)
+	outPath := fs.String("out", "-", "csv file to write the predictions to, - for stdout")
+	interval := fs.Float64("interval", 0, "also write the bounds of the interval holding a new observation with this probability, such as 0.95")
+	fs.Parse(args)
+
+	if *interval < 0 || *interval >= 1 {
+		return fmt.Errorf("interval %v is not between 0 and 1", *interval)
+	}
+	model, err := ml.LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	if *interval > 0 && model.Stats == nil {
+		return fmt.Errorf("%s has no statistics for prediction intervals", *modelPath)
+	}
+
+	format, err := readFormat()
+	if err != nil {
+		return err
+	}
+	data, err := readData(*dataFile, format)
+	if err != nil {
+		return err
+	}
+	indices, err := featureIndices(data.Header, model.Features)
+	if err != nil {
+		return err
+	}
+	raw, err := data.parse(indices)
+	if err != nil {
+		return err
+	}
+	for _, err := range data.Bad {
+		fmt.Fprintln(os.Stderr, "skipped", err)
+	}
+	x, err := model.Encode(raw)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	// the prediction, and the interval around it, found for a transformed target before
+	// transforming it back
+	w := csv.NewWriter(out)
+	output := model.Output(x)
+	yHat := model.InvertTargets(output)
+	names := model.Targets
+	if *interval > 0 {
+		scaled := x
+		if model.Scaler != nil {
+			scaled = model.Scaler.Transform(x)
+		}
+		widths := model.Stats.PredictionInterval(scaled, *interval)
+		lower, upper := make([][]float64, len(output)), make([][]float64, len(output))
+		for i := range output {
+			lower[i] = []float64{output[i][0] - widths[i]}
+			upper[i] = []float64{output[i][0] + widths[i]}
+		}
+		lower, upper = model.InvertTargets(lower), model.InvertTargets(upper)
+		for i := range yHat {
+			yHat[i] = append(yHat[i], lower[i][0], upper[i][0])
+		}
+		names = append(names, "lower", "upper")
+	}
+	w.Write(names)
+	for _, row := range yHat {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// featureIndices finds the model's features in the header by name, or by position if the data has
+// as many columns as the model has features but not their names.
+func featureIndices(header, features []string) ([]int, error) {
+	indices := make([]int, len(features))
+	for i, name := range features {
+		index, err := columnIndex(header, name)
+		if err != nil {
+			if len(header) != len(features) {
+				return nil, err
+			}
+			for i := range indices {
+				indices[i] = i
+			}
+			return indices, nil
+		}
+		indices[i] = index
+	}
+	return indices, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"../lesson7/ml"
+)
+
+func TestFeatureIndices(t *testing.T) {
+	var tests = []struct {
+		header, features []string
+		expected         []int
+	}{
+		{[]string{"Country", "BMI"}, []string{"BMI"}, []int{1}},
+		{[]string{"0"}, []string{"BMI"}, []int{0}},
+		{[]string{"b", "a"}, []string{"a", "b"}, []int{1, 0}},
+		{[]string{"0", "1"}, []string{"0"}, []int{0}},
+	}
+
+	for _, test := range tests {
+		actual, err := featureIndices(test.header, test.features)
+		if err != nil || !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("featureIndices(%v, %v): expected %v, actual %v (err %v)", test.header, test.features, test.expected, actual, err)
+		}
+	}
+
+	if _, err := featureIndices([]string{"0", "1"}, []string{"BMI"}); err == nil {
+		t.Errorf("featureIndices: expected err != nil for a missing feature")
+	}
+}
+
+func TestPredictCommand(t *testing.T) {
+	path, cleanup := writeFile(t, "x\n1\n3\n")
+	defer cleanup()
+	dir := filepath.Dir(path)
+
+	// y is about 1 + 2x
+	x := [][]float64{{0}, {1}, {2}, {3}, {4}}
+	y := [][]float64{{1.5}, {2.5}, {5.5}, {6.5}, {9}}
+	b, w, err := solveLeastSquares(x, y, &ml.Penalty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := ml.NewLinearStats(x, ml.Sub(predict(b, w, x), y))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := &ml.Model{
+		Network: ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: w, Bias: []float64{b}, Activation: ml.ActivationLinear},
+		}},
+		Features: []string{"x"},
+		Targets:  []string{"y"},
+		Stats:    stats,
+	}
+	modelPath := filepath.Join(dir, "model.json")
+	if err := ml.SaveModel(modelPath, model); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(dir, "out.csv")
+	if err := predictCommand([]string{"-model", modelPath, "-data", path, "-out", outPath, "-interval", "0.95"}); err != nil {
+		t.Fatal("predictCommand unexpected error:", err)
+	}
+	out, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 || lines[0] != "y,lower,upper" {
+		t.Fatalf("predictCommand: expected a header and 2 rows, actual %q", out)
+	}
+	widths := stats.PredictionInterval([][]float64{{1}, {3}}, 0.95)
+	for i, line := range lines[1:] {
+		var values []float64
+		for _, field := range strings.Split(line, ",") {
+			v, _ := strconv.ParseFloat(field, 64)
+			values = append(values, v)
+		}
+		expected := predict(b, w, [][]float64{{float64(2*i + 1)}})[0][0]
+		if math.Abs(values[0]-expected) > 1e-12 || math.Abs(values[2]-values[0]-widths[i]) > 1e-12 || math.Abs(values[0]-values[1]-widths[i]) > 1e-12 {
+			t.Errorf("predictCommand: expected %v ± %v, actual %q", expected, widths[i], line)
+		}
+	}
+
+	// intervals need the statistics
+	model.Stats = nil
+	if err := ml.SaveModel(modelPath, model); err != nil {
+		t.Fatal(err)
+	}
+	if err := predictCommand([]string{"-model", modelPath, "-data", path, "-out", outPath, "-interval", "0.95"}); err == nil {
+		t.Errorf("predictCommand: expected err != nil for intervals without statistics")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"./ml"
+)
+
+// baseline fits a logistic regression, the linear classifier a network has to beat.
+func baseline(args []string) error {
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	dataPath := fs.String("data", "binary.csv", "csv file with a header row")
+	target := fs.String("target", "admit", "column to classify")
+	features := fs.String("features", "", "comma separated columns to use as features, all but the target if empty")
+	categorical := fs.String("categorical", "rank", "comma separated feature columns to one-hot encode, the first category of each being the reference")
+	standardize := fs.Bool("standardize", true, "standardize the numeric features before fitting")
+	multinomial := fs.Bool("multinomial", false, "fit a probability per class of the target with a softmax, rather than the probability of 1")
+	solver := fs.String("solver", ml.SolverIRLS, "irls for Newton's method, or gd for gradient descent")
+	l1 := fs.Float64("l1", 0, "L1 penalty on the weights, gd only")
+	l2 := fs.Float64("l2", 0, "L2 penalty on the weights")
+	classWeight := fs.String("class-weight", "", "comma separated weights of the rows of every class, or balanced to make the classes count the same")
+	learnRate := fs.Float64("lr", 0.1, "learning rate of gd")
+	iterations := fs.Int("iterations", 0, "most steps to take (default 100 for irls, 10000 for gd)")
+	tolerance := fs.Float64("tol", 1e-8, "stop once no coefficient moves by more than this in a step")
+	testFrac := fs.Float64("test", 0.1, "fraction of the rows, taken from the end, held out to measure accuracy")
+	level := fs.Float64("level", 0.95, "confidence level of the intervals of the coefficients")
+	modelPath := fs.String("model", "", "file to save the model to, for nn evaluate and nn predict, none if empty")
+	fs.Parse(args)
+
+	header, records, err := readCSV(*dataPath)
+	if err != nil {
+		return err
+	}
+	featureNames := splitList(*features)
+	if featureNames == nil {
+		for _, name := range header {
+			if name != *target {
+				featureNames = append(featureNames, name)
+			}
+		}
+	}
+	raw, err := selectColumns(header, records, featureNames)
+	if err != nil {
+		return err
+	}
+	targets, err := selectColumns(header, records, []string{*target})
+	if err != nil {
+		return err
+	}
+
+	// a multinomial model has an output per class of the target
+	var classes []float64
+	if *multinomial {
+		column := ml.Column{Name: *target, Categories: ml.Categories(ml.T(targets)[0])}
+		if targets, err = ml.Encode([]ml.Column{column}, targets); err != nil {
+			return err
+		}
+		classes = column.Categories
+	}
+
+	// encode and scale the features the way train does
+	columns := make([]ml.Column, len(featureNames))
+	rawColumns := ml.T(raw)
+	for i, name := range featureNames {
+		columns[i].Name = name
+		if contains(splitList(*categorical), name) {
+			columns[i].Categories = ml.Categories(rawColumns[i])
+		}
+	}
+	inputs, err := ml.Encode(columns, raw)
+	if err != nil {
+		return err
+	}
+	var scaler *ml.Scaler
+	if numeric := numericFeatures(columns); *standardize && len(numeric) > 0 {
+		scaler = ml.FitScaler(inputs, numeric...)
+		inputs = scaler.Transform(inputs)
+	}
+
+	// with an intercept, all the dummies of a column would be one too many
+	references := referenceFeatures(columns)
+	var names []string
+	for j, name := range ml.FeatureNames(columns) {
+		if !containsIndex(references, j) {
+			names = append(names, name)
+		}
+	}
+	x := dropFeatures(inputs, references)
+	xTrain, xTest := ml.SplitMatrix(x, float32(1-*testFrac))
+	yTrain, yTest := ml.SplitMatrix(targets, float32(1-*testFrac))
+	if len(xTrain) == 0 {
+		return fmt.Errorf("%s: not enough rows to fit", *dataPath)
+	}
+
+	opts := &ml.LogisticOptions{
+		Solver:     *solver,
+		Penalty:    &ml.Penalty{L1: *l1, L2: *l2},
+		LearnRate:  *learnRate,
+		Iterations: *iterations,
+		Tolerance:  *tolerance,
+	}
+	if opts.Iterations == 0 {
+		opts.Iterations = 100
+		if *solver == ml.SolverGD {
+			opts.Iterations = 10000
+		}
+	}
+	switch *classWeight {
+	case "":
+	case "balanced":
+		opts.ClassWeights = ml.BalancedClassWeights(yTrain)
+	default:
+		for _, s := range splitList(*classWeight) {
+			w, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("bad class weight %q", s)
+			}
+			opts.ClassWeights = append(opts.ClassWeights, w)
+		}
+	}
+
+	l, result, err := ml.FitLogistic(xTrain, yTrain, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s stopped by %v after %v iterations\n", opts.Solver, result.Reason, result.Steps)
+
+	// the coefficients, and how sure they are when that is known: the z-tests hold for the
+	// maximum likelihood estimate, which a fit stopped short of converging hasn't reached
+	var coefficients []ml.Coefficient
+	switch {
+	case *l1 != 0 || *l2 != 0 || opts.ClassWeights != nil || classes != nil:
+	case !result.Converged():
+		fmt.Fprintf(os.Stderr, "no standard errors: %s stopped by %s before converging, raise -iterations or -tol\n", opts.Solver, result.Reason)
+	default:
+		coefficients, _ = l.Summarize(xTrain, names, *level) // nil if the features are dependent
+	}
+	if scaler != nil {
+		fmt.Println("coefficients of the standardized features:")
+	}
+	if coefficients != nil {
+		lower, upper := fmt.Sprintf("[%.4g", (1-*level)/2), fmt.Sprintf("%.4g]", (1+*level)/2)
+		fmt.Printf("%-16s %12s %12s %12s %9s %9s %12s %12s\n", "", "coef", "odds ratio", "std err", "z", "P>|z|", lower, upper)
+		for _, c := range coefficients {
+			fmt.Printf("%-16s %12.6g %12.6g %12.6g %9.3f %9.3g %12.6g %12.6g\n", c.Name, c.Value, math.Exp(c.Value), c.StdErr, c.T, c.P, c.Lower, c.Upper)
+		}
+	} else {
+		fmt.Printf("%-16s", "")
+		if classes == nil {
+			fmt.Printf(" %12s", "coef")
+		}
+		for _, class := range classes {
+			fmt.Printf(" %12s", fmt.Sprintf("%s=%v", *target, class))
+		}
+		fmt.Println()
+		for j, row := range append([][]float64{l.Bias}, l.Weights...) {
+			name := "intercept"
+			if j > 0 {
+				name = names[j-1]
+			}
+			fmt.Printf("%-16s", name)
+			for _, v := range row {
+				fmt.Printf(" %12.6g", v)
+			}
+			fmt.Println()
+		}
+	}
+
+	// how well it classifies
+	accuracy := ml.BinaryAccuracy
+	if classes != nil {
+		accuracy = ml.CategoricalAccuracy
+	}
+	loss := l.Loss(xTrain, yTrain, nil)
+	fmt.Printf("Train loss: %v, accuracy: %v\n", loss, accuracy(l.Predict(xTrain), yTrain))
+	if len(xTest) > 0 {
+		fmt.Printf("Test loss: %v\n", l.Loss(xTest, yTest, nil))
+		fmt.Printf("Prediction accuracy: %v\n", accuracy(l.Predict(xTest), yTest))
+	}
+
+	if *modelPath == "" {
+		return nil
+	}
+	layer := l.Layer()
+	layer.Weights = insertZeroRows(layer.Weights, references)
+	model := &ml.Model{
+		Network:  ml.Network{Layers: []ml.Layer{layer}},
+		Columns:  columns,
+		Scaler:   scaler,
+		Features: ml.FeatureNames(columns),
+		Targets:  []string{*target},
+		Classes:  classes,
+		Training: ml.TrainingInfo{
+			Epochs:     result.Steps,
+			LearnRate:  opts.LearnRate,
+			Loss:       loss,
+			TrainedAt:  time.Now().UTC(),
+			StopReason: result.Reason,
+		},
+	}
+	if err := ml.SaveModel(*modelPath, model); err != nil {
+		return err
+	}
+	fmt.Printf("Model saved to %v\n", *modelPath)
+	return nil
+}
+
+// referenceFeatures returns the indices of the encoded features of the first category of every
+// one-hot column, left out of a linear model so that the intercept stands for them.
+func referenceFeatures(columns []ml.Column) []int {
+	var references []int
+	index := 0
+	for _, c := range columns {
+		if c.Categories == nil {
+			index++
+			continue
+		}
+		references = append(references, index)
+		index += len(c.Categories)
+	}
+	return references
+}
+
+// dropFeatures returns the rows without the features at the given indices, in increasing order.
+func dropFeatures(m [][]float64, indices []int) [][]float64 {
+	result := make([][]float64, len(m))
+	for i, row := range m {
+		for j, v := range row {
+			if !containsIndex(indices, j) {
+				result[i] = append(result[i], v)
+			}
+		}
+	}
+	return result
+}
+
+// containsIndex checks if the list has the given index.
+func containsIndex(list []int, index int) bool {
+	for _, i := range list {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+// insertZeroRows undoes dropFeatures on the weights of a layer, the dropped features weighing nothing.
+func insertZeroRows(w [][]float64, indices []int) [][]float64 {
+	var result [][]float64
+	next := 0
+	for len(result) < len(w)+len(indices) {
+		if next < len(indices) && indices[next] == len(result) {
+			result = append(result, make([]float64, len(w[0])))
+			next++
+			continue
+		}
+		result = append(result, w[len(result)-next])
+	}
+	return result
+}
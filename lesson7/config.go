@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"./ml"
+)
+
+// Config describes a training run. It can be loaded from a JSON file with -config, flags given on
+// the command line override it, and the resolved config is saved next to the model.
+type Config struct {
+	Dataset       DatasetConfig        `json:"dataset"`
+	Preprocessing PreprocessingConfig  `json:"preprocessing"`
+	Layers        []LayerConfig        `json:"layers"` // the last one is the output layer
+	Loss          string               `json:"loss"`
+	Optimizer     OptimizerConfig      `json:"optimizer"`
+	Training      TrainingConfig       `json:"training"`
+	Metrics       []string             `json:"metrics"`
+	Checkpoints   CheckpointConfig     `json:"checkpoints"`
+	EarlyStopping *EarlyStoppingConfig `json:"early_stopping,omitempty"` // train for all the epochs if nil
+	Convergence   *ConvergenceConfig   `json:"convergence,omitempty"`    // train for all the epochs if nil
+	Model         string               `json:"model"`
+	Log           string               `json:"log,omitempty"` // per-epoch metrics, csv if it ends in .csv, JSON Lines otherwise
+}
+
+// DatasetConfig says where the data is and how to split it.
+type DatasetConfig struct {
+	Path       string   `json:"path"`
+	Target     string   `json:"target"`
+	Features   []string `json:"features,omitempty"` // all but the target if empty
+	Test       float64  `json:"test"`               // fraction of the rows, from the end, held out for the metrics
+	Validation float64  `json:"validation"`         // fraction of the remaining rows used to pick the best checkpoint
+}
+
+// PreprocessingConfig says how the raw columns become features.
+type PreprocessingConfig struct {
+	Categorical []string `json:"categorical"` // one-hot encoded
+	Standardize bool     `json:"standardize"` // standardize the numeric features
+}
+
+// LayerConfig describes a layer: dense, dropout, batch_norm, layer_norm, or activation to apply
+// Activation on its own, typically after a normalization. The output layer is dense, and gets
+// one unit per target if its size is 0.
+type LayerConfig struct {
+	Type        string      `json:"type,omitempty"` // dense if empty
+	Size        int         `json:"size,omitempty"`
+	Activation  string      `json:"activation,omitempty"`
+	Rate        float64     `json:"rate,omitempty"`         // fraction of the units dropped by dropout
+	Penalty     *ml.Penalty `json:"penalty,omitempty"`      // L1/L2 regularization of the weights
+	BiasPenalty *ml.Penalty `json:"bias_penalty,omitempty"` // and of the bias
+}
+
+// dense reports whether the layer is a dense layer.
+func (l LayerConfig) dense() bool {
+	return l.Type == "" || l.Type == ml.LayerDense
+}
+
+// OptimizerConfig picks the optimizer and its settings.
+type OptimizerConfig struct {
+	Method      string          `json:"method"`
+	LearnRate   float64         `json:"learn_rate"`
+	Momentum    float64         `json:"momentum,omitempty"`     // the optimizer's default if 0
+	WeightDecay float64         `json:"weight_decay,omitempty"` // decoupled from the gradient, weights only
+	Schedule    *ScheduleConfig `json:"schedule,omitempty"`     // a constant learn_rate if nil
+	Plateau     *PlateauConfig  `json:"plateau,omitempty"`      // lower the rate when a metric stops improving
+}
+
+// ScheduleConfig changes the learning rate every epoch, starting from learn_rate.
+type ScheduleConfig struct {
+	Type    string  `json:"type"` // constant, step, exponential, cosine or one_cycle
+	Warmup  int     `json:"warmup,omitempty"`
+	Step    int     `json:"step,omitempty"`
+	Gamma   float64 `json:"gamma,omitempty"`
+	Mult    float64 `json:"mult,omitempty"`
+	MinRate float64 `json:"min_rate,omitempty"`
+	Epochs  int     `json:"epochs,omitempty"` // one_cycle only, training.epochs if 0
+}
+
+// PlateauConfig multiplies the learning rate by Factor when Monitor has not improved for Patience epochs.
+type PlateauConfig struct {
+	Monitor  string  `json:"monitor"` // loss or val_loss
+	Factor   float64 `json:"factor"`
+	Patience int     `json:"patience"`
+	MinDelta float64 `json:"min_delta,omitempty"`
+	MinRate  float64 `json:"min_rate,omitempty"`
+}
+
+// schedule returns the learning rate schedule of the config, nil without one.
+func (c *Config) schedule() *ml.Schedule {
+	s := c.Optimizer.Schedule
+	if s == nil {
+		return nil
+	}
+	epochs := s.Epochs
+	if epochs == 0 {
+		epochs = c.Training.Epochs
+	}
+	return &ml.Schedule{
+		Type:    s.Type,
+		Rate:    c.Optimizer.LearnRate,
+		Warmup:  s.Warmup,
+		Step:    s.Step,
+		Gamma:   s.Gamma,
+		Mult:    s.Mult,
+		MinRate: s.MinRate,
+		Epochs:  epochs,
+	}
+}
+
+// TrainingConfig sets how long and in what steps to train.
+type TrainingConfig struct {
+	Epochs    int    `json:"epochs"`
+	BatchSize int    `json:"batch_size"` // 0 uses the whole training set
+	Seed      uint64 `json:"seed"`
+}
+
+// CheckpointConfig says where and how often to save checkpoints, no checkpoints are saved without a Dir.
+type CheckpointConfig struct {
+	Dir   string `json:"dir,omitempty"`
+	Every int    `json:"every"`
+	Keep  int    `json:"keep"`
+}
+
+// EarlyStoppingConfig stops training once a metric has not improved for Patience epochs.
+type EarlyStoppingConfig struct {
+	Monitor     string  `json:"monitor"`        // loss, val_loss or val_accuracy
+	Mode        string  `json:"mode,omitempty"` // min or max, max for accuracy and min otherwise if empty
+	Patience    int     `json:"patience"`
+	MinDelta    float64 `json:"min_delta,omitempty"`
+	RestoreBest bool    `json:"restore_best"` // keep the weights of the best epoch rather than the last
+}
+
+// ConvergenceConfig stops training once it converges or runs out of time, a zero field turning
+// its test off.
+type ConvergenceConfig struct {
+	GradNorm    float64 `json:"grad_norm,omitempty"`    // of the last batch of an epoch
+	LossChange  float64 `json:"loss_change,omitempty"`  // of the training loss from one epoch to the next, over its size
+	ParamChange float64 `json:"param_change,omitempty"` // of all the weights over an epoch, over their norm
+	TimeLimit   string  `json:"time_limit,omitempty"`   // such as 90s or 10m
+	Patience    int     `json:"patience,omitempty"`     // epochs in a row the change tests have to pass, 1 if 0
+}
+
+// convergence returns the convergence criteria of the config, nil without them.
+func (c *Config) convergence() *ml.Convergence {
+	cc := c.Convergence
+	if cc == nil {
+		return nil
+	}
+	limit, _ := time.ParseDuration(cc.TimeLimit) // checked by validate
+	return &ml.Convergence{
+		GradNorm:    cc.GradNorm,
+		LossChange:  cc.LossChange,
+		ParamChange: cc.ParamChange,
+		TimeLimit:   limit,
+		Patience:    cc.Patience,
+	}
+}
+
+// mode returns the direction in which the monitored metric improves.
+func (c *EarlyStoppingConfig) mode() string {
+	if c.Mode != "" {
+		return c.Mode
+	}
+	if strings.HasSuffix(c.Monitor, metricAccuracy) {
+		return ml.ModeMax
+	}
+	return ml.ModeMin
+}
+
+// Metrics reported on the test set, and on the validation set during training. Classifiers also
+// take top<k>_accuracy, such as top2_accuracy.
+const (
+	metricLoss     = "loss"
+	metricAccuracy = "accuracy"
+)
+
+// topK returns the k of a top<k>_accuracy metric, 0 for any other name.
+func topK(name string) int {
+	k, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "top"), "_"+metricAccuracy))
+	if err != nil || name != fmt.Sprintf("top%d_%s", k, metricAccuracy) {
+		return 0
+	}
+	return k
+}
+
+// classifier reports whether the config trains a classifier, whose target holds class values.
+func (c *Config) classifier() bool {
+	return c.Loss == ml.LossCrossEntropy
+}
+
+// defaultConfig returns the settings of the original lesson7 network on binary.csv.
+func defaultConfig() *Config {
+	return &Config{
+		Dataset: DatasetConfig{
+			Path:       "binary.csv",
+			Target:     "admit",
+			Test:       0.1,
+			Validation: 0.1,
+		},
+		Preprocessing: PreprocessingConfig{
+			Categorical: []string{"rank"},
+			Standardize: true,
+		},
+		Layers: []LayerConfig{
+			{Size: 4, Activation: ml.ActivationSigmoid},
+			{Size: 0, Activation: ml.ActivationSigmoid},
+		},
+		Loss:        ml.LossMSE,
+		Optimizer:   OptimizerConfig{Method: ml.OptimizerSGD, LearnRate: 0.005},
+		Training:    TrainingConfig{Epochs: 10000, Seed: 1},
+		Metrics:     []string{metricLoss, metricAccuracy},
+		Checkpoints: CheckpointConfig{Every: 1000, Keep: 5},
+		Model:       modelFile,
+	}
+}
+
+// loadConfig reads a JSON config on top of the defaults. Unknown keys are an error, to catch typos.
+// The layers and categorical columns of the file are decoded from scratch rather than over the
+// default ones, which belong to binary.csv: a file without layers gets the default layers, one
+// without categorical columns has none.
+func loadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := defaultConfig()
+	defaultLayers := cfg.Layers
+	cfg.Layers, cfg.Preprocessing.Categorical = nil, nil
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if cfg.Layers == nil {
+		cfg.Layers = defaultLayers
+	}
+	return cfg, nil
+}
+
+// save writes the config as JSON.
+func (c *Config) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// validate checks the config and reports every problem it finds.
+func (c *Config) validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Dataset.Path == "" {
+		problem("dataset.path: missing")
+	}
+	if c.Dataset.Target == "" {
+		problem("dataset.target: missing")
+	}
+	if contains(c.Dataset.Features, c.Dataset.Target) {
+		problem("dataset.features: contains the target %q", c.Dataset.Target)
+	}
+	if c.Dataset.Test < 0 || c.Dataset.Test >= 1 {
+		problem("dataset.test: %v is not in [0, 1)", c.Dataset.Test)
+	}
+	if c.Dataset.Validation < 0 || c.Dataset.Validation >= 1 {
+		problem("dataset.validation: %v is not in [0, 1)", c.Dataset.Validation)
+	}
+	for _, name := range c.Preprocessing.Categorical {
+		if c.Dataset.Features != nil && !contains(c.Dataset.Features, name) {
+			problem("preprocessing.categorical: %q is not a feature", name)
+		}
+		if name == c.Dataset.Target {
+			problem("preprocessing.categorical: %q is the target", name)
+		}
+	}
+
+	if len(c.Layers) == 0 {
+		problem("layers: need at least the output layer")
+	}
+	for i, l := range c.Layers {
+		if !l.dense() && i == len(c.Layers)-1 {
+			problem("layers[%d].type: the output layer must be dense, not %s", i, l.Type)
+		}
+		switch l.Type {
+		case "", ml.LayerDense:
+		case ml.LayerDropout:
+			if l.Rate < 0 || l.Rate >= 1 {
+				problem("layers[%d].rate: %v is not in [0, 1)", i, l.Rate)
+			}
+			continue
+		case ml.LayerBatchNorm, ml.LayerLayerNorm:
+			continue
+		case ml.LayerActivation:
+			if !ml.KnownActivation(l.Activation) {
+				problem("layers[%d].activation: unknown activation %q", i, l.Activation)
+			}
+			continue
+		default:
+			problem("layers[%d].type: unknown layer type %q", i, l.Type)
+			continue
+		}
+		if l.Size < 0 || (l.Size == 0 && i != len(c.Layers)-1) {
+			problem("layers[%d].size: %d is not positive", i, l.Size)
+		}
+		if !ml.KnownActivation(l.Activation) {
+			problem("layers[%d].activation: unknown activation %q", i, l.Activation)
+		}
+		if l.Penalty.Validate() != nil {
+			problem("layers[%d].penalty: %+v is negative", i, *l.Penalty)
+		}
+		if l.BiasPenalty.Validate() != nil {
+			problem("layers[%d].bias_penalty: %+v is negative", i, *l.BiasPenalty)
+		}
+	}
+
+	if !ml.KnownLoss(c.Loss) {
+		problem("loss: unknown loss %q", c.Loss)
+	}
+	if _, err := ml.NewOptimizer(c.Optimizer.Method, c.Optimizer.LearnRate); err != nil {
+		problem("optimizer.method: unknown optimizer %q", c.Optimizer.Method)
+	}
+	if c.Optimizer.LearnRate <= 0 {
+		problem("optimizer.learn_rate: %v is not positive", c.Optimizer.LearnRate)
+	}
+	if c.Optimizer.Momentum < 0 || c.Optimizer.Momentum >= 1 {
+		problem("optimizer.momentum: %v is not in [0, 1)", c.Optimizer.Momentum)
+	}
+	if c.Optimizer.WeightDecay < 0 {
+		problem("optimizer.weight_decay: %v is negative", c.Optimizer.WeightDecay)
+	}
+	if s := c.schedule(); s != nil && c.Optimizer.LearnRate > 0 {
+		if err := s.Validate(); err != nil {
+			problem("optimizer.schedule: %s", strings.TrimPrefix(err.Error(), "ml: "))
+		}
+	}
+	if p := c.Optimizer.Plateau; p != nil {
+		if p.Monitor != metricLoss && p.Monitor != "val_"+metricLoss {
+			problem("optimizer.plateau.monitor: unknown metric %q", p.Monitor)
+		} else if p.Monitor != metricLoss && c.Dataset.Validation == 0 {
+			problem("optimizer.plateau.monitor: %q needs dataset.validation", p.Monitor)
+		}
+		if p.Factor <= 0 || p.Factor >= 1 {
+			problem("optimizer.plateau.factor: %v is not in (0, 1)", p.Factor)
+		}
+		if p.Patience < 0 {
+			problem("optimizer.plateau.patience: %d is negative", p.Patience)
+		}
+	}
+
+	if c.Training.Epochs <= 0 {
+		problem("training.epochs: %d is not positive", c.Training.Epochs)
+	}
+	if c.Training.BatchSize < 0 {
+		problem("training.batch_size: %d is negative", c.Training.BatchSize)
+	}
+
+	if c.classifier() && len(c.Layers) > 0 && c.Layers[len(c.Layers)-1].Activation != ml.ActivationSoftmax {
+		problem("layers[%d].activation: %s needs a softmax output", len(c.Layers)-1, c.Loss)
+	}
+
+	for _, name := range c.Metrics {
+		if name != metricLoss && name != metricAccuracy && (topK(name) <= 0 || !c.classifier()) {
+			problem("metrics: unknown metric %q", name)
+		}
+	}
+
+	if c.Checkpoints.Every <= 0 {
+		problem("checkpoints.every: %d is not positive", c.Checkpoints.Every)
+	}
+	if c.Checkpoints.Keep < 0 {
+		problem("checkpoints.keep: %d is negative", c.Checkpoints.Keep)
+	}
+
+	if e := c.EarlyStopping; e != nil {
+		metric := strings.TrimPrefix(e.Monitor, "val_")
+		switch {
+		case e.Monitor == metricLoss:
+		case metric == e.Monitor:
+			problem("early_stopping.monitor: unknown metric %q", e.Monitor)
+		case metric != metricLoss && !contains(c.Metrics, metric):
+			problem("early_stopping.monitor: %q needs the %s metric", e.Monitor, metric)
+		case c.Dataset.Validation == 0:
+			problem("early_stopping.monitor: %q needs dataset.validation", e.Monitor)
+		}
+		if e.Mode != "" && e.Mode != ml.ModeMin && e.Mode != ml.ModeMax {
+			problem("early_stopping.mode: %q is not min or max", e.Mode)
+		}
+		if e.Patience < 0 {
+			problem("early_stopping.patience: %d is negative", e.Patience)
+		}
+		if e.MinDelta < 0 {
+			problem("early_stopping.min_delta: %v is negative", e.MinDelta)
+		}
+	}
+
+	if cc := c.Convergence; cc != nil {
+		if cc.TimeLimit != "" {
+			if limit, err := time.ParseDuration(cc.TimeLimit); err != nil || limit < 0 {
+				problem("convergence.time_limit: %q is not a duration such as 10m", cc.TimeLimit)
+			}
+		}
+		if cc.GradNorm < 0 {
+			problem("convergence.grad_norm: %v is negative", cc.GradNorm)
+		}
+		if cc.LossChange < 0 {
+			problem("convergence.loss_change: %v is negative", cc.LossChange)
+		}
+		if cc.ParamChange < 0 {
+			problem("convergence.param_change: %v is negative", cc.ParamChange)
+		}
+		if cc.Patience < 0 {
+			problem("convergence.patience: %d is negative", cc.Patience)
+		}
+	}
+
+	if c.Model == "" {
+		problem("model: missing")
+	}
+
+	if problems != nil {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// configPath returns where the config of a model is saved: model.json gets model.config.json.
+func configPath(modelPath string) string {
+	return strings.TrimSuffix(modelPath, filepath.Ext(modelPath)) + ".config.json"
+}
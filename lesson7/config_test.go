@@ -0,0 +1,294 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"./ml"
+)
+
+// writeConfig writes the JSON config to a file in dir and returns its path.
+func writeConfig(t *testing.T, dir, json string) string {
+	path := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(path, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSetHidden(t *testing.T) {
+	sigmoid := LayerConfig{Activation: ml.ActivationSigmoid}
+	output := LayerConfig{Activation: ml.ActivationSoftmax}
+	relu := LayerConfig{Size: 8, Activation: ml.ActivationReLU, Penalty: &ml.Penalty{L2: 0.1}}
+	dropout := LayerConfig{Type: ml.LayerDropout, Rate: 0.5}
+	norm := LayerConfig{Type: ml.LayerBatchNorm}
+	sized := func(l LayerConfig, size int) LayerConfig {
+		l.Size = size
+		return l
+	}
+
+	tests := []struct {
+		name     string
+		layers   []LayerConfig
+		sizes    []int
+		expected []LayerConfig
+	}{
+		{"no hidden layers", []LayerConfig{output}, []int{3, 2}, []LayerConfig{sized(sigmoid, 3), sized(sigmoid, 2), output}},
+		{"fewer sizes", []LayerConfig{relu, relu, output}, []int{5}, []LayerConfig{sized(relu, 5), output}},
+		{"no sizes", []LayerConfig{relu, dropout, output}, nil, []LayerConfig{output}},
+		{"keeps the layers after", []LayerConfig{norm, relu, dropout, output}, []int{2, 3}, []LayerConfig{norm, sized(relu, 2), dropout, sized(relu, 3), dropout, output}},
+	}
+	for _, test := range tests {
+		cfg := &Config{Layers: test.layers}
+		if err := setHidden(cfg, test.sizes); err != nil {
+			t.Errorf("setHidden(%s) unexpected error: %v", test.name, err)
+		} else if !reflect.DeepEqual(test.expected, cfg.Layers) {
+			t.Errorf("setHidden(%s): expected %+v, actual %+v", test.name, test.expected, cfg.Layers)
+		}
+	}
+
+	// the copies of a layer must not share its penalty
+	cfg := &Config{Layers: []LayerConfig{relu, output}}
+	setHidden(cfg, []int{1, 1})
+	if cfg.Layers[0].Penalty == cfg.Layers[1].Penalty || cfg.Layers[0].Penalty == relu.Penalty {
+		t.Errorf("setHidden: expected a penalty per layer, actual %p %p", cfg.Layers[0].Penalty, cfg.Layers[1].Penalty)
+	}
+
+	if err := setHidden(&Config{}, []int{4}); err == nil {
+		t.Errorf("setHidden: expected err != nil without layers")
+	}
+}
+
+func TestTrainConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// flags override the file, which overrides the defaults
+	path := writeConfig(t, dir, `{
+		"layers": [{"size": 6, "activation": "relu"}, {"activation": "sigmoid"}],
+		"optimizer": {"method": "adam", "learn_rate": 0.01},
+		"training": {"epochs": 50, "seed": 7}
+	}`)
+	cfg, resume, _, _, err := trainConfig([]string{"-config", path, "-lr", "0.2", "-hidden", "3,2", "-checkpoints", dir, "-resume"})
+	if err != nil {
+		t.Fatal("trainConfig unexpected error:", err)
+	}
+	if cfg.Optimizer.LearnRate != 0.2 || cfg.Optimizer.Method != ml.OptimizerAdam {
+		t.Errorf("trainConfig: expected adam at 0.2, actual %v at %v", cfg.Optimizer.Method, cfg.Optimizer.LearnRate)
+	}
+	if cfg.Training.Epochs != 50 || cfg.Training.Seed != 7 || cfg.Dataset.Path != "binary.csv" {
+		t.Errorf("trainConfig: expected 50 epochs, seed 7 and binary.csv, actual %+v %v", cfg.Training, cfg.Dataset.Path)
+	}
+	expected := []LayerConfig{{Size: 3, Activation: ml.ActivationReLU}, {Size: 2, Activation: ml.ActivationReLU}, {Activation: ml.ActivationSigmoid}}
+	if !reflect.DeepEqual(expected, cfg.Layers) {
+		t.Errorf("trainConfig: expected layers %+v, actual %+v", expected, cfg.Layers)
+	}
+	if !resume || cfg.Checkpoints.Dir != dir {
+		t.Errorf("trainConfig: expected to resume from %v, actual %v from %v", dir, resume, cfg.Checkpoints.Dir)
+	}
+
+	// the activation flags apply to the layers -hidden made
+	cfg, _, _, _, err = trainConfig([]string{"-config", path, "-hidden", "2", "-activation", "tanh", "-output", "linear"})
+	if err != nil {
+		t.Fatal("trainConfig unexpected error:", err)
+	}
+	expected = []LayerConfig{{Size: 2, Activation: ml.ActivationTanh}, {Activation: ml.ActivationLinear}}
+	if !reflect.DeepEqual(expected, cfg.Layers) {
+		t.Errorf("trainConfig: expected layers %+v, actual %+v", expected, cfg.Layers)
+	}
+
+	for _, test := range []struct {
+		json string
+		args []string
+	}{
+		{`{"layers": []}`, []string{"-hidden", "4"}},
+		{`{"layers": []}`, []string{"-output", "linear"}},
+		{`{}`, []string{"-hidden", "4,x"}},
+		{`{}`, []string{"-resume"}},
+		{`{"layer": []}`, nil},
+	} {
+		path := writeConfig(t, dir, test.json)
+		if _, _, _, _, err := trainConfig(append([]string{"-config", path}, test.args...)); err == nil {
+			t.Errorf("trainConfig(%s %v): expected err != nil", test.json, test.args)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// what the file leaves out keeps its default
+	cfg, err := loadConfig(writeConfig(t, dir, `{"loss": "cross_entropy", "training": {"epochs": 5}}`))
+	if err != nil {
+		t.Fatal("loadConfig unexpected error:", err)
+	}
+	defaults := defaultConfig()
+	if cfg.Loss != ml.LossCrossEntropy || cfg.Training.Epochs != 5 || cfg.Optimizer != defaults.Optimizer || cfg.Model != defaults.Model {
+		t.Errorf("loadConfig: expected the defaults with cross_entropy and 5 epochs, actual %+v", cfg)
+	}
+	if !reflect.DeepEqual(defaults.Layers, cfg.Layers) || cfg.Preprocessing.Categorical != nil {
+		t.Errorf("loadConfig: expected the default layers and no categorical columns, actual %+v and %v", cfg.Layers, cfg.Preprocessing.Categorical)
+	}
+
+	// the layers of the file take nothing from the default layers at the same index
+	var layerTests = []struct {
+		json    string
+		layers  []LayerConfig
+		problem string
+	}{
+		{
+			`{"layers": [{"activation": "relu"}, {"activation": "sigmoid"}]}`,
+			[]LayerConfig{{Activation: ml.ActivationReLU}, {Activation: ml.ActivationSigmoid}},
+			"layers[0].size: 0 is not positive",
+		},
+		{
+			`{"layers": [{"type": "dropout", "rate": 0.2}, {"type": "dropout", "rate": 0.1}, {"size": 3, "activation": "relu"}, {"activation": "sigmoid"}]}`,
+			[]LayerConfig{{Type: ml.LayerDropout, Rate: 0.2}, {Type: ml.LayerDropout, Rate: 0.1}, {Size: 3, Activation: ml.ActivationReLU}, {Activation: ml.ActivationSigmoid}},
+			"",
+		},
+	}
+	for _, test := range layerTests {
+		cfg, err := loadConfig(writeConfig(t, dir, test.json))
+		if err != nil {
+			t.Fatalf("loadConfig(%s) unexpected error: %v", test.json, err)
+		}
+		if !reflect.DeepEqual(test.layers, cfg.Layers) {
+			t.Errorf("loadConfig(%s): expected layers %+v, actual %+v", test.json, test.layers, cfg.Layers)
+		}
+		err = cfg.validate()
+		switch {
+		case test.problem == "" && err != nil:
+			t.Errorf("loadConfig(%s): validate unexpected error: %v", test.json, err)
+		case test.problem != "" && (err == nil || !strings.Contains(err.Error(), test.problem)):
+			t.Errorf("loadConfig(%s): validate expected %q, actual %v", test.json, test.problem, err)
+		}
+	}
+
+	// and what it saves loads back the same
+	path := filepath.Join(dir, "saved.json")
+	if err := cfg.save(path); err != nil {
+		t.Fatal("save unexpected error:", err)
+	}
+	loaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatal("loadConfig unexpected error:", err)
+	}
+	if !reflect.DeepEqual(cfg, loaded) {
+		t.Errorf("loadConfig(save(%+v)): expected the same config, actual %+v", cfg, loaded)
+	}
+
+	for _, json := range []string{`{"epochs": 5}`, `{"training": {"epochs": "5"}}`, `{`} {
+		if _, err := loadConfig(writeConfig(t, dir, json)); err == nil {
+			t.Errorf("loadConfig(%s): expected err != nil", json)
+		}
+	}
+	if _, err := loadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("loadConfig: expected err != nil for a missing file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := defaultConfig().validate(); err != nil {
+		t.Errorf("validate(defaultConfig()) unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		change  func(c *Config)
+		problem string
+	}{
+		{func(c *Config) { c.Dataset.Path = "" }, "dataset.path: missing"},
+		{func(c *Config) { c.Dataset.Features = []string{"gre", "admit"} }, "dataset.features: contains the target"},
+		{func(c *Config) { c.Dataset.Test = 1 }, "dataset.test: 1 is not in [0, 1)"},
+		{func(c *Config) { c.Dataset.Features = []string{"gre"} }, `preprocessing.categorical: "rank" is not a feature`},
+		{func(c *Config) { c.Layers = nil }, "layers: need at least the output layer"},
+		{func(c *Config) { c.Layers = c.Layers[:1] }, ""},
+		{func(c *Config) { c.Layers = append(c.Layers, LayerConfig{Type: ml.LayerDropout}) }, "layers[2].type: the output layer must be dense"},
+		{func(c *Config) { c.Layers[0].Type = "conv" }, `layers[0].type: unknown layer type "conv"`},
+		{func(c *Config) { c.Layers[0].Size = 0 }, "layers[0].size: 0 is not positive"},
+		{func(c *Config) { c.Layers[0].Activation = "swish" }, `layers[0].activation: unknown activation "swish"`},
+		{func(c *Config) { c.Layers[0].Penalty = &ml.Penalty{L2: -1} }, "layers[0].penalty"},
+		{func(c *Config) { c.Loss = ml.LossCrossEntropy }, "layers[1].activation: cross_entropy needs a softmax output"},
+		{func(c *Config) { c.Optimizer.LearnRate = 0 }, "optimizer.learn_rate: 0 is not positive"},
+		{func(c *Config) { c.Optimizer.Method = "lbfgs" }, `optimizer.method: unknown optimizer "lbfgs"`},
+		{func(c *Config) { c.Optimizer.Plateau = &PlateauConfig{Monitor: "loss", Factor: 2} }, "optimizer.plateau.factor: 2 is not in (0, 1)"},
+		{func(c *Config) { c.Training.Epochs = 0 }, "training.epochs: 0 is not positive"},
+		{func(c *Config) { c.Metrics = []string{"top2_accuracy"} }, `metrics: unknown metric "top2_accuracy"`},
+		{func(c *Config) { c.Checkpoints.Every = 0 }, "checkpoints.every: 0 is not positive"},
+		{func(c *Config) { c.EarlyStopping = &EarlyStoppingConfig{Monitor: "val_f1"} }, `early_stopping.monitor: "val_f1" needs the f1 metric`},
+		{func(c *Config) { c.EarlyStopping = &EarlyStoppingConfig{Monitor: "loss", Patience: -1} }, "early_stopping.patience: -1 is negative"},
+		{func(c *Config) { c.Convergence = &ConvergenceConfig{TimeLimit: "soon"} }, `convergence.time_limit: "soon" is not a duration`},
+		{func(c *Config) { c.Convergence = &ConvergenceConfig{LossChange: -1} }, "convergence.loss_change: -1 is negative"},
+		{func(c *Config) { c.Model = "" }, "model: missing"},
+	}
+	for _, test := range tests {
+		cfg := defaultConfig()
+		test.change(cfg)
+		err := cfg.validate()
+		switch {
+		case test.problem == "" && err != nil:
+			t.Errorf("validate: unexpected error: %v", err)
+		case test.problem != "" && (err == nil || !strings.Contains(err.Error(), test.problem)):
+			t.Errorf("validate: expected %q, actual %v", test.problem, err)
+		}
+	}
+
+	// every problem is reported at once
+	cfg := defaultConfig()
+	cfg.Dataset.Path, cfg.Model = "", ""
+	if err := cfg.validate(); err == nil || strings.Count(err.Error(), "\n") != 2 {
+		t.Errorf("validate: expected two problems, actual %v", err)
+	}
+}
+
+func TestBuildNetwork(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Layers = []LayerConfig{
+		{Size: 5, Activation: ml.ActivationLinear, Penalty: &ml.Penalty{L2: 0.1}},
+		{Type: ml.LayerBatchNorm},
+		{Type: ml.LayerActivation, Activation: ml.ActivationReLU},
+		{Type: ml.LayerDropout, Rate: 0.2},
+		{Activation: ml.ActivationSoftmax},
+	}
+	net, err := buildNetwork(cfg, 3, 2)
+	if err != nil {
+		t.Fatal("buildNetwork unexpected error:", err)
+	}
+	var types []string
+	for _, l := range net.Layers {
+		types = append(types, l.Type)
+	}
+	expected := []string{ml.LayerDense, ml.LayerBatchNorm, ml.LayerActivation, ml.LayerDropout, ml.LayerDense}
+	if !reflect.DeepEqual(expected, types) {
+		t.Errorf("buildNetwork: expected layers %v, actual %v", expected, types)
+	}
+	first, norm, last := net.Layers[0], net.Layers[1], net.Layers[4]
+	if len(first.Weights) != 3 || len(first.Weights[0]) != 5 || first.Penalty.L2 != 0.1 || len(norm.Gamma) != 5 {
+		t.Errorf("buildNetwork: expected 3x5 penalized weights normalized over 5 units, actual %+v %+v", first, norm)
+	}
+	if len(last.Weights) != 5 || len(last.Weights[0]) != 2 || last.Activation != ml.ActivationSoftmax {
+		t.Errorf("buildNetwork: expected a 5x2 softmax output, actual %+v", last)
+	}
+
+	// the same seed draws the same weights
+	again, _ := buildNetwork(cfg, 3, 2)
+	if !ml.MatrixEquals(first.Weights, again.Layers[0].Weights) {
+		t.Errorf("buildNetwork: expected the same weights from seed %v", cfg.Training.Seed)
+	}
+
+	// a sized output layer has to match the targets
+	cfg.Layers[len(cfg.Layers)-1].Size = 3
+	if _, err := buildNetwork(cfg, 3, 2); err == nil {
+		t.Errorf("buildNetwork: expected err != nil for 3 output units and 2 targets")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"./ml"
+)
+
+// readCSV reads a csv file with a header row, "-" meaning stdin, and returns the header and the records.
+func readCSV(path string) ([]string, [][]float64, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// read all the lines
+	r := csv.NewReader(bufio.NewReader(in))
+	rawRecords, err := r.ReadAll() // records look like [[admit gre gpa rank], ...]
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rawRecords) == 0 {
+		return nil, nil, fmt.Errorf("%s: empty file", path)
+	}
+
+	// parse data
+	records, err := ml.MatrixParseFloat(rawRecords[1:]) // exclude the csv header row
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return rawRecords[0], records, nil
+}
+
+// selectColumns returns the named columns of the records, in the given order.
+func selectColumns(header []string, records [][]float64, names []string) ([][]float64, error) {
+	indices := make([]int, len(names))
+	for i, name := range names {
+		indices[i] = -1
+		for j, h := range header {
+			if h == name {
+				indices[i] = j
+			}
+		}
+		if indices[i] < 0 {
+			return nil, fmt.Errorf("no column %q in %v", name, header)
+		}
+	}
+
+	result := make([][]float64, len(records))
+	for i, record := range records {
+		result[i] = make([]float64, len(indices))
+		for j, index := range indices {
+			result[i][j] = record[index]
+		}
+	}
+	return result, nil
+}
+
+// splitList splits a comma separated flag value, an empty value giving no items.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	items := strings.Split(s, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// contains checks if the list has the given item.
+func contains(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+// numericFeatures returns the indices of the encoded features of the columns that aren't one-hot.
+func numericFeatures(columns []ml.Column) []int {
+	var numeric []int
+	index := 0
+	for _, c := range columns {
+		if c.Categories == nil {
+			numeric = append(numeric, index)
+			index++
+		} else {
+			index += len(c.Categories)
+		}
+	}
+	return numeric
+}
@@ -0,0 +1,226 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	TransformLog    = "log"
+	TransformBoxCox = "box_cox"
+)
+
+// Transform is a monotonic transform of positive values that straightens skewed data: the
+// natural log, or the Box-Cox transform (x^λ - 1)/λ, which is the log at λ = 0.
+type Transform struct {
+	Type   string  `json:"type"`
+	Lambda float64 `json:"lambda,omitempty"`
+}
+
+// NewTransform returns a transform of the given type for the values, fitting the λ of Box-Cox
+// to them by maximum likelihood.
+func NewTransform(name string, xs []float64) (*Transform, error) {
+	t := &Transform{Type: name}
+	if err := t.check(xs); err != nil {
+		return nil, err
+	}
+	if name == TransformBoxCox {
+		t.Lambda = FitBoxCox(xs)
+	}
+	return t, nil
+}
+
+// check returns an error for an unknown transform or a value it isn't defined for.
+func (t *Transform) check(xs []float64) error {
+	switch t.Type {
+	case TransformLog, TransformBoxCox:
+	default:
+		return fmt.Errorf("ml: unknown transform %q", t.Type)
+	}
+	for _, x := range xs {
+		if x <= 0 {
+			return fmt.Errorf("ml: %s transform of %v, expected positive values", t.Type, x)
+		}
+	}
+	return nil
+}
+
+// Apply returns the transformed values, or an error if one isn't positive.
+func (t *Transform) Apply(xs []float64) ([]float64, error) {
+	if err := t.check(xs); err != nil {
+		return nil, err
+	}
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		if t.Type == TransformLog {
+			ys[i] = math.Log(x)
+		} else {
+			ys[i] = BoxCox(x, t.Lambda)
+		}
+	}
+	return ys, nil
+}
+
+// Invert undoes Apply. Values outside the range of Box-Cox (below -1/λ for λ > 0) give NaN.
+func (t *Transform) Invert(ys []float64) []float64 {
+	xs := make([]float64, len(ys))
+	for i, y := range ys {
+		if t.Type == TransformLog {
+			xs[i] = math.Exp(y)
+		} else {
+			xs[i] = InvBoxCox(y, t.Lambda)
+		}
+	}
+	return xs
+}
+
+// BoxCox returns (x^λ - 1)/λ, or log x when λ is 0.
+func BoxCox(x, lambda float64) float64 {
+	if lambda == 0 {
+		return math.Log(x)
+	}
+	return (math.Pow(x, lambda) - 1) / lambda
+}
+
+// InvBoxCox returns the x for which BoxCox(x, λ) = y.
+func InvBoxCox(y, lambda float64) float64 {
+	if lambda == 0 {
+		return math.Exp(y)
+	}
+	return math.Pow(lambda*y+1, 1/lambda)
+}
+
+// FitBoxCox returns the λ in [-5, 5] maximizing the log-likelihood of the Box-Cox transform of
+// the positive values being normal, by golden section search.
+func FitBoxCox(xs []float64) float64 {
+	var sumLog float64
+	for _, x := range xs {
+		sumLog += math.Log(x)
+	}
+	llf := func(lambda float64) float64 {
+		ys := make([]float64, len(xs))
+		for i, x := range xs {
+			ys[i] = BoxCox(x, lambda)
+		}
+		std := Std(ys)
+		return -float64(len(xs))*math.Log(std) + (lambda-1)*sumLog
+	}
+
+	lo, hi := -5.0, 5.0
+	ratio := (math.Sqrt(5) - 1) / 2
+	a, b := hi-ratio*(hi-lo), lo+ratio*(hi-lo)
+	fa, fb := llf(a), llf(b)
+	for hi-lo > 1e-10 {
+		if fa > fb {
+			hi, b, fb = b, a, fa
+			a = hi - ratio*(hi-lo)
+			fa = llf(a)
+		} else {
+			lo, a, fa = a, b, fb
+			b = lo + ratio*(hi-lo)
+			fb = llf(b)
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// Basis expands every feature into basis functions of it, so that a linear model of them fits
+// curves: its transform first, then its powers up to Degree, then for a regression spline
+// the truncated powers (x - k)₊^Degree at each of its knots. There are no cross terms.
+type Basis struct {
+	Transforms []*Transform `json:"transforms,omitempty"` // of every feature, nil for none
+	Degree     int          `json:"degree"`
+	Knots      [][]float64  `json:"knots,omitempty"` // of every feature
+}
+
+// SplineKnots returns n knots splitting the values into n+1 groups of about the same size.
+func SplineKnots(xs []float64, n int) []float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	knots := make([]float64, n)
+	for i := range knots {
+		q := float64(i+1) / float64(n+1) * float64(len(sorted)-1)
+		lo := int(q)
+		hi := lo
+		if hi+1 < len(sorted) {
+			hi++
+		}
+		knots[i] = sorted[lo] + (q-float64(lo))*(sorted[hi]-sorted[lo])
+	}
+	return knots
+}
+
+// Expand returns the basis functions of every row, feature by feature. It fails if a transform
+// isn't defined for a value.
+func (b *Basis) Expand(x [][]float64) ([][]float64, error) {
+	if len(x) == 0 {
+		return [][]float64{}, nil
+	}
+	columns := T(x)
+	for j, t := range b.Transforms {
+		if t == nil {
+			continue
+		}
+		var err error
+		if columns[j], err = t.Apply(columns[j]); err != nil {
+			return nil, err
+		}
+	}
+
+	var expanded [][]float64
+	for j, column := range columns {
+		for d := 1; d <= b.Degree; d++ {
+			expanded = append(expanded, powers(column, d))
+		}
+		if j < len(b.Knots) {
+			for _, knot := range b.Knots[j] {
+				expanded = append(expanded, truncatedPowers(column, knot, b.Degree))
+			}
+		}
+	}
+	return T(expanded), nil
+}
+
+// Names returns the names of the basis functions of the named features.
+func (b *Basis) Names(features []string) []string {
+	var names []string
+	for j, name := range features {
+		if j < len(b.Transforms) && b.Transforms[j] != nil {
+			name = fmt.Sprintf("%s(%s)", b.Transforms[j].Type, name)
+		}
+		for d := 1; d <= b.Degree; d++ {
+			if d == 1 {
+				names = append(names, name)
+			} else {
+				names = append(names, fmt.Sprintf("%s^%d", name, d))
+			}
+		}
+		if j < len(b.Knots) {
+			for _, knot := range b.Knots[j] {
+				names = append(names, fmt.Sprintf("(%s-%.4g)+^%d", name, knot, b.Degree))
+			}
+		}
+	}
+	return names
+}
+
+// powers returns x^d of every value.
+func powers(xs []float64, d int) []float64 {
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		ys[i] = math.Pow(x, float64(d))
+	}
+	return ys
+}
+
+// truncatedPowers returns (x - knot)^d of every value above the knot, 0 for the others.
+func truncatedPowers(xs []float64, knot float64, d int) []float64 {
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		if x > knot {
+			ys[i] = math.Pow(x-knot, float64(d))
+		}
+	}
+	return ys
+}
@@ -0,0 +1,119 @@
+package ml_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"."
+)
+
+func TestBoxCox(t *testing.T) {
+	var tests = []struct {
+		x, lambda, expected float64
+	}{
+		{math.E, 0, 1},
+		{4, 0.5, 2},
+		{3, 1, 2},
+		{2, -1, 0.5},
+		{9, 2, 40},
+	}
+
+	for _, test := range tests {
+		if actual := ml.BoxCox(test.x, test.lambda); math.Abs(test.expected-actual) > 1e-12 {
+			t.Errorf("BoxCox(%v, %v): expected %v, actual %v", test.x, test.lambda, test.expected, actual)
+		}
+		if actual := ml.InvBoxCox(test.expected, test.lambda); math.Abs(test.x-actual) > 1e-12 {
+			t.Errorf("InvBoxCox(%v, %v): expected %v, actual %v", test.expected, test.lambda, test.x, actual)
+		}
+	}
+}
+
+func TestFitBoxCox(t *testing.T) {
+	// the logs are symmetric, the other is checked against a grid search of the log-likelihood
+	z := []float64{-1.5, -1, -0.6, -0.3, 0, 0.3, 0.6, 1, 1.5}
+	var logNormal, squares []float64
+	for _, v := range z {
+		logNormal = append(logNormal, math.Exp(v))
+		squares = append(squares, (4+v)*(4+v))
+	}
+	if lambda := ml.FitBoxCox(logNormal); math.Abs(lambda) > 1e-4 {
+		t.Errorf("FitBoxCox(%v): expected about 0, actual %v", logNormal, lambda)
+	}
+	if lambda := ml.FitBoxCox(squares); math.Abs(lambda-0.4129) > 1e-4 {
+		t.Errorf("FitBoxCox(%v): expected 0.4129, actual %v", squares, lambda)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	xs := []float64{1, 2, 5, 10}
+	for _, name := range []string{ml.TransformLog, ml.TransformBoxCox} {
+		transform, err := ml.NewTransform(name, xs)
+		if err != nil {
+			t.Fatalf("NewTransform(%s) unexpected error: %v", name, err)
+		}
+		ys, err := transform.Apply(xs)
+		if err != nil {
+			t.Fatalf("Apply(%v) unexpected error: %v", xs, err)
+		}
+		if back := transform.Invert(ys); !ml.MatrixAlmostEquals([][]float64{xs}, [][]float64{back}, 1e-9) {
+			t.Errorf("Invert(Apply(%v)) with %s: expected the values back, actual %v", xs, name, back)
+		}
+		if _, err := transform.Apply([]float64{1, 0}); err == nil {
+			t.Errorf("Apply([1 0]) with %s: expected err != nil", name)
+		}
+	}
+
+	if _, err := ml.NewTransform("sqrt", xs); err == nil {
+		t.Errorf("NewTransform(sqrt): expected err != nil")
+	}
+	if _, err := ml.NewTransform(ml.TransformLog, []float64{1, -2}); err == nil {
+		t.Errorf("NewTransform(log, [1 -2]): expected err != nil")
+	}
+}
+
+func TestSplineKnots(t *testing.T) {
+	var tests = []struct {
+		xs       []float64
+		n        int
+		expected []float64
+	}{
+		{[]float64{4, 1, 3, 2, 5}, 1, []float64{3}},
+		{[]float64{0, 1, 2, 3, 4, 5, 6}, 2, []float64{2, 4}},
+		{[]float64{1, 2}, 3, []float64{1.25, 1.5, 1.75}},
+	}
+
+	for _, test := range tests {
+		if actual := ml.SplineKnots(test.xs, test.n); !ml.MatrixAlmostEquals([][]float64{test.expected}, [][]float64{actual}, 1e-12) {
+			t.Errorf("SplineKnots(%v, %d): expected %v, actual %v", test.xs, test.n, test.expected, actual)
+		}
+	}
+}
+
+func TestBasis(t *testing.T) {
+	x := [][]float64{{1, math.E}, {-2, 1}, {3, math.E * math.E}}
+
+	// a cubic spline with a knot at 0 on the first feature, a line through the log of the second
+	basis := &ml.Basis{
+		Transforms: []*ml.Transform{nil, {Type: ml.TransformLog}},
+		Degree:     3,
+		Knots:      [][]float64{{0}, nil},
+	}
+	expected := [][]float64{
+		{1, 1, 1, 1, 1, 1, 1},
+		{-2, 4, -8, 0, 0, 0, 0},
+		{3, 9, 27, 27, 2, 4, 8},
+	}
+	actual, err := basis.Expand(x)
+	if err != nil || !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Expand(%v): expected %v, actual %v (err %v)", x, expected, actual, err)
+	}
+	names := []string{"x", "x^2", "x^3", "(x-0)+^3", "log(y)", "log(y)^2", "log(y)^3"}
+	if actual := basis.Names([]string{"x", "y"}); !reflect.DeepEqual(names, actual) {
+		t.Errorf("Names([x y]): expected %v, actual %v", names, actual)
+	}
+
+	if _, err := basis.Expand([][]float64{{1, 0}}); err == nil {
+		t.Errorf("Expand([[1 0]]): expected err != nil for the log of 0")
+	}
+}
@@ -0,0 +1,305 @@
+package ml
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Metrics holds the values measured at the end of an epoch, such as "loss" and "val_loss".
+type Metrics map[string]float64
+
+// MetricFunc measures predictions against their targets.
+type MetricFunc func(yHat, y [][]float64) float64
+
+// Callback is told about the progress of Trainer.Fit. A callback can end training early by
+// setting the trainer's Stop field; returning an error aborts it.
+type Callback interface {
+	OnEpochEnd(t *Trainer, metrics Metrics) error
+	OnTrainEnd(t *Trainer) error
+}
+
+// StatefulCallback is a Callback that carries state from one epoch to the next. Checkpoints save
+// that state under StateName and restoring them gives it back, so that a resumed Fit carries on
+// with it rather than starting it over.
+type StatefulCallback interface {
+	Callback
+	StateName() string
+	State() (json.RawMessage, error)
+	SetState(state json.RawMessage) error
+}
+
+// BinaryAccuracy returns the fraction of probabilities that fall on the same side of 0.5 as the targets.
+func BinaryAccuracy(yHat, y [][]float64) float64 {
+	return MeanM(BinaryMatch(BinarySquash(yHat, 0.5), y))
+}
+
+// names returns the metric names in a stable order: loss first, then the rest sorted.
+func (m Metrics) names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		if name != "loss" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := m["loss"]; ok {
+		names = append([]string{"loss"}, names...)
+	}
+	return names
+}
+
+// History is a callback that keeps the metrics of every epoch.
+type History struct {
+	Epochs  []int
+	Metrics []Metrics
+}
+
+// OnEpochEnd records the metrics of the epoch.
+func (h *History) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	h.Epochs = append(h.Epochs, t.Epoch)
+	h.Metrics = append(h.Metrics, metrics)
+	return nil
+}
+
+// OnTrainEnd does nothing.
+func (h *History) OnTrainEnd(t *Trainer) error {
+	return nil
+}
+
+// Values returns the recorded values of one metric, one per epoch.
+func (h *History) Values(name string) []float64 {
+	values := make([]float64, len(h.Metrics))
+	for i, m := range h.Metrics {
+		values[i] = m[name]
+	}
+	return values
+}
+
+// WriteJSONL writes the history as JSON Lines, one object per epoch.
+func (h *History) WriteJSONL(w io.Writer) error {
+	logger := &JSONLLogger{W: w}
+	for i, m := range h.Metrics {
+		if err := logger.write(h.Epochs[i], m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the history as csv, with a header row and one row per epoch.
+func (h *History) WriteCSV(w io.Writer) error {
+	logger := &CSVLogger{W: w}
+	for i, m := range h.Metrics {
+		if err := logger.write(h.Epochs[i], m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONLLogger is a callback that writes the metrics of every epoch as a line of JSON, like
+// {"epoch":1,"loss":0.25,"val_loss":0.24}.
+type JSONLLogger struct {
+	W io.Writer
+}
+
+// OnEpochEnd writes the line of the epoch.
+func (l *JSONLLogger) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	return l.write(t.Epoch, metrics)
+}
+
+// OnTrainEnd does nothing.
+func (l *JSONLLogger) OnTrainEnd(t *Trainer) error {
+	return nil
+}
+
+func (l *JSONLLogger) write(epoch int, metrics Metrics) error {
+	line := map[string]interface{}{"epoch": epoch}
+	for name, value := range metrics {
+		line[name] = value
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	_, err = l.W.Write(append(data, '\n'))
+	return err
+}
+
+// CSVLogger is a callback that writes the metrics of every epoch as a csv row, after a header
+// row taken from the metrics of the first epoch. Given Columns, as when appending to a log with a
+// header already, it writes the rows without a header.
+type CSVLogger struct {
+	W       io.Writer
+	Columns []string // of the metrics after the epoch, in order
+}
+
+// OnEpochEnd writes the row of the epoch.
+func (l *CSVLogger) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	return l.write(t.Epoch, metrics)
+}
+
+// OnTrainEnd does nothing.
+func (l *CSVLogger) OnTrainEnd(t *Trainer) error {
+	return nil
+}
+
+func (l *CSVLogger) write(epoch int, metrics Metrics) error {
+	w := csv.NewWriter(l.W)
+	if l.Columns == nil {
+		l.Columns = metrics.names()
+		w.Write(append([]string{"epoch"}, l.Columns...))
+	}
+
+	record := []string{strconv.Itoa(epoch)}
+	for _, name := range l.Columns {
+		value, ok := metrics[name]
+		if !ok {
+			record = append(record, "")
+			continue
+		}
+		record = append(record, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	w.Write(record)
+
+	w.Flush()
+	return w.Error()
+}
+
+// ProgressLogger is a callback that prints the metrics every Every epochs and warns when the
+// training loss went up since the last print.
+type ProgressLogger struct {
+	W     io.Writer
+	Every int
+
+	lastLoss float64
+}
+
+// OnEpochEnd prints the metrics of the first epoch and then of every Every epochs.
+func (l *ProgressLogger) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	if l.Every > 1 && (t.Epoch-1)%l.Every != 0 {
+		return nil
+	}
+
+	line := fmt.Sprintf("Epoch %d:", t.Epoch)
+	for _, name := range metrics.names() {
+		line += fmt.Sprintf(" %s %v", name, metrics[name])
+	}
+	loss := metrics["loss"]
+	if l.lastLoss != 0 && l.lastLoss < loss {
+		line += " WARNING - Loss Increasing"
+	}
+	l.lastLoss = loss
+
+	_, err := fmt.Fprintln(l.W, line)
+	return err
+}
+
+// OnTrainEnd does nothing.
+func (l *ProgressLogger) OnTrainEnd(t *Trainer) error {
+	return nil
+}
+
+// Directions in which EarlyStopping expects a metric to improve.
+const (
+	ModeMin = "min"
+	ModeMax = "max"
+)
+
+// improves reports whether value is better than best by more than minDelta in the given mode.
+func improves(mode string, value, best, minDelta float64) (bool, error) {
+	switch mode {
+	case ModeMin:
+		return value < best-minDelta, nil
+	case ModeMax:
+		return value > best+minDelta, nil
+	default:
+		return false, fmt.Errorf("ml: unknown mode %q", mode)
+	}
+}
+
+// EarlyStopping is a callback that stops training once the Monitor metric has not improved by more
+// than MinDelta for Patience epochs in a row, optionally restoring the weights of the best epoch.
+// It is a StatefulCallback, so that its patience survives a checkpoint.
+type EarlyStopping struct {
+	Monitor     string  // such as "val_loss" or "val_accuracy"
+	Mode        string  // ModeMin or ModeMax
+	Patience    int     // epochs without improvement before stopping
+	MinDelta    float64 // smallest change that counts as an improvement
+	RestoreBest bool    // put back the weights of the best epoch when training ends
+
+	Best         float64 // best value of the metric seen so far
+	BestEpoch    int     // epoch of the best value, 0 before the first epoch
+	StoppedEpoch int     // epoch training was stopped at, 0 if it ran to the end
+
+	wait    int
+	bestNet *Network
+}
+
+// OnEpochEnd compares the metric with the best so far and stops the trainer when patience runs out.
+func (e *EarlyStopping) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	value, ok := metrics[e.Monitor]
+	if !ok {
+		return fmt.Errorf("ml: early stopping: no metric %q", e.Monitor)
+	}
+
+	better, err := improves(e.Mode, value, e.Best, e.MinDelta)
+	if err != nil {
+		return err
+	}
+
+	if e.BestEpoch == 0 || better {
+		e.Best, e.BestEpoch, e.wait = value, t.Epoch, 0
+		if e.RestoreBest {
+			e.bestNet = t.Net.Clone()
+		}
+		return nil
+	}
+
+	e.wait++
+	if e.wait >= e.Patience {
+		e.StoppedEpoch = t.Epoch
+		t.Stop, t.Result.Reason = true, StopEarly
+	}
+	return nil
+}
+
+// OnTrainEnd restores the weights of the best epoch, if asked to.
+func (e *EarlyStopping) OnTrainEnd(t *Trainer) error {
+	if e.RestoreBest && e.bestNet != nil {
+		*t.Net = *e.bestNet
+	}
+	return nil
+}
+
+// earlyStoppingState is what EarlyStopping keeps in a checkpoint.
+type earlyStoppingState struct {
+	Best      float64  `json:"best"`
+	BestEpoch int      `json:"best_epoch"`
+	Wait      int      `json:"wait"`
+	BestNet   *Network `json:"best_net,omitempty"`
+}
+
+// StateName returns the name of the state in a checkpoint.
+func (e *EarlyStopping) StateName() string {
+	return "early_stopping"
+}
+
+// State returns the best value so far, its epoch and weights, and the epochs waited since.
+func (e *EarlyStopping) State() (json.RawMessage, error) {
+	return json.Marshal(earlyStoppingState{e.Best, e.BestEpoch, e.wait, e.bestNet})
+}
+
+// SetState restores a state returned by State.
+func (e *EarlyStopping) SetState(state json.RawMessage) error {
+	var s earlyStoppingState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return fmt.Errorf("ml: early stopping state: %v", err)
+	}
+	e.Best, e.BestEpoch, e.wait, e.bestNet = s.Best, s.BestEpoch, s.Wait, s.BestNet
+	return nil
+}
@@ -0,0 +1,237 @@
+package ml_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"."
+)
+
+// stopAfter is a callback that stops training after the given epoch.
+type stopAfter int
+
+func (s stopAfter) OnEpochEnd(t *ml.Trainer, metrics ml.Metrics) error {
+	if t.Epoch >= int(s) {
+		t.Stop = true
+	}
+	return nil
+}
+
+func (s stopAfter) OnTrainEnd(t *ml.Trainer) error {
+	return nil
+}
+
+func TestFitHistory(t *testing.T) {
+	x, y := xorData()
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	trainer.Metrics = map[string]ml.MetricFunc{"accuracy": ml.BinaryAccuracy}
+
+	history := &ml.History{}
+	if err := trainer.Fit(x, y, x, y, 5, history); err != nil {
+		t.Fatal("Fit unexpected error:", err)
+	}
+	if !reflect.DeepEqual([]int{1, 2, 3, 4, 5}, history.Epochs) {
+		t.Errorf("History.Epochs: expected [1 2 3 4 5], actual %v", history.Epochs)
+	}
+	for _, m := range history.Metrics {
+		if _, ok := m["loss"]; !ok || len(m) != 3 {
+			t.Errorf("History.Metrics: expected loss, val_loss and val_accuracy, actual %v", m)
+		}
+	}
+
+	// the loss of an epoch is measured before its update, so it matches the val_loss of the epoch before
+	losses, valLosses := history.Values("loss"), history.Values("val_loss")
+	for i := 1; i < len(losses); i++ {
+		if losses[i] != valLosses[i-1] {
+			t.Errorf("History: loss of epoch %d is %v, expected val_loss of the previous epoch %v", i+1, losses[i], valLosses[i-1])
+		}
+	}
+
+	// Fit continues up to the total number of epochs
+	if err := trainer.Fit(x, y, nil, nil, 8, history); err != nil {
+		t.Fatal("Fit unexpected error:", err)
+	}
+	if len(history.Epochs) != 8 || len(history.Metrics[7]) != 1 {
+		t.Errorf("Fit(8): expected 8 epochs with only the loss last, actual %v %v", history.Epochs, history.Metrics[7])
+	}
+}
+
+func TestFitStop(t *testing.T) {
+	x, y := xorData()
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	if err := trainer.Fit(x, y, nil, nil, 100, stopAfter(3)); err != nil {
+		t.Fatal("Fit unexpected error:", err)
+	}
+	if trainer.Epoch != 3 {
+		t.Errorf("Fit: expected to stop after epoch 3, actual %v", trainer.Epoch)
+	}
+}
+
+func TestHistoryWrite(t *testing.T) {
+	history := &ml.History{
+		Epochs:  []int{1, 2},
+		Metrics: []ml.Metrics{{"val_loss": 0.5, "loss": 0.25}, {"val_loss": 0.125, "loss": 0.2}},
+	}
+
+	var buf bytes.Buffer
+	if err := history.WriteJSONL(&buf); err != nil {
+		t.Fatal("WriteJSONL unexpected error:", err)
+	}
+	expected := `{"epoch":1,"loss":0.25,"val_loss":0.5}` + "\n" + `{"epoch":2,"loss":0.2,"val_loss":0.125}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("WriteJSONL: expected %q, actual %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := history.WriteCSV(&buf); err != nil {
+		t.Fatal("WriteCSV unexpected error:", err)
+	}
+	expected = "epoch,loss,val_loss\n1,0.25,0.5\n2,0.2,0.125\n"
+	if buf.String() != expected {
+		t.Errorf("WriteCSV: expected %q, actual %q", expected, buf.String())
+	}
+}
+
+func TestCSVLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &ml.CSVLogger{W: &buf}
+	trainer := &ml.Trainer{Epoch: 1}
+	logger.OnEpochEnd(trainer, ml.Metrics{"val_loss": 0.5, "loss": 0.25})
+	if expected := "epoch,loss,val_loss\n1,0.25,0.5\n"; buf.String() != expected {
+		t.Errorf("CSVLogger: expected %q, actual %q", expected, buf.String())
+	}
+
+	// given the columns, it writes no header and leaves out the other metrics
+	buf.Reset()
+	logger = &ml.CSVLogger{W: &buf, Columns: []string{"val_loss", "lr"}}
+	trainer.Epoch = 2
+	logger.OnEpochEnd(trainer, ml.Metrics{"val_loss": 0.125, "loss": 0.2})
+	if expected := "2,0.125,\n"; buf.String() != expected {
+		t.Errorf("CSVLogger: expected %q, actual %q", expected, buf.String())
+	}
+}
+
+func TestProgressLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &ml.ProgressLogger{W: &buf, Every: 2}
+	trainer := &ml.Trainer{}
+	for i, loss := range []float64{0.5, 0.4, 0.3, 0.2, 0.35} {
+		trainer.Epoch = i + 1
+		logger.OnEpochEnd(trainer, ml.Metrics{"loss": loss})
+	}
+
+	expected := []string{
+		"Epoch 1: loss 0.5",
+		"Epoch 3: loss 0.3",
+		"Epoch 5: loss 0.35 WARNING - Loss Increasing",
+	}
+	actual := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("ProgressLogger: expected %q, actual %q", expected, actual)
+	}
+}
+
+func TestBinaryAccuracy(t *testing.T) {
+	yHat := [][]float64{{0.2}, {0.7}, {0.9}, {0.4}}
+	y := [][]float64{{0}, {1}, {0}, {0}}
+	expected := 0.75
+	actual := ml.BinaryAccuracy(yHat, y)
+	if actual != expected {
+		t.Errorf("BinaryAccuracy(%v, %v): expected %v, actual %v", yHat, y, expected, actual)
+	}
+}
+
+// epochMetrics is a callback that feeds made up metrics to the callbacks after it.
+type epochMetrics struct {
+	values    []float64
+	callbacks []ml.Callback
+}
+
+func (e *epochMetrics) OnEpochEnd(t *ml.Trainer, metrics ml.Metrics) error {
+	metrics["val_loss"] = e.values[t.Epoch-1]
+	for _, c := range e.callbacks {
+		if err := c.OnEpochEnd(t, metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *epochMetrics) OnTrainEnd(t *ml.Trainer) error {
+	for _, c := range e.callbacks {
+		if err := c.OnTrainEnd(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestEarlyStopping(t *testing.T) {
+	var tests = []struct {
+		mode      string    // direction of improvement
+		minDelta  float64   // smallest improvement
+		values    []float64 // metric of every epoch
+		bestEpoch int       // expected best epoch
+		stopped   int       // expected epoch training stopped at
+	}{
+		{ml.ModeMin, 0, []float64{5, 4, 3, 3.5, 3.2, 3.1, 2, 1, 1, 1}, 3, 5},
+		{ml.ModeMin, 0, []float64{5, 4, 3, 3.5, 2.9, 3.1, 2, 1, 0.5, 0.4}, 10, 0},
+		{ml.ModeMin, 0.5, []float64{5, 4, 3.6, 3.7, 3.8, 3, 2, 1, 0.5, 0.4}, 2, 4},
+		{ml.ModeMax, 0, []float64{0.5, 0.6, 0.7, 0.7, 0.7, 0.7, 0.8, 0.9, 1, 1}, 3, 5},
+	}
+
+	x, y := xorData()
+	for _, test := range tests {
+		opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+		trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+		stopping := &ml.EarlyStopping{Monitor: "val_loss", Mode: test.mode, Patience: 2, MinDelta: test.minDelta}
+		feed := &epochMetrics{test.values, []ml.Callback{stopping}}
+		if err := trainer.Fit(x, y, nil, nil, len(test.values), feed); err != nil {
+			t.Fatal("Fit unexpected error:", err)
+		}
+		if stopping.BestEpoch != test.bestEpoch || stopping.StoppedEpoch != test.stopped {
+			t.Errorf("EarlyStopping(%v, %v): expected best epoch %v and stop at %v, actual %v and %v",
+				test.values, test.mode, test.bestEpoch, test.stopped, stopping.BestEpoch, stopping.StoppedEpoch)
+		}
+		if test.stopped != 0 && (trainer.Epoch != test.stopped || trainer.Result.Reason != ml.StopEarly) {
+			t.Errorf("EarlyStopping(%v): expected training to end at epoch %v by %s, actual %v by %s", test.values, test.stopped, ml.StopEarly, trainer.Epoch, trainer.Result.Reason)
+		}
+	}
+}
+
+func TestEarlyStoppingRestoreBest(t *testing.T) {
+	x, y := xorData()
+	values := []float64{3, 2, 1, 4, 5}
+
+	// the weights after the third epoch
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	trainer.Fit(x, y, nil, nil, 3)
+	expected := trainer.Net.Params()
+
+	opt, _ = ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer = ml.NewTrainer(xorNetwork(), opt, 1)
+	stopping := &ml.EarlyStopping{Monitor: "val_loss", Mode: ml.ModeMin, Patience: 1, RestoreBest: true}
+	if err := trainer.Fit(x, y, nil, nil, len(values), &epochMetrics{values, []ml.Callback{stopping}}); err != nil {
+		t.Fatal("Fit unexpected error:", err)
+	}
+	for i, p := range trainer.Net.Params() {
+		if !ml.MatrixEquals(expected[i].Value, p.Value) {
+			t.Errorf("EarlyStopping: expected the weights of epoch 3 for %s, actual %v", p.Name, p.Value)
+		}
+	}
+}
+
+func TestEarlyStoppingUnknownMetric(t *testing.T) {
+	x, y := xorData()
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	stopping := &ml.EarlyStopping{Monitor: "val_auc", Mode: ml.ModeMax}
+	if err := trainer.Fit(x, y, nil, nil, 3, stopping); err == nil {
+		t.Errorf("Fit: expected err != nil for an unknown metric")
+	}
+}
@@ -0,0 +1,212 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CheckpointVersion is the version of the checkpoint format written by Checkpointer. Version 2
+// added the schedule and the state of the callbacks.
+const CheckpointVersion = 2
+
+// bestCheckpoint is the file name of the checkpoint with the lowest validation loss.
+const bestCheckpoint = "best.json"
+
+// Checkpoint is a snapshot of a trainer: restoring it continues training exactly as if it had never stopped.
+type Checkpoint struct {
+	Version   int       `json:"version"`
+	Epoch     int       `json:"epoch"`
+	Network   Network   `json:"network"`
+	Optimizer Optimizer `json:"optimizer"`
+	RandState []byte    `json:"rand_state"`
+	ValLoss   float64   `json:"val_loss"`
+
+	Schedule  *Schedule                  `json:"schedule,omitempty"`  // as lowered by ReduceOnPlateau
+	Callbacks map[string]json.RawMessage `json:"callbacks,omitempty"` // of every StatefulCallback, by StateName
+}
+
+// Checkpoint takes a snapshot of the trainer, noting the given validation loss. During Fit it
+// includes the state of the callbacks, so a Checkpointer should come after the callbacks whose
+// state it saves.
+func (t *Trainer) Checkpoint(valLoss float64) (*Checkpoint, error) {
+	state, err := t.RandState()
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{
+		Version:   CheckpointVersion,
+		Epoch:     t.Epoch,
+		Network:   *t.Net,
+		Optimizer: *t.Opt,
+		RandState: state,
+		ValLoss:   valLoss,
+	}
+	if t.Schedule != nil {
+		schedule := *t.Schedule
+		c.Schedule = &schedule
+	}
+	for _, callback := range t.callbacks {
+		if s, ok := callback.(StatefulCallback); ok {
+			state, err := s.State()
+			if err != nil {
+				return nil, err
+			}
+			if c.Callbacks == nil {
+				c.Callbacks = map[string]json.RawMessage{}
+			}
+			c.Callbacks[s.StateName()] = state
+		}
+	}
+	return c, nil
+}
+
+// Restore puts the trainer, its network, its optimizer and its schedule back in the state of the
+// checkpoint. The callbacks get their state back when the next Fit starts.
+func (t *Trainer) Restore(c *Checkpoint) error {
+	if err := t.SetRandState(c.RandState); err != nil {
+		return err
+	}
+	*t.Net = c.Network
+	*t.Opt = c.Optimizer
+	t.Epoch = c.Epoch
+	if c.Schedule != nil && t.Schedule != nil {
+		*t.Schedule = *c.Schedule
+	}
+	t.states = c.Callbacks
+	return nil
+}
+
+// LoadCheckpoint reads a checkpoint written by Checkpointer.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	c := &Checkpoint{}
+	if err := readJSON(path, c); err != nil {
+		return nil, err
+	}
+
+	if c.Version < 1 || c.Version > CheckpointVersion {
+		return nil, fmt.Errorf("ml: %s: unsupported checkpoint version %d", path, c.Version)
+	}
+	if err := c.Network.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return c, nil
+}
+
+// LatestCheckpoint returns the path of the most recent periodic checkpoint in the directory.
+func LatestCheckpoint(dir string) (string, error) {
+	paths, err := checkpointPaths(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("ml: no checkpoints in %s", dir)
+	}
+	return paths[len(paths)-1], nil
+}
+
+// BestCheckpoint returns the path of the checkpoint with the lowest validation loss in the directory.
+func BestCheckpoint(dir string) string {
+	return filepath.Join(dir, bestCheckpoint)
+}
+
+// checkpointPaths returns the periodic checkpoints in the directory, oldest first.
+func checkpointPaths(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "epoch-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths) // the epoch is zero padded
+	return paths, nil
+}
+
+// Checkpointer saves checkpoints of a trainer to a directory.
+type Checkpointer struct {
+	Dir   string
+	Every int // epochs between periodic checkpoints
+	Keep  int // number of periodic checkpoints kept, 0 keeps them all
+
+	best    float64
+	hasBest bool
+}
+
+// Save should be called at the end of every epoch. Every Every epochs it writes a checkpoint and
+// deletes the old ones beyond Keep; whenever valLoss is the lowest seen so far it also overwrites
+// best.json, which is never deleted.
+func (c *Checkpointer) Save(t *Trainer, valLoss float64) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	// pick up the best loss of an earlier run after resuming
+	if !c.hasBest {
+		if best, err := LoadCheckpoint(BestCheckpoint(c.Dir)); err == nil {
+			c.best, c.hasBest = best.ValLoss, true
+		}
+	}
+
+	periodic := c.Every > 0 && t.Epoch%c.Every == 0
+	better := !c.hasBest || valLoss < c.best
+	if !periodic && !better {
+		return nil
+	}
+
+	ckpt, err := t.Checkpoint(valLoss)
+	if err != nil {
+		return err
+	}
+
+	if better {
+		if err := writeJSON(BestCheckpoint(c.Dir), ckpt); err != nil {
+			return err
+		}
+		c.best, c.hasBest = valLoss, true
+	}
+
+	if periodic {
+		path := filepath.Join(c.Dir, fmt.Sprintf("epoch-%08d.json", t.Epoch))
+		if err := writeJSON(path, ckpt); err != nil {
+			return err
+		}
+		return c.prune()
+	}
+
+	return nil
+}
+
+// OnEpochEnd saves checkpoints as a callback of Trainer.Fit, judging them by their validation
+// loss, or by their training loss if there is no validation data.
+func (c *Checkpointer) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	loss, ok := metrics["val_loss"]
+	if !ok {
+		loss = metrics["loss"]
+	}
+	return c.Save(t, loss)
+}
+
+// OnTrainEnd does nothing.
+func (c *Checkpointer) OnTrainEnd(t *Trainer) error {
+	return nil
+}
+
+// prune deletes the oldest periodic checkpoints until only Keep are left.
+func (c *Checkpointer) prune() error {
+	if c.Keep <= 0 {
+		return nil
+	}
+
+	paths, err := checkpointPaths(c.Dir)
+	if err != nil {
+		return err
+	}
+	for len(paths) > c.Keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
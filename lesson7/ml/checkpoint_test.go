@@ -0,0 +1,231 @@
+package ml_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"."
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	x, y := xorData()
+	newTrainer := func() *ml.Trainer {
+		opt, _ := ml.NewOptimizer(ml.OptimizerAdam, 0.05)
+		trainer := ml.NewTrainer(xorNetwork(), opt, 42)
+		trainer.BatchSize = 3
+		return trainer
+	}
+
+	// uninterrupted run
+	straight := newTrainer()
+	for straight.Epoch < 10 {
+		straight.TrainEpoch(x, y)
+	}
+
+	// interrupted after 6 epochs
+	first := newTrainer()
+	ckpts := &ml.Checkpointer{Dir: dir, Every: 3, Keep: 1}
+	for first.Epoch < 6 {
+		first.TrainEpoch(x, y)
+		if err := ckpts.Save(first, first.Evaluate(x, y)); err != nil {
+			t.Fatal("Save unexpected error:", err)
+		}
+	}
+
+	// resume in a fresh trainer with a different seed
+	path, err := ml.LatestCheckpoint(dir)
+	if err != nil {
+		t.Fatal("LatestCheckpoint unexpected error:", err)
+	}
+	if filepath.Base(path) != "epoch-00000006.json" {
+		t.Errorf("LatestCheckpoint: expected epoch-00000006.json, actual %v", path)
+	}
+	ckpt, err := ml.LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal("LoadCheckpoint unexpected error:", err)
+	}
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 1)
+	resumed := ml.NewTrainer(&ml.Network{}, opt, 1)
+	resumed.BatchSize = 3
+	if err := resumed.Restore(ckpt); err != nil {
+		t.Fatal("Restore unexpected error:", err)
+	}
+	for resumed.Epoch < 10 {
+		resumed.TrainEpoch(x, y)
+	}
+
+	if !reflect.DeepEqual(straight.Net.Params(), resumed.Net.Params()) {
+		t.Errorf("resumed training: expected %v, actual %v", straight.Net.Params(), resumed.Net.Params())
+	}
+}
+
+func TestCheckpointerKeepAndBest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.1)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	ckpts := &ml.Checkpointer{Dir: dir, Every: 2, Keep: 2}
+
+	losses := []float64{0.5, 0.3, 0.4, 0.2, 0.6, 0.7, 0.9, 0.8}
+	for _, loss := range losses {
+		trainer.Epoch++
+		if err := ckpts.Save(trainer, loss); err != nil {
+			t.Fatal("Save unexpected error:", err)
+		}
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	expected := []string{"best.json", "epoch-00000006.json", "epoch-00000008.json"}
+	if !reflect.DeepEqual(expected, names) {
+		t.Errorf("checkpoint files: expected %v, actual %v", expected, names)
+	}
+
+	best, err := ml.LoadCheckpoint(ml.BestCheckpoint(dir))
+	if err != nil {
+		t.Fatal("LoadCheckpoint unexpected error:", err)
+	}
+	if best.Epoch != 4 || best.ValLoss != 0.2 {
+		t.Errorf("best checkpoint: expected epoch 4 with loss 0.2, actual epoch %v with loss %v", best.Epoch, best.ValLoss)
+	}
+
+	// a new checkpointer on the same directory remembers the best loss
+	ckpts = &ml.Checkpointer{Dir: dir, Every: 100}
+	trainer.Epoch++
+	if err := ckpts.Save(trainer, 0.25); err != nil {
+		t.Fatal("Save unexpected error:", err)
+	}
+	best, _ = ml.LoadCheckpoint(ml.BestCheckpoint(dir))
+	if best.Epoch != 4 {
+		t.Errorf("best checkpoint after resume: expected epoch 4, actual %v", best.Epoch)
+	}
+}
+
+func TestLatestCheckpointEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := ml.LatestCheckpoint(dir); err == nil {
+		t.Errorf("LatestCheckpoint(empty): expected err != nil")
+	}
+}
+
+// resumedFit fits the XOR data for an epoch per value with a trainer and callbacks from the given
+// functions, stopping after interrupt epochs, and carries on to the end in a fresh trainer restored
+// from the checkpoint of the last epoch; with interrupt 0 it runs straight through. The trainers
+// measure val_fake, the value of every epoch.
+func resumedFit(t *testing.T, values []float64, interrupt int, newTrainer func() *ml.Trainer, newCallbacks func() []ml.Callback) (*ml.Trainer, []ml.Callback) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	x, y := xorData()
+	fit := func(epochs int, ckpt *ml.Checkpoint) (*ml.Trainer, []ml.Callback) {
+		trainer := newTrainer()
+		trainer.Metrics = map[string]ml.MetricFunc{"fake": func(yHat, y [][]float64) float64 {
+			return values[trainer.Epoch-1]
+		}}
+		if ckpt != nil {
+			if err := trainer.Restore(ckpt); err != nil {
+				t.Fatal("Restore unexpected error:", err)
+			}
+		}
+		callbacks := newCallbacks()
+		ckpts := &ml.Checkpointer{Dir: dir, Every: 1}
+		if err := trainer.Fit(x, y, x, y, epochs, append(callbacks, ckpts)...); err != nil {
+			t.Fatal("Fit unexpected error:", err)
+		}
+		return trainer, callbacks
+	}
+
+	if interrupt == 0 {
+		return fit(len(values), nil)
+	}
+	fit(interrupt, nil)
+	path, err := ml.LatestCheckpoint(dir)
+	if err != nil {
+		t.Fatal("LatestCheckpoint unexpected error:", err)
+	}
+	ckpt, err := ml.LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal("LoadCheckpoint unexpected error:", err)
+	}
+	return fit(len(values), ckpt)
+}
+
+func TestCheckpointResumeEarlyStopping(t *testing.T) {
+	values := []float64{5, 4, 3, 3.5, 3.2, 2, 2.5, 2.4, 2.6, 2.2, 2.1, 2}
+	newTrainer := func() *ml.Trainer {
+		opt, _ := ml.NewOptimizer(ml.OptimizerAdam, 0.05)
+		trainer := ml.NewTrainer(xorNetwork(), opt, 42)
+		trainer.BatchSize = 3
+		return trainer
+	}
+	newCallbacks := func() []ml.Callback {
+		return []ml.Callback{&ml.EarlyStopping{Monitor: "val_fake", Mode: ml.ModeMin, Patience: 3, RestoreBest: true}}
+	}
+
+	// uninterrupted, it stops 3 epochs after the best
+	straight, callbacks := resumedFit(t, values, 0, newTrainer, newCallbacks)
+	stopping := callbacks[0].(*ml.EarlyStopping)
+	if stopping.BestEpoch != 6 || stopping.StoppedEpoch != 9 {
+		t.Errorf("EarlyStopping: expected best epoch 6 and stop at 9, actual %v and %v", stopping.BestEpoch, stopping.StoppedEpoch)
+	}
+
+	// and so it does when interrupted while waiting, restoring the same weights
+	resumed, callbacks := resumedFit(t, values, 7, newTrainer, newCallbacks)
+	stopping = callbacks[0].(*ml.EarlyStopping)
+	if stopping.BestEpoch != 6 || stopping.StoppedEpoch != 9 {
+		t.Errorf("resumed EarlyStopping: expected best epoch 6 and stop at 9, actual %v and %v", stopping.BestEpoch, stopping.StoppedEpoch)
+	}
+	if !reflect.DeepEqual(straight.Net.Params(), resumed.Net.Params()) {
+		t.Errorf("resumed training: expected %v, actual %v", straight.Net.Params(), resumed.Net.Params())
+	}
+}
+
+func TestCheckpointResumePlateau(t *testing.T) {
+	values := []float64{5, 4, 4, 4, 3, 3, 3, 3, 3, 3}
+	newTrainer := func() *ml.Trainer {
+		opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+		trainer := ml.NewTrainer(xorNetwork(), opt, 42)
+		trainer.BatchSize = 3
+		trainer.Schedule = &ml.Schedule{Type: ml.ScheduleCosine, Rate: 0.5, Step: 4}
+		return trainer
+	}
+	newCallbacks := func() []ml.Callback {
+		return []ml.Callback{&ml.ReduceOnPlateau{Monitor: "val_fake", Mode: ml.ModeMin, Factor: 0.5, Patience: 2}}
+	}
+
+	// the rate is lowered after epochs 4, 7 and 9, the resumed run picking up the first and the wait
+	straight, _ := resumedFit(t, values, 0, newTrainer, newCallbacks)
+	if straight.Schedule.Rate != 0.0625 {
+		t.Errorf("ReduceOnPlateau: expected the schedule rate lowered to 0.0625, actual %v", straight.Schedule.Rate)
+	}
+	resumed, _ := resumedFit(t, values, 6, newTrainer, newCallbacks)
+	if resumed.Schedule.Rate != straight.Schedule.Rate {
+		t.Errorf("resumed ReduceOnPlateau: expected schedule rate %v, actual %v", straight.Schedule.Rate, resumed.Schedule.Rate)
+	}
+	if !reflect.DeepEqual(straight.Net.Params(), resumed.Net.Params()) {
+		t.Errorf("resumed training: expected %v, actual %v", straight.Net.Params(), resumed.Net.Params())
+	}
+}
@@ -0,0 +1,101 @@
+package ml
+
+import (
+	"math"
+)
+
+// Softmax turns every row of scores into probabilities that sum to one.
+func Softmax(m [][]float64) [][]float64 {
+	result := make([][]float64, len(m))
+	for i, row := range m {
+		// shift by the largest score so that exp cannot overflow
+		max := math.Inf(-1)
+		for _, x := range row {
+			max = math.Max(max, x)
+		}
+
+		result[i] = make([]float64, len(row))
+		sum := float64(0)
+		for j, x := range row {
+			result[i][j] = math.Exp(x - max)
+			sum += result[i][j]
+		}
+		for j := range row {
+			result[i][j] /= sum
+		}
+	}
+	return result
+}
+
+// OneHot returns a row per label with a one in the column of the label, which counts from 0,
+// and zeros in the others.
+func OneHot(labels []int, numClasses int) [][]float64 {
+	result := FilledMatrix(len(labels), numClasses, 0)
+	for i, label := range labels {
+		result[i][label] = 1
+	}
+	return result
+}
+
+// Argmax returns the column of the largest value of every row, the first one on ties.
+func Argmax(m [][]float64) []int {
+	result := make([]int, len(m))
+	for i, row := range m {
+		for j, x := range row {
+			if x > row[result[i]] {
+				result[i] = j
+			}
+		}
+	}
+	return result
+}
+
+// labels returns the class of every row of targets, which are either one-hot or, when they have
+// a single column, the class labels themselves.
+func labels(y [][]float64) []int {
+	if len(y) > 0 && len(y[0]) == 1 {
+		result := make([]int, len(y))
+		for i, row := range y {
+			result[i] = int(row[0])
+		}
+		return result
+	}
+	return Argmax(y)
+}
+
+// oneHotTargets returns the targets one-hot encoded for predictions with the given number of
+// classes, converting a single column of labels if needed.
+func oneHotTargets(y [][]float64, numClasses int) [][]float64 {
+	if numClasses > 1 && len(y) > 0 && len(y[0]) == 1 {
+		return OneHot(labels(y), numClasses)
+	}
+	return y
+}
+
+// CategoricalAccuracy returns the fraction of rows whose most probable class is the target class.
+// The targets are one-hot rows or a single column of labels.
+func CategoricalAccuracy(yHat, y [][]float64) float64 {
+	return TopKAccuracy(1)(yHat, y)
+}
+
+// TopKAccuracy returns a metric measuring the fraction of rows whose target class is among the k
+// most probable ones. The targets are one-hot rows or a single column of labels.
+func TopKAccuracy(k int) MetricFunc {
+	return func(yHat, y [][]float64) float64 {
+		targets := labels(y)
+		hits := 0
+		for i, row := range yHat {
+			// count the classes more probable than the target
+			better := 0
+			for _, p := range row {
+				if p > row[targets[i]] {
+					better++
+				}
+			}
+			if better < k {
+				hits++
+			}
+		}
+		return float64(hits) / float64(len(yHat))
+	}
+}
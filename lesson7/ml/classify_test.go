@@ -0,0 +1,64 @@
+package ml_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"."
+)
+
+func TestSoftmax(t *testing.T) {
+	input := [][]float64{{1, 2, 3}, {1000, 1000, 0}}
+	e := math.E
+	sum := 1 + e + e*e
+	expected := [][]float64{{1 / sum, e / sum, e * e / sum}, {0.5, 0.5, 0}}
+	actual := ml.Softmax(input)
+	if !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Softmax(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
+
+func TestOneHot(t *testing.T) {
+	input := []int{2, 0, 1}
+	expected := [][]float64{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}}
+	actual := ml.OneHot(input, 3)
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("OneHot(%v, 3): expected %v, actual %v", input, expected, actual)
+	}
+}
+
+func TestArgmax(t *testing.T) {
+	input := [][]float64{{0.1, 0.7, 0.2}, {3, 3, 1}, {-1, -2, -0.5}}
+	expected := []int{1, 0, 2}
+	actual := ml.Argmax(input)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Argmax(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
+
+func TestTopKAccuracy(t *testing.T) {
+	yHat := [][]float64{{0.1, 0.6, 0.3}, {0.5, 0.2, 0.3}, {0.2, 0.3, 0.5}, {0.4, 0.35, 0.25}}
+	oneHot := [][]float64{{0, 1, 0}, {0, 0, 1}, {1, 0, 0}, {0, 0, 1}}
+	labels := [][]float64{{1}, {2}, {0}, {2}}
+
+	var tests = []struct {
+		k        int
+		expected float64
+	}{
+		{1, 0.25},
+		{2, 0.5},
+		{3, 1},
+	}
+
+	for _, y := range [][][]float64{oneHot, labels} {
+		for _, test := range tests {
+			if actual := ml.TopKAccuracy(test.k)(yHat, y); actual != test.expected {
+				t.Errorf("TopKAccuracy(%v)(%v, %v): expected %v, actual %v", test.k, yHat, y, test.expected, actual)
+			}
+		}
+		if actual := ml.CategoricalAccuracy(yHat, y); actual != 0.25 {
+			t.Errorf("CategoricalAccuracy(%v, %v): expected 0.25, actual %v", yHat, y, actual)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Reasons an iterative fit stops, as recorded in a FitResult.
+const (
+	StopMaxSteps    = "max_steps"    // it took all the steps it was given
+	StopGradNorm    = "grad_norm"    // the gradient got small enough
+	StopLossChange  = "loss_change"  // the loss stopped changing
+	StopParamChange = "param_change" // the parameters stopped changing
+	StopTimeLimit   = "time_limit"   // it ran out of time
+	StopDiverged    = "diverged"     // the loss or the parameter change is no longer a finite number
+	StopEarly       = "early_stopping"
+	StopCallback    = "callback" // a callback set the trainer's Stop field
+)
+
+// Convergence decides when an iterative fit has converged, or has run long enough, to stop before
+// its last step. A zero field turns its test off. The loss and parameter change tests have to pass
+// Patience steps in a row, so that a noisy minibatch step does not stop the fit by chance.
+type Convergence struct {
+	GradNorm    float64       // stop once the norm of the gradient is at most this
+	LossChange  float64       // stop once the change of the loss over its size is at most this
+	ParamChange float64       // stop once the norm of the change of the parameters over their norm (at least 1) is at most this
+	TimeLimit   time.Duration // stop once the fit has taken this long
+	Patience    int           // steps in a row the change tests have to pass, 1 if 0
+
+	start    time.Time
+	lastLoss float64
+	steps    int
+	calm     int
+}
+
+// FitResult says why an iterative fit stopped and how far it got.
+type FitResult struct {
+	Reason  string // one of the Stop constants
+	Steps   int    // taken by the fit
+	Loss    float64
+	Elapsed time.Duration
+}
+
+// Converged checks if the fit stopped because it converged, rather than because it ran out of
+// steps or time or was stopped.
+func (r FitResult) Converged() bool {
+	return r.Reason == StopGradNorm || r.Reason == StopLossChange || r.Reason == StopParamChange
+}
+
+// Validate checks that no threshold is negative.
+func (c *Convergence) Validate() error {
+	if c != nil && (c.GradNorm < 0 || c.LossChange < 0 || c.ParamChange < 0 || c.TimeLimit < 0 || c.Patience < 0) {
+		return fmt.Errorf("ml: negative convergence threshold in grad norm %v, loss change %v, param change %v, time limit %v, patience %v",
+			c.GradNorm, c.LossChange, c.ParamChange, c.TimeLimit, c.Patience)
+	}
+	return nil
+}
+
+// Start starts the clock of the time limit and forgets the steps of an earlier fit.
+func (c *Convergence) Start() {
+	c.start, c.steps, c.calm = time.Now(), 0, 0
+}
+
+// Elapsed returns the time since Start.
+func (c *Convergence) Elapsed() time.Duration {
+	return time.Since(c.start)
+}
+
+// NeedsParamChange checks if the caller has to measure how much the parameters changed.
+func (c *Convergence) NeedsParamChange() bool {
+	return c != nil && c.ParamChange > 0
+}
+
+// Check takes note of a step, after which the loss is loss, the gradient had the norm gradNorm
+// and the parameters changed by paramChange relative to their size, and returns the reason to
+// stop there or "" to go on. Every Convergence stops a fit that diverged, a nil one only stops that.
+func (c *Convergence) Check(loss, gradNorm, paramChange float64) string {
+	if !finite(loss) || !finite(paramChange) {
+		return StopDiverged
+	}
+	if c == nil {
+		return ""
+	}
+	if c.start.IsZero() {
+		c.Start()
+	}
+	c.steps++
+	lastLoss := c.lastLoss
+	c.lastLoss = loss
+	if c.GradNorm > 0 && gradNorm <= c.GradNorm {
+		return StopGradNorm
+	}
+
+	// the change tests, which need a previous step for the loss
+	var reason string
+	if c.LossChange > 0 && c.steps > 1 && math.Abs(loss-lastLoss) <= c.LossChange*math.Max(math.Abs(lastLoss), 1e-300) {
+		reason = StopLossChange
+	} else if c.ParamChange > 0 && paramChange <= c.ParamChange {
+		reason = StopParamChange
+	}
+	if reason == "" {
+		c.calm = 0
+	} else if c.calm++; c.calm >= c.Patience {
+		return reason
+	}
+
+	if c.TimeLimit > 0 && time.Since(c.start) >= c.TimeLimit {
+		return StopTimeLimit
+	}
+	return ""
+}
+
+// RelativeChange returns the norm of the difference of the values over the norm of the old ones,
+// or over 1 if they are smaller, so that parameters near 0 are compared absolutely.
+func RelativeChange(old, new [][]float64) float64 {
+	var diff, size float64
+	for i, row := range old {
+		for j, v := range row {
+			diff += (new[i][j] - v) * (new[i][j] - v)
+			size += v * v
+		}
+	}
+	return math.Sqrt(diff) / math.Max(math.Sqrt(size), 1)
+}
+
+// finite checks that v is neither NaN nor infinite.
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// notFinite returns the name of the first parameter with a value that is not finite, or "".
+func notFinite(params []Param) string {
+	for _, p := range params {
+		for _, row := range p.Value {
+			for _, v := range row {
+				if !finite(v) {
+					return p.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Norm returns the Frobenius norm of the matrix, the square root of the sum of its squares.
+func Norm(m [][]float64) float64 {
+	var sum float64
+	for _, row := range m {
+		for _, v := range row {
+			sum += v * v
+		}
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,149 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"."
+)
+
+func TestConvergenceCheck(t *testing.T) {
+	var tests = []struct {
+		convergence ml.Convergence
+		losses      []float64
+		gradNorms   []float64
+		changes     []float64
+		expected    string
+		steps       int // at which it stops, counting from 1
+	}{
+		// nothing to test, never stops
+		{ml.Convergence{}, []float64{1, 1, 1}, []float64{0, 0, 0}, []float64{0, 0, 0}, "", 0},
+		{ml.Convergence{GradNorm: 0.1}, []float64{3, 2, 1}, []float64{1, 0.5, 0.1}, []float64{1, 1, 1}, ml.StopGradNorm, 3},
+		// the first loss has nothing to compare with
+		{ml.Convergence{LossChange: 0.01}, []float64{1, 0.5, 0.499, 0.3}, []float64{1, 1, 1, 1}, []float64{1, 1, 1, 1}, ml.StopLossChange, 3},
+		// the loss has to settle twice in a row
+		{ml.Convergence{LossChange: 0.01, Patience: 2}, []float64{1, 0.999, 0.5, 0.499, 0.498}, []float64{1, 1, 1, 1, 1}, []float64{1, 1, 1, 1, 1}, ml.StopLossChange, 5},
+		{ml.Convergence{ParamChange: 1e-3}, []float64{3, 2, 1}, []float64{1, 1, 1}, []float64{0.1, 0.01, 0.001}, ml.StopParamChange, 3},
+		// a loss or change that is no longer a number stops it, whatever it tests
+		{ml.Convergence{}, []float64{1, math.Inf(1), 1}, []float64{0, 0, 0}, []float64{0, 0, 0}, ml.StopDiverged, 2},
+		{ml.Convergence{LossChange: 0.01}, []float64{1, math.NaN(), math.NaN()}, []float64{1, 1, 1}, []float64{1, 1, 1}, ml.StopDiverged, 2},
+		{ml.Convergence{ParamChange: 1e-3}, []float64{3, 2, 1}, []float64{1, 1, 1}, []float64{0.1, math.NaN(), 0.001}, ml.StopDiverged, 2},
+	}
+
+	for _, test := range tests {
+		c := test.convergence
+		c.Start()
+		var reason string
+		var steps int
+		for i := range test.losses {
+			if reason = c.Check(test.losses[i], test.gradNorms[i], test.changes[i]); reason != "" {
+				steps = i + 1
+				break
+			}
+		}
+		if reason != test.expected || steps != test.steps {
+			t.Errorf("Check(%+v): expected %q at step %d, actual %q at step %d", test.convergence, test.expected, test.steps, reason, steps)
+		}
+	}
+
+	c := &ml.Convergence{TimeLimit: time.Millisecond}
+	c.Start()
+	time.Sleep(2 * time.Millisecond)
+	if reason := c.Check(1, 1, 1); reason != ml.StopTimeLimit {
+		t.Errorf("Check after the time limit: expected %q, actual %q", ml.StopTimeLimit, reason)
+	}
+	var none *ml.Convergence
+	if reason := none.Check(0, 0, 0); reason != "" || none.NeedsParamChange() {
+		t.Errorf("Check of a nil Convergence: expected \"\", actual %q", reason)
+	}
+	if reason := none.Check(math.NaN(), 0, 0); reason != ml.StopDiverged {
+		t.Errorf("Check of a nil Convergence with a NaN loss: expected %q, actual %q", ml.StopDiverged, reason)
+	}
+	if err := (&ml.Convergence{LossChange: -1}).Validate(); err == nil {
+		t.Errorf("Validate(LossChange -1): expected err != nil")
+	}
+
+	// running out of steps or time is no convergence
+	for reason, expected := range map[string]bool{ml.StopGradNorm: true, ml.StopLossChange: true, ml.StopParamChange: true, ml.StopMaxSteps: false, ml.StopTimeLimit: false, ml.StopEarly: false, ml.StopDiverged: false} {
+		if actual := (ml.FitResult{Reason: reason}).Converged(); actual != expected {
+			t.Errorf("Converged(%s): expected %v, actual %v", reason, expected, actual)
+		}
+	}
+}
+
+func TestRelativeChange(t *testing.T) {
+	var tests = []struct {
+		old, new [][]float64
+		expected float64
+	}{
+		{[][]float64{{3}, {4}}, [][]float64{{3}, {4}}, 0},
+		{[][]float64{{3}, {4}}, [][]float64{{3.3}, {4.4}}, 0.1},
+		// compared absolutely below 1
+		{[][]float64{{0.1}}, [][]float64{{0.2}}, 0.1},
+	}
+
+	for _, test := range tests {
+		if actual := ml.RelativeChange(test.old, test.new); math.Abs(test.expected-actual) > 1e-12 {
+			t.Errorf("RelativeChange(%v, %v): expected %v, actual %v", test.old, test.new, test.expected, actual)
+		}
+	}
+	if norm := ml.Norm([][]float64{{3, 4}}); norm != 5 {
+		t.Errorf("Norm([[3 4]]): expected 5, actual %v", norm)
+	}
+}
+
+func TestFitConvergence(t *testing.T) {
+	x, y := xorData()
+	for _, c := range []*ml.Convergence{{LossChange: 1e-4}, {ParamChange: 1e-4}, {GradNorm: 0.05}} {
+		opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+		trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+		trainer.Convergence = c
+		if err := trainer.Fit(x, y, nil, nil, 100000); err != nil {
+			t.Fatal("Fit unexpected error:", err)
+		}
+		r := trainer.Result
+		if r.Reason == ml.StopMaxSteps || r.Steps != trainer.Epoch || r.Steps >= 100000 || r.Loss <= 0 {
+			t.Errorf("Fit(%+v): expected to stop on convergence before 100000 epochs, actual %+v", *c, r)
+		}
+	}
+
+	// without convergence it runs to the end
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	trainer.Fit(x, y, nil, nil, 10)
+	if r := trainer.Result; r.Reason != ml.StopMaxSteps || r.Steps != 10 {
+		t.Errorf("Fit(10 epochs): expected %q after 10, actual %+v", ml.StopMaxSteps, r)
+	}
+	trainer.Fit(x, y, nil, nil, 15, stopAfter(12))
+	if r := trainer.Result; r.Reason != ml.StopCallback || r.Steps != 2 {
+		t.Errorf("Fit(stopped at epoch 12): expected %q after 2 more epochs, actual %+v", ml.StopCallback, r)
+	}
+
+	// but not past the epoch its loss blows up in, with a learning rate far too large
+	linear := func() *ml.Network {
+		return &ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{0}, {0}}, Bias: []float64{0}, Activation: ml.ActivationLinear},
+		}}
+	}
+	opt, _ = ml.NewOptimizer(ml.OptimizerSGD, 1000)
+	trainer = ml.NewTrainer(linear(), opt, 1)
+	trainer.Fit(x, y, nil, nil, 100000)
+	if r := trainer.Result; r.Reason != ml.StopDiverged || r.Steps >= 100000 {
+		t.Errorf("Fit(learning rate 1000): expected %q before 100000 epochs, actual %+v", ml.StopDiverged, r)
+	}
+
+	// and before the callbacks, so no checkpoint or log holds what diverged
+	history := &ml.History{}
+	opt, _ = ml.NewOptimizer(ml.OptimizerSGD, 1000)
+	trainer = ml.NewTrainer(linear(), opt, 1)
+	trainer.Fit(x, y, nil, nil, 100000, history)
+	for i, m := range history.Metrics {
+		if loss := m["loss"]; math.IsNaN(loss) || math.IsInf(loss, 0) {
+			t.Errorf("Fit(learning rate 1000): expected finite losses in the history, actual %v at epoch %v", loss, history.Epochs[i])
+		}
+	}
+	if len(history.Epochs) == 0 || len(history.Epochs) != trainer.Epoch-1 {
+		t.Errorf("Fit(learning rate 1000): expected the callbacks to miss the last of %v epochs, actual %v", trainer.Epoch, history.Epochs)
+	}
+}
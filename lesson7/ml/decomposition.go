@@ -0,0 +1,204 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxSweeps bounds the passes over all pairs of columns made by the Jacobi methods, which
+// converge quadratically and normally need fewer than ten.
+const maxSweeps = 100
+
+// SymmetricEigen returns the eigenvalues of the symmetric matrix a in decreasing order, and the
+// matching unit eigenvectors as the columns of vectors, so that a·vectors = vectors·diag(values).
+// It uses cyclic Jacobi rotations. The sign of every eigenvector makes its largest component positive.
+func SymmetricEigen(a [][]float64) (values []float64, vectors [][]float64, err error) {
+	if err := checkSquare(a); err != nil {
+		return nil, nil, err
+	}
+	if err := checkSymmetric(a); err != nil {
+		return nil, nil, err
+	}
+	n := len(a)
+	d := copyMatrix(a)
+	v := Identity(n)
+
+	var total float64
+	for _, row := range d {
+		for _, x := range row {
+			total += x * x
+		}
+	}
+
+	for sweep := 0; ; sweep++ {
+		var off float64
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += 2 * d[p][q] * d[p][q]
+			}
+		}
+		if off <= 1e-30*total {
+			break
+		}
+		if sweep == maxSweeps {
+			return nil, nil, fmt.Errorf("ml: eigendecomposition did not converge in %d sweeps", maxSweeps)
+		}
+
+		// rotate every pair p, q to zero d[p][q]
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if d[p][q] == 0 {
+					continue
+				}
+				c, s := jacobiRotation(d[p][p], d[q][q], d[p][q])
+				rotateColumns(d, p, q, c, s)
+				for k := range d {
+					d[p][k], d[q][k] = c*d[p][k]-s*d[q][k], s*d[p][k]+c*d[q][k]
+				}
+				rotateColumns(v, p, q, c, s)
+			}
+		}
+	}
+
+	values = make([]float64, n)
+	for i := range values {
+		values[i] = d[i][i]
+	}
+	values, vectors = sortColumns(values, v, nil)
+	return values, vectors, nil
+}
+
+// SVD returns the thin singular value decomposition of the m×n matrix a: a = u·diag(s)·vᵀ, with
+// the k = min(m, n) singular values s in decreasing order and the columns of u (m×k) and v (n×k)
+// orthonormal, except for the columns of u of zero singular values, which are zero. It uses
+// one-sided Jacobi rotations. The sign of every column of v makes its largest component positive.
+func SVD(a [][]float64) (u [][]float64, s []float64, v [][]float64, err error) {
+	if len(a) == 0 || len(a[0]) == 0 {
+		return nil, nil, nil, fmt.Errorf("ml: svd of an empty matrix")
+	}
+	for i, row := range a {
+		if len(row) != len(a[0]) {
+			return nil, nil, nil, fmt.Errorf("ml: svd: row %d has %d columns, expected %d", i, len(row), len(a[0]))
+		}
+	}
+
+	// decompose the transpose of a wide matrix, swapping u and v
+	if len(a) < len(a[0]) {
+		v, s, u, err = SVD(T(a))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		s, v = sortColumns(s, v, u)
+		return u, s, v, nil
+	}
+
+	// rotate pairs of columns until they are all orthogonal
+	n := len(a[0])
+	u = copyMatrix(a)
+	v = Identity(n)
+	for sweep := 0; ; sweep++ {
+		rotated := false
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				var alpha, beta, gamma float64
+				for _, row := range u {
+					alpha += row[p] * row[p]
+					beta += row[q] * row[q]
+					gamma += row[p] * row[q]
+				}
+				if math.Abs(gamma) <= 1e-15*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+				c, s := jacobiRotation(alpha, beta, gamma)
+				rotateColumns(u, p, q, c, s)
+				rotateColumns(v, p, q, c, s)
+			}
+		}
+		if !rotated {
+			break
+		}
+		if sweep == maxSweeps {
+			return nil, nil, nil, fmt.Errorf("ml: svd did not converge in %d sweeps", maxSweeps)
+		}
+	}
+
+	// the lengths of the columns are the singular values
+	s = make([]float64, n)
+	for j := range s {
+		var sum float64
+		for _, row := range u {
+			sum += row[j] * row[j]
+		}
+		s[j] = math.Sqrt(sum)
+		for _, row := range u {
+			if s[j] != 0 {
+				row[j] /= s[j]
+			}
+		}
+	}
+	s, v = sortColumns(s, v, u)
+	return u, s, v, nil
+}
+
+// jacobiRotation returns the cosine and sine of the rotation zeroing the off-diagonal of the
+// symmetric 2×2 matrix [[app, apq], [apq, aqq]].
+func jacobiRotation(app, aqq, apq float64) (c, s float64) {
+	theta := (aqq - app) / (2 * apq)
+	t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+	if theta < 0 {
+		t = -t
+	}
+	c = 1 / math.Sqrt(t*t+1)
+	return c, t * c
+}
+
+// rotateColumns rotates columns p and q of m in place.
+func rotateColumns(m [][]float64, p, q int, c, s float64) {
+	for _, row := range m {
+		row[p], row[q] = c*row[p]-s*row[q], s*row[p]+c*row[q]
+	}
+}
+
+// sortColumns orders the values decreasingly along with the columns of vectors (and of other, if
+// given), flipping the signs of the columns so that the largest component of each vector is positive.
+func sortColumns(values []float64, vectors, other [][]float64) ([]float64, [][]float64) {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] > values[order[j]] })
+
+	sorted := make([]float64, len(values))
+	for i, j := range order {
+		sorted[i] = values[j]
+	}
+	permute := func(m [][]float64) {
+		for _, row := range m {
+			old := append([]float64(nil), row...)
+			for i, j := range order {
+				row[i] = old[j]
+			}
+		}
+	}
+	permute(vectors)
+	permute(other)
+
+	for j := range sorted {
+		largest := 0
+		for i := range vectors {
+			if math.Abs(vectors[i][j]) > math.Abs(vectors[largest][j]) {
+				largest = i
+			}
+		}
+		if vectors[largest][j] < 0 {
+			for _, m := range [][][]float64{vectors, other} {
+				for _, row := range m {
+					row[j] = -row[j]
+				}
+			}
+		}
+	}
+	return sorted, vectors
+}
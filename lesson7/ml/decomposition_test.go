@@ -0,0 +1,105 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestSymmetricEigen(t *testing.T) {
+	var tests = []struct {
+		a        [][]float64
+		expected []float64
+	}{
+		{[][]float64{{2, 1}, {1, 2}}, []float64{3, 1}},
+		{[][]float64{{1, 0, 0}, {0, 3, 0}, {0, 0, 2}}, []float64{3, 2, 1}},
+		{[][]float64{{4, -2}, {-2, 1}}, []float64{5, 0}},
+		{[][]float64{{2, -1, 0, 0}, {-1, 2, -1, 0}, {0, -1, 2, -1}, {0, 0, -1, 2}}, []float64{
+			2 + 2*math.Cos(math.Pi/5), 2 + 2*math.Cos(2*math.Pi/5), 2 - 2*math.Cos(2*math.Pi/5), 2 - 2*math.Cos(math.Pi/5),
+		}},
+	}
+
+	for _, test := range tests {
+		values, vectors, err := ml.SymmetricEigen(test.a)
+		if err != nil {
+			t.Fatalf("SymmetricEigen(%v) unexpected error: %v", test.a, err)
+		}
+		if !ml.MatrixAlmostEquals([][]float64{test.expected}, [][]float64{values}, 1e-12) {
+			t.Errorf("SymmetricEigen(%v): expected values %v, actual %v", test.a, test.expected, values)
+		}
+		diag := ml.FilledMatrix(len(values), len(values), 0)
+		for i, v := range values {
+			diag[i][i] = v
+		}
+		if !ml.MatrixAlmostEquals(ml.Dot(test.a, vectors), ml.Dot(vectors, diag), 1e-12) {
+			t.Errorf("SymmetricEigen(%v): expected a·v == v·diag(values), actual v %v", test.a, vectors)
+		}
+		if !ml.MatrixAlmostEquals(ml.Identity(len(values)), ml.Dot(ml.T(vectors), vectors), 1e-12) {
+			t.Errorf("SymmetricEigen(%v): expected orthonormal vectors, actual %v", test.a, vectors)
+		}
+	}
+
+	// the signs are fixed by the largest component
+	_, vectors, _ := ml.SymmetricEigen([][]float64{{2, 1}, {1, 2}})
+	expected := [][]float64{{math.Sqrt(0.5), math.Sqrt(0.5)}, {math.Sqrt(0.5), -math.Sqrt(0.5)}}
+	if !ml.MatrixAlmostEquals(expected, vectors, 1e-12) {
+		t.Errorf("SymmetricEigen: expected vectors %v, actual %v", expected, vectors)
+	}
+
+	if _, _, err := ml.SymmetricEigen([][]float64{{1, 2}, {0, 1}}); err == nil {
+		t.Errorf("SymmetricEigen: expected err != nil for a non-symmetric matrix")
+	}
+}
+
+func TestSVD(t *testing.T) {
+	var tests = [][][]float64{
+		{{3, 0}, {4, 5}},
+		{{1, 2}, {3, 4}, {5, 6}, {7, 8}},
+		{{1, 2, 3}, {4, 5, 6}},
+		{{1, 2}, {2, 4}, {3, 6}},
+		{{0, 0}, {0, 0}},
+	}
+
+	for _, a := range tests {
+		u, s, v, err := ml.SVD(a)
+		if err != nil {
+			t.Fatalf("SVD(%v) unexpected error: %v", a, err)
+		}
+		k := len(s)
+		diag := ml.FilledMatrix(k, k, 0)
+		for i := range s {
+			diag[i][i] = s[i]
+			if s[i] < 0 || i > 0 && s[i] > s[i-1] {
+				t.Errorf("SVD(%v): expected decreasing non-negative singular values, actual %v", a, s)
+			}
+		}
+		if !ml.MatrixAlmostEquals(a, ml.Dot(ml.Dot(u, diag), ml.T(v)), 1e-12) {
+			t.Errorf("SVD(%v): expected u·diag(s)·vᵀ == a, actual u %v s %v v %v", a, u, s, v)
+		}
+		if len(u) != len(a) || len(u[0]) != k || len(v) != len(a[0]) || len(v[0]) != k {
+			t.Errorf("SVD(%v): expected thin u and v of %d columns, actual u %v v %v", a, k, u, v)
+		}
+
+		// the squared singular values are the eigenvalues of aᵀa
+		values, _, _ := ml.SymmetricEigen(ml.Dot(ml.T(a), a))
+		for i := range s {
+			if math.Abs(s[i]*s[i]-values[i]) > 1e-9 {
+				t.Errorf("SVD(%v): expected s² == eigenvalues %v of aᵀa, actual s %v", a, values, s)
+			}
+		}
+	}
+
+	// 45 and 5 are the roots of λ² - 50λ + 225
+	_, s, _, _ := ml.SVD(tests[0])
+	if expected := []float64{math.Sqrt(45), math.Sqrt(5)}; !ml.MatrixAlmostEquals([][]float64{expected}, [][]float64{s}, 1e-12) {
+		t.Errorf("SVD(%v): expected s %v, actual %v", tests[0], expected, s)
+	}
+
+	if _, _, _, err := ml.SVD([][]float64{}); err == nil {
+		t.Errorf("SVD: expected err != nil for an empty matrix")
+	}
+	if _, _, _, err := ml.SVD([][]float64{{1, 2}, {3}}); err == nil {
+		t.Errorf("SVD: expected err != nil for a ragged matrix")
+	}
+}
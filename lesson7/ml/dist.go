@@ -0,0 +1,112 @@
+package ml
+
+import "math"
+
+// StudentTCDF returns the probability that a Student's t variable with dof degrees of freedom
+// is at most t.
+func StudentTCDF(t, dof float64) float64 {
+	// the tail beyond |t| is I_x(dof/2, 1/2)/2 with x = dof/(dof+t²)
+	tail := incompleteBeta(dof/2, 0.5, dof/(dof+t*t), t*t/(dof+t*t)) / 2
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// StudentTQuantile returns the t at which StudentTCDF(t, dof) reaches p, for 0 < p < 1,
+// by bisection.
+func StudentTQuantile(p, dof float64) float64 {
+	if p <= 0 {
+		return math.Inf(-1)
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+	lo, hi := -1.0, 1.0
+	for StudentTCDF(lo, dof) > p {
+		lo *= 2
+	}
+	for StudentTCDF(hi, dof) < p {
+		hi *= 2
+	}
+	for i := 0; i < 200 && hi-lo > 1e-14*math.Max(1, math.Abs(lo)); i++ {
+		mid := (lo + hi) / 2
+		if StudentTCDF(mid, dof) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// FCDF returns the probability that an F variable with d1 and d2 degrees of freedom is at most f.
+func FCDF(f, d1, d2 float64) float64 {
+	if f <= 0 {
+		return 0
+	}
+	return incompleteBeta(d1/2, d2/2, d1*f/(d1*f+d2), d2/(d1*f+d2))
+}
+
+// FSurvival returns the probability that an F variable with d1 and d2 degrees of freedom is
+// above f, 1 - FCDF without losing the small values to rounding.
+func FSurvival(f, d1, d2 float64) float64 {
+	if f <= 0 {
+		return 1
+	}
+	return incompleteBeta(d2/2, d1/2, d2/(d1*f+d2), d1*f/(d1*f+d2))
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b), evaluating its
+// continued fraction on whichever side converges faster. It takes y = 1 - x as well, so that
+// callers can give both exactly.
+func incompleteBeta(a, b, x, y float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if y <= 0 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(y))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, y)/b
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta function with the
+// modified Lentz method.
+func betaFraction(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}
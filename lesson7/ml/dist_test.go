@@ -0,0 +1,87 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestStudentTCDF(t *testing.T) {
+	var tests = []struct {
+		t, dof   float64
+		expected float64
+	}{
+		{0, 5, 0.5},
+		{1, 1, 0.75}, // the Cauchy distribution
+		{-1, 1, 0.25},
+		{2, 10, 0.9633059826146297},
+		{-2.228138851986, 10, 0.025},
+		{2, 3, 0.9303370157205784},
+		{30, 3, 0.9999593235978642},
+		{1.983971518523552, 100, 0.975},
+	}
+
+	for _, test := range tests {
+		actual := ml.StudentTCDF(test.t, test.dof)
+		if math.Abs(actual-test.expected) > 1e-9 {
+			t.Errorf("StudentTCDF(%v, %v): expected %v, actual %v", test.t, test.dof, test.expected, actual)
+		}
+	}
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	var tests = []struct {
+		p, dof   float64
+		expected float64
+	}{
+		{0.5, 7, 0},
+		{0.975, 1, 12.706204736174698},
+		{0.975, 10, 2.2281388519649385},
+		{0.025, 10, -2.2281388519649385},
+		{0.95, 30, 1.6972608865939574},
+		{0.995, 100, 2.625890521289},
+	}
+
+	for _, test := range tests {
+		actual := ml.StudentTQuantile(test.p, test.dof)
+		if math.Abs(actual-test.expected) > 1e-9*math.Max(1, math.Abs(test.expected)) {
+			t.Errorf("StudentTQuantile(%v, %v): expected %v, actual %v", test.p, test.dof, test.expected, actual)
+		}
+	}
+
+	if !math.IsInf(ml.StudentTQuantile(0, 3), -1) || !math.IsInf(ml.StudentTQuantile(1, 3), 1) {
+		t.Errorf("StudentTQuantile: expected -Inf and +Inf at 0 and 1")
+	}
+}
+
+func TestFCDF(t *testing.T) {
+	var tests = []struct {
+		f, d1, d2 float64
+		expected  float64
+	}{
+		{0, 3, 4, 0},
+		{-1, 3, 4, 0},
+		{1, 2, 2, 0.5}, // f/(1+f) for 2 and 2 degrees of freedom
+		{3, 2, 2, 0.75},
+		{4, 1, 10, 2*ml.StudentTCDF(2, 10) - 1}, // the square of a t variable
+	}
+
+	for _, test := range tests {
+		actual := ml.FCDF(test.f, test.d1, test.d2)
+		if math.Abs(actual-test.expected) > 1e-12 {
+			t.Errorf("FCDF(%v, %v, %v): expected %v, actual %v", test.f, test.d1, test.d2, test.expected, actual)
+		}
+	}
+
+	for _, test := range tests {
+		if actual := ml.FSurvival(test.f, test.d1, test.d2); math.Abs(actual-(1-test.expected)) > 1e-12 {
+			t.Errorf("FSurvival(%v, %v, %v): expected %v, actual %v", test.f, test.d1, test.d2, 1-test.expected, actual)
+		}
+	}
+
+	// far in the tail, where 1 - FCDF rounds to 0
+	if p := ml.FSurvival(139.063, 1, 161); p < 1e-24 || p > 1e-22 {
+		t.Errorf("FSurvival(139.063, 1, 161): expected about 1.6e-23, actual %v", p)
+	}
+}
@@ -0,0 +1,76 @@
+package ml
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Column describes a raw input column and how it turns into features: numeric columns are used
+// as they are, categorical ones are one-hot encoded over their Categories.
+type Column struct {
+	Name       string    `json:"name"`
+	Categories []float64 `json:"categories,omitempty"`
+}
+
+// Categories returns the distinct values of the array in increasing order.
+func Categories(xs []float64) []float64 {
+	seen := map[float64]bool{}
+	var values []float64
+	for _, x := range xs {
+		if !seen[x] {
+			seen[x] = true
+			values = append(values, x)
+		}
+	}
+	sort.Float64s(values)
+	return values
+}
+
+// Encode turns raw rows with one value per column into feature rows. It fails on a category
+// that was not seen when the columns were set up.
+func Encode(columns []Column, raw [][]float64) ([][]float64, error) {
+	result := make([][]float64, len(raw))
+
+	for i, row := range raw {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("ml: row %d: expected %d columns, got %d", i, len(columns), len(row))
+		}
+
+		for j, c := range columns {
+			if c.Categories == nil {
+				result[i] = append(result[i], row[j])
+				continue
+			}
+
+			found := false
+			for _, category := range c.Categories {
+				if row[j] == category {
+					result[i] = append(result[i], 1)
+					found = true
+				} else {
+					result[i] = append(result[i], 0)
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("ml: row %d: unknown %s category %v", i, c.Name, row[j])
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// FeatureNames returns the names of the features Encode produces, such as rank1 for category 1 of rank.
+func FeatureNames(columns []Column) []string {
+	var names []string
+	for _, c := range columns {
+		if c.Categories == nil {
+			names = append(names, c.Name)
+			continue
+		}
+		for _, category := range c.Categories {
+			names = append(names, fmt.Sprintf("%s%v", c.Name, category))
+		}
+	}
+	return names
+}
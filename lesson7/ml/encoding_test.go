@@ -0,0 +1,51 @@
+package ml_test
+
+import (
+	"reflect"
+	"testing"
+
+	"."
+)
+
+func TestCategories(t *testing.T) {
+	input := []float64{3, 1, 2, 3, 1}
+	expected := []float64{1, 2, 3}
+	actual := ml.Categories(input)
+	if !ml.ArrayEquals(expected, actual) {
+		t.Errorf("Categories(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	columns := []ml.Column{{Name: "gre"}, {Name: "rank", Categories: []float64{1, 2, 4}}}
+	input := [][]float64{{380, 4}, {660, 1}}
+	expected := [][]float64{{380, 0, 0, 1}, {660, 1, 0, 0}}
+	actual, err := ml.Encode(columns, input)
+	if err != nil {
+		t.Fatal("Encode unexpected error:", err)
+	}
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Encode(%v): expected %v, actual %v", input, expected, actual)
+	}
+
+	// unknown category
+	input = [][]float64{{380, 3}}
+	if _, err := ml.Encode(columns, input); err == nil {
+		t.Errorf("Encode(%v): expected err != nil", input)
+	}
+
+	// wrong number of columns
+	input = [][]float64{{380}}
+	if _, err := ml.Encode(columns, input); err == nil {
+		t.Errorf("Encode(%v): expected err != nil", input)
+	}
+}
+
+func TestFeatureNames(t *testing.T) {
+	columns := []ml.Column{{Name: "gre"}, {Name: "rank", Categories: []float64{1, 2}}}
+	expected := []string{"gre", "rank1", "rank2"}
+	actual := ml.FeatureNames(columns)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("FeatureNames(%v): expected %v, actual %v", columns, expected, actual)
+	}
+}
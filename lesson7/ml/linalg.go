@@ -0,0 +1,341 @@
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrSingular is returned when a matrix has no inverse, or a system has no unique solution.
+	ErrSingular = errors.New("ml: singular matrix")
+	// ErrNotPositiveDefinite is returned by Cholesky for a matrix that has no such factorization.
+	ErrNotPositiveDefinite = errors.New("ml: matrix is not positive definite")
+)
+
+// Identity returns the n×n identity matrix.
+func Identity(n int) [][]float64 {
+	m := FilledMatrix(n, n, 0)
+	for i := range m {
+		m[i][i] = 1
+	}
+	return m
+}
+
+// LU factors the square matrix a with partial pivoting, so that row perm[i] of a is row i of
+// l·u, l being unit lower triangular and u upper triangular. A singular a gives a u with a zero
+// on its diagonal rather than an error.
+func LU(a [][]float64) (l, u [][]float64, perm []int, err error) {
+	if err := checkSquare(a); err != nil {
+		return nil, nil, nil, err
+	}
+	n := len(a)
+	u = copyMatrix(a)
+	l = Identity(n)
+	perm = make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+
+	for k := 0; k < n; k++ {
+		// swap the row with the largest pivot up
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(u[i][k]) > math.Abs(u[p][k]) {
+				p = i
+			}
+		}
+		if p != k {
+			u[k], u[p] = u[p], u[k]
+			perm[k], perm[p] = perm[p], perm[k]
+			for j := 0; j < k; j++ {
+				l[k][j], l[p][j] = l[p][j], l[k][j]
+			}
+		}
+		if u[k][k] == 0 {
+			continue
+		}
+
+		// eliminate below the pivot
+		for i := k + 1; i < n; i++ {
+			l[i][k] = u[i][k] / u[k][k]
+			for j := k; j < n; j++ {
+				u[i][j] -= l[i][k] * u[k][j]
+			}
+		}
+	}
+	return l, u, perm, nil
+}
+
+// Det returns the determinant of the square matrix a, from its LU factorization.
+func Det(a [][]float64) (float64, error) {
+	_, u, perm, err := LU(a)
+	if err != nil {
+		return 0, err
+	}
+	det := float64(1)
+	for i := range u {
+		det *= u[i][i]
+	}
+
+	// every swap flips the sign, count them by following the cycles of the permutation
+	seen := make([]bool, len(perm))
+	for i := range perm {
+		for j := perm[i]; !seen[i] && j != i; j = perm[j] {
+			seen[j] = true
+			det = -det
+		}
+		seen[i] = true
+	}
+	return det, nil
+}
+
+// Solve returns the x for which a·x = b, a being square, solving for every column of b.
+// It returns ErrSingular if a has no inverse.
+func Solve(a, b [][]float64) ([][]float64, error) {
+	l, u, perm, err := LU(a)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != len(a) {
+		return nil, fmt.Errorf("ml: solving a %d×%d system for %d rows", len(a), len(a), len(b))
+	}
+	if len(u) == 0 || rankDeficient(u) {
+		return nil, ErrSingular
+	}
+	return backSubstitute(u, forwardSubstitute(l, Rows(b, perm))), nil
+}
+
+// Inverse returns the inverse of the square matrix a, or ErrSingular if it has none.
+func Inverse(a [][]float64) ([][]float64, error) {
+	return Solve(a, Identity(len(a)))
+}
+
+// Cond returns the condition number of the square matrix a in the 1-norm, |a|·|a⁻¹|: how much
+// the relative error of b can grow in the solution of a·x = b. It is +Inf with ErrSingular for
+// a singular a.
+func Cond(a [][]float64) (float64, error) {
+	inverse, err := Inverse(a)
+	if err == ErrSingular {
+		return math.Inf(1), err
+	} else if err != nil {
+		return 0, err
+	}
+	return norm1(a) * norm1(inverse), nil
+}
+
+// Cholesky factors the symmetric positive definite matrix a as l·lᵀ, l being lower triangular.
+// It returns ErrNotPositiveDefinite if a is not, including when it is singular.
+func Cholesky(a [][]float64) ([][]float64, error) {
+	if err := checkSquare(a); err != nil {
+		return nil, err
+	}
+	if err := checkSymmetric(a); err != nil {
+		return nil, err
+	}
+	n := len(a)
+	l := FilledMatrix(n, n, 0)
+	for j := 0; j < n; j++ {
+		d := a[j][j]
+		for k := 0; k < j; k++ {
+			d -= l[j][k] * l[j][k]
+		}
+		if d <= 0 {
+			return nil, ErrNotPositiveDefinite
+		}
+		l[j][j] = math.Sqrt(d)
+		for i := j + 1; i < n; i++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			l[i][j] = sum / l[j][j]
+		}
+	}
+	return l, nil
+}
+
+// checkSquare returns an error if the matrix is not square.
+func checkSquare(a [][]float64) error {
+	for i, row := range a {
+		if len(row) != len(a) {
+			return fmt.Errorf("ml: row %d of a %d row matrix has %d columns, expected a square matrix", i, len(a), len(row))
+		}
+	}
+	return nil
+}
+
+// checkSymmetric returns an error if the square matrix is not symmetric, up to rounding.
+func checkSymmetric(a [][]float64) error {
+	for i := range a {
+		for j := 0; j < i; j++ {
+			if math.Abs(a[i][j]-a[j][i]) > 1e-12*math.Max(math.Abs(a[i][j]), math.Abs(a[j][i])) {
+				return fmt.Errorf("ml: matrix is not symmetric at [%d][%d]", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// norm1 returns the largest sum of absolute values of a column of the matrix.
+func norm1(m [][]float64) float64 {
+	var largest float64
+	for _, column := range T(m) {
+		var sum float64
+		for _, x := range column {
+			sum += math.Abs(x)
+		}
+		largest = math.Max(largest, sum)
+	}
+	return largest
+}
+
+// QR decomposes the m×n matrix a into q·r with Householder reflections, q being m×k with
+// orthonormal columns and r being k×n upper triangular, where k = min(m, n).
+func QR(a [][]float64) (q, r [][]float64) {
+	m := len(a)
+	if m == 0 {
+		return [][]float64{}, [][]float64{}
+	}
+	n := len(a[0])
+	k := n
+	if m < k {
+		k = m
+	}
+
+	// reflect the columns below the diagonal to zero, one at a time
+	r = copyMatrix(a)
+	reflectors := make([][]float64, k)
+	for j := 0; j < k; j++ {
+		v := make([]float64, m-j)
+		for i := range v {
+			v[i] = r[j+i][j]
+		}
+		alpha := norm(v)
+		if v[0] > 0 {
+			alpha = -alpha
+		}
+		v[0] -= alpha
+		if vNorm := norm(v); vNorm != 0 {
+			for i := range v {
+				v[i] /= vNorm
+			}
+			reflect(r, v, j)
+		}
+		reflectors[j] = v
+	}
+
+	// q is the product of the reflections applied to the first k columns of the identity
+	q = FilledMatrix(m, k, 0)
+	for i := 0; i < k; i++ {
+		q[i][i] = 1
+	}
+	for j := k - 1; j >= 0; j-- {
+		reflect(q, reflectors[j], j)
+	}
+
+	r = r[:k]
+	for i := range r {
+		for j := 0; j < i && j < n; j++ {
+			r[i][j] = 0
+		}
+	}
+	return q, r
+}
+
+// reflect applies the Householder reflection I - 2vvᵀ to rows from..from+len(v) of m, in place.
+func reflect(m [][]float64, v []float64, from int) {
+	for c := range m[from] {
+		var dot float64
+		for i, vi := range v {
+			dot += vi * m[from+i][c]
+		}
+		for i, vi := range v {
+			m[from+i][c] -= 2 * vi * dot
+		}
+	}
+}
+
+// LeastSquares returns the w minimizing |x·w - y|² + ridge·|w|², solving it through the QR
+// decomposition of x (stacked on √ridge·I when ridge is set). Each column of y gets its own
+// column of w. It returns ErrSingular if the columns of x are linearly dependent and ridge is 0.
+func LeastSquares(x, y [][]float64, ridge float64) ([][]float64, error) {
+	if len(x) == 0 || len(x) != len(y) {
+		return nil, fmt.Errorf("ml: least squares of %d rows against %d targets", len(x), len(y))
+	}
+	if ridge < 0 {
+		return nil, fmt.Errorf("ml: negative ridge %v", ridge)
+	}
+	n := len(x[0])
+
+	// the ridge penalty is the error of extra rows fitting √ridge·w to 0
+	if ridge > 0 {
+		x = append(copyMatrix(x), FilledMatrix(n, n, 0)...)
+		y = append(copyMatrix(y), FilledMatrix(n, len(y[0]), 0)...)
+		for i := 0; i < n; i++ {
+			x[len(x)-n+i][i] = math.Sqrt(ridge)
+		}
+	}
+
+	q, r := QR(x)
+	if len(r) < n || rankDeficient(r) {
+		return nil, ErrSingular
+	}
+	return backSubstitute(r, Dot(T(q), y)), nil
+}
+
+// rankDeficient checks if the square upper triangular r has a diagonal entry that is negligible
+// next to the largest one.
+func rankDeficient(r [][]float64) bool {
+	var largest float64
+	for i := range r {
+		largest = math.Max(largest, math.Abs(r[i][i]))
+	}
+	tol := float64(len(r)) * largest * 1e-15
+	for i := range r {
+		if math.Abs(r[i][i]) <= tol {
+			return true
+		}
+	}
+	return false
+}
+
+// backSubstitute solves r·x = b for the square upper triangular r, column by column of b.
+func backSubstitute(r, b [][]float64) [][]float64 {
+	n := len(r)
+	x := FilledMatrix(n, len(b[0]), 0)
+	for c := range x[0] {
+		for i := n - 1; i >= 0; i-- {
+			sum := b[i][c]
+			for j := i + 1; j < n; j++ {
+				sum -= r[i][j] * x[j][c]
+			}
+			x[i][c] = sum / r[i][i]
+		}
+	}
+	return x
+}
+
+// forwardSubstitute solves l·x = b for the square unit lower triangular l, column by column of b.
+func forwardSubstitute(l, b [][]float64) [][]float64 {
+	x := FilledMatrix(len(l), len(b[0]), 0)
+	for c := range x[0] {
+		for i := range l {
+			sum := b[i][c]
+			for j := 0; j < i; j++ {
+				sum -= l[i][j] * x[j][c]
+			}
+			x[i][c] = sum
+		}
+	}
+	return x
+}
+
+// norm returns the Euclidean length of the vector.
+func norm(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,240 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestQR(t *testing.T) {
+	var tests = [][][]float64{
+		{{12, -51, 4}, {6, 167, -68}, {-4, 24, -41}},
+		{{1, 2}, {3, 4}, {5, 6}, {7, 8}},
+		{{1, 2, 3}, {4, 5, 6}},
+		{{0, 1}, {0, 1}, {0, 1}},
+	}
+
+	for _, a := range tests {
+		q, r := ml.QR(a)
+		if !ml.MatrixAlmostEquals(a, ml.Dot(q, r), 1e-12) {
+			t.Errorf("QR(%v): expected q·r == a, actual q %v r %v", a, q, r)
+		}
+		if !ml.MatrixAlmostEquals(ml.Identity(len(r)), ml.Dot(ml.T(q), q), 1e-12) {
+			t.Errorf("QR(%v): expected orthonormal q, actual %v", a, q)
+		}
+		for i := range r {
+			for j := 0; j < i; j++ {
+				if r[i][j] != 0 {
+					t.Errorf("QR(%v): expected upper triangular r, actual %v", a, r)
+				}
+			}
+		}
+	}
+
+	// the textbook example, up to the signs of the rows of r
+	_, r := ml.QR(tests[0])
+	expected := [][]float64{{14, 21, -14}, {0, 175, -70}, {0, 0, 35}}
+	for i := range r {
+		for j := range r[i] {
+			if math.Abs(math.Abs(r[i][j])-math.Abs(expected[i][j])) > 1e-12 {
+				t.Errorf("QR(%v): expected r %v, actual %v", tests[0], expected, r)
+			}
+		}
+	}
+}
+
+func TestLeastSquares(t *testing.T) {
+	var tests = []struct {
+		x, y     [][]float64
+		ridge    float64
+		expected [][]float64
+	}{
+		// exact solution of a square system
+		{[][]float64{{2, 1}, {1, 3}}, [][]float64{{3}, {5}}, 0, [][]float64{{0.8}, {1.4}}},
+		// a line through (0, 1), (1, 3), (2, 5) with a column of ones for the intercept
+		{[][]float64{{1, 0}, {1, 1}, {1, 2}}, [][]float64{{1}, {3}, {5}}, 0, [][]float64{{1}, {2}}},
+		// overdetermined: the mean of the targets
+		{[][]float64{{1}, {1}, {1}, {1}}, [][]float64{{1}, {2}, {3}, {6}}, 0, [][]float64{{3}}},
+		// ridge shrinks the mean: 4w = 12 - 2w
+		{[][]float64{{1}, {1}, {1}, {1}}, [][]float64{{1}, {2}, {3}, {6}}, 2, [][]float64{{2}}},
+		// two targets at once
+		{[][]float64{{1}, {2}}, [][]float64{{2, -1}, {4, -2}}, 0, [][]float64{{2, -1}}},
+	}
+
+	for _, test := range tests {
+		actual, err := ml.LeastSquares(test.x, test.y, test.ridge)
+		if err != nil || !ml.MatrixAlmostEquals(test.expected, actual, 1e-12) {
+			t.Errorf("LeastSquares(%v, %v, %v): expected %v, actual %v (err %v)", test.x, test.y, test.ridge, test.expected, actual, err)
+		}
+	}
+}
+
+func TestLeastSquaresErrors(t *testing.T) {
+	dependent := [][]float64{{1, 2}, {2, 4}, {3, 6}}
+	y := [][]float64{{1}, {2}, {3}}
+	if _, err := ml.LeastSquares(dependent, y, 0); err != ml.ErrSingular {
+		t.Errorf("LeastSquares(%v): expected ErrSingular, actual %v", dependent, err)
+	}
+	if _, err := ml.LeastSquares(dependent, y, 0.1); err != nil {
+		t.Errorf("LeastSquares(%v) with ridge: unexpected error %v", dependent, err)
+	}
+	if _, err := ml.LeastSquares([][]float64{{1, 2}}, [][]float64{{1}}, 0); err != ml.ErrSingular {
+		t.Errorf("LeastSquares: expected ErrSingular for fewer rows than columns, actual %v", err)
+	}
+	if _, err := ml.LeastSquares(dependent, y[:2], 0); err == nil {
+		t.Errorf("LeastSquares: expected err != nil for mismatched rows")
+	}
+	if _, err := ml.LeastSquares(dependent, y, -1); err == nil {
+		t.Errorf("LeastSquares: expected err != nil for a negative ridge")
+	}
+}
+
+func TestLU(t *testing.T) {
+	var tests = [][][]float64{
+		{{1, 2}, {3, 4}},
+		{{0, 2, 1}, {1, 1, 1}, {2, 1, 0}},
+		{{6, 1, 1}, {4, -2, 5}, {2, 8, 7}},
+		{{1, 2}, {2, 4}},
+	}
+
+	for _, a := range tests {
+		l, u, perm, err := ml.LU(a)
+		if err != nil {
+			t.Fatalf("LU(%v) unexpected error: %v", a, err)
+		}
+		if !ml.MatrixAlmostEquals(ml.Rows(a, perm), ml.Dot(l, u), 1e-12) {
+			t.Errorf("LU(%v): expected l·u == rows %v of a, actual l %v u %v", a, perm, l, u)
+		}
+		for i := range a {
+			if l[i][i] != 1 {
+				t.Errorf("LU(%v): expected unit diagonal of l, actual %v", a, l)
+			}
+			for j := i + 1; j < len(a); j++ {
+				if l[i][j] != 0 || u[j][i] != 0 {
+					t.Errorf("LU(%v): expected triangular factors, actual l %v u %v", a, l, u)
+				}
+			}
+		}
+	}
+
+	if _, _, _, err := ml.LU([][]float64{{1, 2, 3}, {4, 5, 6}}); err == nil {
+		t.Errorf("LU: expected err != nil for a non-square matrix")
+	}
+}
+
+func TestDet(t *testing.T) {
+	var tests = []struct {
+		a        [][]float64
+		expected float64
+	}{
+		{[][]float64{{1, 2}, {3, 4}}, -2},
+		{[][]float64{{6, 1, 1}, {4, -2, 5}, {2, 8, 7}}, -306},
+		{[][]float64{{0, 1}, {1, 0}}, -1},
+		{[][]float64{{0, 1, 0}, {0, 0, 1}, {1, 0, 0}}, 1},
+		{[][]float64{{2, 0, 1}, {1, 3, 2}, {1, 1, 1}}, 0},
+		{ml.Identity(4), 1},
+	}
+
+	for _, test := range tests {
+		actual, err := ml.Det(test.a)
+		if err != nil || math.Abs(actual-test.expected) > 1e-12 {
+			t.Errorf("Det(%v): expected %v, actual %v (err %v)", test.a, test.expected, actual, err)
+		}
+	}
+
+	if _, err := ml.Det([][]float64{{1, 2}}); err == nil {
+		t.Errorf("Det: expected err != nil for a non-square matrix")
+	}
+}
+
+func TestSolve(t *testing.T) {
+	var tests = []struct {
+		a, b     [][]float64
+		expected [][]float64
+	}{
+		{[][]float64{{2, 1}, {1, 3}}, [][]float64{{3}, {5}}, [][]float64{{0.8}, {1.4}}},
+		// needs pivoting: the first pivot is 0
+		{[][]float64{{0, 2, 1}, {1, 1, 1}, {2, 1, 0}}, [][]float64{{7}, {6}, {4}}, [][]float64{{1}, {2}, {3}}},
+		// every column of b
+		{[][]float64{{2, 0}, {0, 4}}, [][]float64{{2, 4}, {4, 8}}, [][]float64{{1, 2}, {1, 2}}},
+	}
+
+	for _, test := range tests {
+		actual, err := ml.Solve(test.a, test.b)
+		if err != nil || !ml.MatrixAlmostEquals(test.expected, actual, 1e-12) {
+			t.Errorf("Solve(%v, %v): expected %v, actual %v (err %v)", test.a, test.b, test.expected, actual, err)
+		}
+	}
+
+	singular := [][]float64{{2, 0, 1}, {1, 3, 2}, {1, 1, 1}}
+	if _, err := ml.Solve(singular, [][]float64{{1}, {2}, {3}}); err != ml.ErrSingular {
+		t.Errorf("Solve(%v): expected ErrSingular, actual %v", singular, err)
+	}
+	if _, err := ml.Solve([][]float64{{1, 0}, {0, 1}}, [][]float64{{1}}); err == nil {
+		t.Errorf("Solve: expected err != nil for mismatched rows")
+	}
+}
+
+func TestInverse(t *testing.T) {
+	a := [][]float64{{4, 7}, {2, 6}}
+	expected := [][]float64{{0.6, -0.7}, {-0.2, 0.4}}
+	if actual, err := ml.Inverse(a); err != nil || !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Inverse(%v): expected %v, actual %v (err %v)", a, expected, actual, err)
+	}
+
+	// the inverse of a badly conditioned matrix still multiplies back to the identity
+	hilbert := ml.FilledMatrix(5, 5, 0)
+	for i := range hilbert {
+		for j := range hilbert[i] {
+			hilbert[i][j] = 1 / float64(i+j+1)
+		}
+	}
+	inverse, err := ml.Inverse(hilbert)
+	if err != nil || !ml.MatrixAlmostEquals(ml.Identity(5), ml.Dot(hilbert, inverse), 1e-9) {
+		t.Errorf("Inverse(%v): expected a·a⁻¹ == I, actual %v (err %v)", hilbert, ml.Dot(hilbert, inverse), err)
+	}
+
+	if _, err := ml.Inverse([][]float64{{1, 2}, {2, 4}}); err != ml.ErrSingular {
+		t.Errorf("Inverse: expected ErrSingular, actual %v", err)
+	}
+}
+
+func TestCond(t *testing.T) {
+	var tests = []struct {
+		a        [][]float64
+		expected float64
+	}{
+		{ml.Identity(3), 1},
+		{[][]float64{{1, 0}, {0, 0.001}}, 1000},
+		{[][]float64{{1, 2}, {3, 4}}, 21},
+	}
+
+	for _, test := range tests {
+		actual, err := ml.Cond(test.a)
+		if err != nil || math.Abs(actual-test.expected) > 1e-9 {
+			t.Errorf("Cond(%v): expected %v, actual %v (err %v)", test.a, test.expected, actual, err)
+		}
+	}
+
+	if actual, err := ml.Cond([][]float64{{1, 2}, {2, 4}}); err != ml.ErrSingular || !math.IsInf(actual, 1) {
+		t.Errorf("Cond: expected +Inf and ErrSingular, actual %v (err %v)", actual, err)
+	}
+}
+
+func TestCholesky(t *testing.T) {
+	a := [][]float64{{4, 12, -16}, {12, 37, -43}, {-16, -43, 98}}
+	expected := [][]float64{{2, 0, 0}, {6, 1, 0}, {-8, 5, 3}}
+	if actual, err := ml.Cholesky(a); err != nil || !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Cholesky(%v): expected %v, actual %v (err %v)", a, expected, actual, err)
+	}
+
+	for _, a := range [][][]float64{{{1, 2}, {2, 1}}, {{1, 1}, {1, 1}}, {{0}}} {
+		if _, err := ml.Cholesky(a); err != ml.ErrNotPositiveDefinite {
+			t.Errorf("Cholesky(%v): expected ErrNotPositiveDefinite, actual %v", a, err)
+		}
+	}
+	if _, err := ml.Cholesky([][]float64{{2, 1}, {0, 2}}); err == nil {
+		t.Errorf("Cholesky: expected err != nil for a non-symmetric matrix")
+	}
+}
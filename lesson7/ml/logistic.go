@@ -0,0 +1,364 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Solvers of FitLogistic: gradient descent on the mean cross entropy, or Newton's method, which
+// for logistic regression is iteratively reweighted least squares.
+const (
+	SolverGD   = "gd"
+	SolverIRLS = "irls"
+)
+
+// Logistic is a logistic regression: the probability of class 1 is the sigmoid of x·w + b for a
+// binary model, with a single column of weights, and the probabilities of the classes are the
+// softmax of x·W + b for a multinomial one, with a column of weights per class.
+type Logistic struct {
+	Weights [][]float64 `json:"weights"`
+	Bias    []float64   `json:"bias"`
+}
+
+// LogisticOptions says how FitLogistic fits a logistic regression.
+type LogisticOptions struct {
+	Solver       string    // SolverGD or SolverIRLS
+	Penalty      *Penalty  // on the weights, not the bias; SolverIRLS takes only L2
+	ClassWeights []float64 // how much the rows of every class count, all 1 if nil
+	LearnRate    float64   // of SolverGD
+	Iterations   int       // most steps to take
+	Tolerance    float64   // stop once no parameter moves by more than this, 0 to take every step
+}
+
+// Validate checks that the options make sense.
+func (o *LogisticOptions) Validate() error {
+	switch o.Solver {
+	case SolverGD:
+		if o.LearnRate <= 0 {
+			return fmt.Errorf("ml: logistic learning rate %v is not positive", o.LearnRate)
+		}
+	case SolverIRLS:
+		if o.Penalty != nil && o.Penalty.L1 != 0 {
+			return fmt.Errorf("ml: the L1 penalty is not smooth enough for %s, use %s", SolverIRLS, SolverGD)
+		}
+	default:
+		return fmt.Errorf("ml: unknown logistic solver %q, expected %s or %s", o.Solver, SolverGD, SolverIRLS)
+	}
+	if o.Iterations <= 0 || o.Tolerance < 0 {
+		return fmt.Errorf("ml: logistic fit of %d iterations to a tolerance of %v", o.Iterations, o.Tolerance)
+	}
+	for _, w := range o.ClassWeights {
+		if w < 0 {
+			return fmt.Errorf("ml: negative class weight %v", w)
+		}
+	}
+	return o.Penalty.Validate()
+}
+
+// FitLogistic fits a logistic regression to the targets y, minimizing their mean cross entropy,
+// each row weighted by the weight of its class, plus the penalty. y is a single column of 0 and 1
+// for a binary model, or one-hot rows for a multinomial one. The weights of a multinomial model
+// sum to 0 over the classes, as the softmax only depends on their differences. The result says
+// how many steps the fit took and whether it converged, by its parameters moving less than the
+// tolerance in a step, or ran out of iterations; its Loss is the weighted cross entropy.
+func FitLogistic(x, y [][]float64, opts *LogisticOptions) (*Logistic, FitResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, FitResult{}, err
+	}
+	if len(x) == 0 || len(x) != len(y) {
+		return nil, FitResult{}, fmt.Errorf("ml: logistic fit of %d rows against %d targets", len(x), len(y))
+	}
+	numClasses := len(y[0])
+	if numClasses == 1 {
+		numClasses = 2
+	}
+	if opts.ClassWeights != nil && len(opts.ClassWeights) != numClasses {
+		return nil, FitResult{}, fmt.Errorf("ml: %d class weights for %d classes", len(opts.ClassWeights), numClasses)
+	}
+	for _, row := range y {
+		for _, v := range row {
+			if v != 0 && v != 1 {
+				return nil, FitResult{}, fmt.Errorf("ml: logistic target %v, expected 0 or 1", v)
+			}
+		}
+	}
+
+	start := time.Now()
+	l := &Logistic{Weights: FilledMatrix(len(x[0]), len(y[0]), 0), Bias: make([]float64, len(y[0]))}
+	weights := rowWeights(y, opts.ClassWeights)
+	var steps int
+	var settled bool
+	if opts.Solver == SolverGD {
+		steps, settled = l.gradientDescent(x, y, weights, opts)
+	} else {
+		var err error
+		if steps, settled, err = l.newton(x, y, weights, opts); err != nil {
+			return nil, FitResult{}, err
+		}
+	}
+	for _, row := range l.params() {
+		for _, v := range row {
+			if !finite(v) {
+				return nil, FitResult{}, fmt.Errorf("ml: logistic fit diverged")
+			}
+		}
+	}
+	if l.Multinomial() {
+		l.center()
+	}
+
+	result := FitResult{Reason: StopMaxSteps, Steps: steps, Loss: l.Loss(x, y, opts.ClassWeights), Elapsed: time.Since(start)}
+	if settled {
+		result.Reason = StopParamChange
+	}
+	return l, result, nil
+}
+
+// Multinomial checks if the model has a probability per class rather than the probability of class 1.
+func (l *Logistic) Multinomial() bool {
+	return len(l.Bias) > 1
+}
+
+// Predict returns the probability of class 1 of every row of x for a binary model, or the
+// probability of every class for a multinomial one.
+func (l *Logistic) Predict(x [][]float64) [][]float64 {
+	z := AddRow(Dot(x, l.Weights), l.Bias)
+	if l.Multinomial() {
+		return Softmax(z)
+	}
+	return SigmoidM(z)
+}
+
+// Loss returns the mean cross entropy of the predictions of x against the targets y, with every
+// row counting as much as the weight of its class, if given.
+func (l *Logistic) Loss(x, y [][]float64, classWeights []float64) float64 {
+	weights := rowWeights(y, classWeights)
+	var total float64
+	for i, row := range l.Predict(x) {
+		for j, p := range row {
+			// keep log finite for saturated predictions
+			p = math.Min(math.Max(p, 1e-15), 1-1e-15)
+			if y[i][j] == 1 {
+				total -= weights[i] * math.Log(p)
+			} else if !l.Multinomial() {
+				total -= weights[i] * math.Log(1-p)
+			}
+		}
+	}
+	return total / float64(len(x))
+}
+
+// Layer returns a dense layer computing the same probabilities as the model, to save it as a Model.
+func (l *Logistic) Layer() Layer {
+	activation := ActivationSigmoid
+	if l.Multinomial() {
+		activation = ActivationSoftmax
+	}
+	return Layer{Type: LayerDense, Weights: l.Weights, Bias: l.Bias, Activation: activation}
+}
+
+// Summarize returns the coefficients of a binary model fitted without a penalty, the intercept
+// first, with their standard errors from the inverse of the Fisher information at the rows x they
+// were fitted to, their z-statistics in T, and the p-values and confidence intervals at the given
+// level of the normal approximation. The features are named by names, if given.
+func (l *Logistic) Summarize(x [][]float64, names []string, level float64) ([]Coefficient, error) {
+	if l.Multinomial() {
+		return nil, fmt.Errorf("ml: no summary of a multinomial logistic regression")
+	}
+	if level <= 0 || level >= 1 {
+		return nil, fmt.Errorf("ml: confidence level %v is not between 0 and 1", level)
+	}
+	design := withOnes(x)
+	info := FilledMatrix(len(design[0]), len(design[0]), 0)
+	for i, p := range T(l.Predict(x))[0] {
+		for a, xa := range design[i] {
+			for b, xb := range design[i] {
+				info[a][b] += p * (1 - p) * xa * xb
+			}
+		}
+	}
+	cov, err := Inverse(info)
+	if err != nil {
+		return nil, err
+	}
+
+	z := math.Sqrt2 * math.Erfinv(level)
+	values := append(append([]float64(nil), l.Bias...), T(l.Weights)[0]...)
+	coefficients := make([]Coefficient, len(values))
+	for j, v := range values {
+		c := Coefficient{Name: "intercept", Value: v, StdErr: math.Sqrt(cov[j][j])}
+		if j > 0 {
+			c.Name = fmt.Sprintf("x%d", j)
+			if j-1 < len(names) {
+				c.Name = names[j-1]
+			}
+		}
+		c.T = c.Value / c.StdErr
+		c.P = math.Erfc(math.Abs(c.T) / math.Sqrt2)
+		c.Lower, c.Upper = c.Value-z*c.StdErr, c.Value+z*c.StdErr
+		coefficients[j] = c
+	}
+	return coefficients, nil
+}
+
+// BalancedClassWeights returns weights making every class of the targets count as much in total,
+// n/(k·count) for k classes with count rows each out of n. y is as for FitLogistic.
+func BalancedClassWeights(y [][]float64) []float64 {
+	classes := labels(oneHotTargets(y, 2))
+	numClasses := len(y[0])
+	if numClasses == 1 {
+		numClasses = 2
+	}
+	counts := make([]float64, numClasses)
+	for _, class := range classes {
+		counts[class]++
+	}
+	weights := make([]float64, numClasses)
+	for k, count := range counts {
+		if count > 0 {
+			weights[k] = float64(len(y)) / (float64(numClasses) * count)
+		}
+	}
+	return weights
+}
+
+// rowWeights returns the weight of the class of every row, all 1 without class weights.
+func rowWeights(y [][]float64, classWeights []float64) []float64 {
+	weights := make([]float64, len(y))
+	for i, label := range labels(oneHotTargets(y, 2)) {
+		weights[i] = 1
+		if classWeights != nil {
+			weights[i] = classWeights[label]
+		}
+	}
+	return weights
+}
+
+// gradient returns the gradient of the weighted mean cross entropy with respect to the weights
+// and the bias, which for the sigmoid and the softmax alike comes from the errors p - y.
+func (l *Logistic) gradient(x, y [][]float64, weights []float64) ([][]float64, []float64) {
+	errors := Sub(l.Predict(x), y)
+	for i, row := range errors {
+		for j := range row {
+			row[j] *= weights[i] / float64(len(x))
+		}
+	}
+	return Dot(T(x), errors), ColumnSums(errors)
+}
+
+// gradientDescent takes steps down the loss and the penalty until they get smaller than the
+// tolerance, and returns how many it took and whether the last one was that small.
+func (l *Logistic) gradientDescent(x, y [][]float64, weights []float64, opts *LogisticOptions) (steps int, settled bool) {
+	for i := 0; i < opts.Iterations; i++ {
+		gradW, gradB := l.gradient(x, y, weights)
+		gradW = Add(gradW, opts.Penalty.Grad(l.Weights))
+		stepW, stepB := Scale(gradW, opts.LearnRate), Scale([][]float64{gradB}, opts.LearnRate)
+		l.Weights = Sub(l.Weights, stepW)
+		l.Bias = Sub([][]float64{l.Bias}, stepB)[0]
+		if math.Max(maxAbs(stepW), maxAbs(stepB)) <= opts.Tolerance {
+			return i + 1, true
+		}
+	}
+	return opts.Iterations, false
+}
+
+// newton takes Newton steps, solving the Hessian of the loss and the L2 penalty against their
+// gradient, until they get smaller than the tolerance, and returns how many it took and whether
+// the last one was that small. The parameters of class k are its bias
+// then its weights, at k·(d+1) for d features. The softmax leaves the sum of the parameters of
+// the classes free, so a tiny multiple of the identity keeps the Hessian invertible.
+func (l *Logistic) newton(x, y [][]float64, weights []float64, opts *LogisticOptions) (steps int, settled bool, err error) {
+	var l2 float64
+	if opts.Penalty != nil {
+		l2 = opts.Penalty.L2
+	}
+	design := withOnes(x)
+	d, k := len(design[0]), len(l.Bias)
+	n := float64(len(x))
+
+	for iteration := 0; iteration < opts.Iterations; iteration++ {
+		gradW, gradB := l.gradient(x, y, weights)
+		grad := make([][]float64, d*k)
+		for c := 0; c < k; c++ {
+			grad[c*d] = []float64{gradB[c]}
+			for a := 1; a < d; a++ {
+				grad[c*d+a] = []float64{gradW[a-1][c] + l2*l.Weights[a-1][c]}
+			}
+		}
+
+		p := l.Predict(x)
+		hessian := FilledMatrix(d*k, d*k, 0)
+		for i, row := range design {
+			for c := 0; c < k; c++ {
+				for e := 0; e < k; e++ {
+					// the derivative of p_c with respect to the scores of class e
+					curvature := -p[i][c] * p[i][e]
+					if c == e {
+						curvature += p[i][c]
+					}
+					curvature *= weights[i] / n
+					for a, xa := range row {
+						for b, xb := range row {
+							hessian[c*d+a][e*d+b] += curvature * xa * xb
+						}
+					}
+				}
+			}
+		}
+		var largest float64
+		for j := range hessian {
+			largest = math.Max(largest, hessian[j][j])
+		}
+		for c := 0; c < k; c++ {
+			for a := 0; a < d; a++ {
+				hessian[c*d+a][c*d+a] += 1e-10 * largest
+				if a > 0 {
+					hessian[c*d+a][c*d+a] += l2
+				}
+			}
+		}
+
+		step, err := Solve(hessian, grad)
+		if err != nil {
+			return 0, false, err
+		}
+		for c := 0; c < k; c++ {
+			l.Bias[c] -= step[c*d][0]
+			for a := 1; a < d; a++ {
+				l.Weights[a-1][c] -= step[c*d+a][0]
+			}
+		}
+		if maxAbs(step) <= opts.Tolerance {
+			return iteration + 1, true, nil
+		}
+	}
+	return opts.Iterations, false, nil
+}
+
+// center subtracts the mean over the classes from the weights and the bias of every feature,
+// which leaves the probabilities as they are.
+func (l *Logistic) center() {
+	for _, row := range l.params() {
+		mean := Mean(row)
+		for c := range row {
+			row[c] -= mean
+		}
+	}
+}
+
+// params returns the rows of the weights followed by the bias, sharing their values.
+func (l *Logistic) params() [][]float64 {
+	return append(append([][]float64(nil), l.Weights...), l.Bias)
+}
+
+// maxAbs returns the largest absolute value of the matrix.
+func maxAbs(m [][]float64) float64 {
+	var largest float64
+	for _, row := range m {
+		for _, v := range row {
+			largest = math.Max(largest, math.Abs(v))
+		}
+	}
+	return largest
+}
@@ -0,0 +1,163 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+// groups returns a binary feature with a quarter of the rows of group 0 and three quarters of
+// those of group 1 in class 1, so that the fit has log(1/3) for intercept and 2·log(3) for weight.
+func groups() (x, y [][]float64) {
+	x = [][]float64{{0}, {0}, {0}, {0}, {1}, {1}, {1}, {1}}
+	y = [][]float64{{1}, {0}, {0}, {0}, {1}, {1}, {1}, {0}}
+	return x, y
+}
+
+func TestFitLogistic(t *testing.T) {
+	x, y := groups()
+	for _, opts := range []*ml.LogisticOptions{
+		{Solver: ml.SolverIRLS, Iterations: 100, Tolerance: 1e-12},
+		{Solver: ml.SolverGD, LearnRate: 2, Iterations: 100000, Tolerance: 1e-12},
+	} {
+		l, result, err := ml.FitLogistic(x, y, opts)
+		if err != nil {
+			t.Fatalf("FitLogistic(%s) unexpected error: %v", opts.Solver, err)
+		}
+		if !result.Converged() || result.Steps <= 1 || result.Steps >= opts.Iterations || result.Loss != l.Loss(x, y, nil) {
+			t.Errorf("FitLogistic(%s): expected to converge within %d steps, actual %+v", opts.Solver, opts.Iterations, result)
+		}
+		if math.Abs(l.Bias[0]-math.Log(1.0/3)) > 1e-6 || math.Abs(l.Weights[0][0]-2*math.Log(3)) > 1e-6 {
+			t.Errorf("FitLogistic(%s): expected b=%v w=%v, actual b=%v w=%v", opts.Solver, math.Log(1.0/3), 2*math.Log(3), l.Bias, l.Weights)
+		}
+		expected := [][]float64{{0.25}, {0.75}}
+		if p := l.Predict([][]float64{{0}, {1}}); !ml.MatrixAlmostEquals(expected, p, 1e-6) {
+			t.Errorf("Predict with %s: expected %v, actual %v", opts.Solver, expected, p)
+		}
+		// the loss of the proportions of both groups
+		if loss, expected := l.Loss(x, y, nil), -(0.25*math.Log(0.25) + 0.75*math.Log(0.75)); math.Abs(loss-expected) > 1e-9 {
+			t.Errorf("Loss with %s: expected %v, actual %v", opts.Solver, expected, loss)
+		}
+	}
+}
+
+func TestFitLogisticIterations(t *testing.T) {
+	// a few steps of gradient descent are not enough
+	x, y := groups()
+	_, result, err := ml.FitLogistic(x, y, &ml.LogisticOptions{Solver: ml.SolverGD, LearnRate: 0.1, Iterations: 5, Tolerance: 1e-12})
+	if err != nil || result.Converged() || result.Reason != ml.StopMaxSteps || result.Steps != 5 {
+		t.Errorf("FitLogistic(5 gd steps): expected to stop by %s after 5 steps, actual %+v (err %v)", ml.StopMaxSteps, result, err)
+	}
+}
+
+func TestFitLogisticClassWeights(t *testing.T) {
+	// class 1 counting 3 times makes the odds 1 and 9
+	x, y := groups()
+	opts := &ml.LogisticOptions{Solver: ml.SolverIRLS, ClassWeights: []float64{1, 3}, Iterations: 100, Tolerance: 1e-12}
+	l, _, err := ml.FitLogistic(x, y, opts)
+	if err != nil || math.Abs(l.Bias[0]) > 1e-9 || math.Abs(l.Weights[0][0]-math.Log(9)) > 1e-9 {
+		t.Errorf("FitLogistic(class weights [1 3]): expected b=0 w=%v, actual %+v (err %v)", math.Log(9), l, err)
+	}
+
+	balanced := ml.BalancedClassWeights([][]float64{{1}, {0}, {0}, {0}})
+	if !ml.ArrayEquals([]float64{2.0 / 3, 2}, balanced) {
+		t.Errorf("BalancedClassWeights: expected [0.667 2], actual %v", balanced)
+	}
+	balanced = ml.BalancedClassWeights([][]float64{{1, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}})
+	if !ml.ArrayEquals([]float64{2.0 / 3, 4.0 / 3, 4.0 / 3}, balanced) {
+		t.Errorf("BalancedClassWeights: expected [0.667 1.333 1.333], actual %v", balanced)
+	}
+}
+
+func TestFitLogisticMultinomial(t *testing.T) {
+	// three groups, told apart by two indicators, with their own class proportions
+	x := [][]float64{{0, 0}, {0, 0}, {0, 0}, {0, 0}, {1, 0}, {1, 0}, {1, 0}, {1, 0}, {0, 1}, {0, 1}, {0, 1}, {0, 1}}
+	y := [][]float64{
+		{1, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1},
+		{0, 1, 0}, {0, 1, 0}, {0, 0, 1}, {1, 0, 0},
+		{0, 0, 1}, {0, 0, 1}, {1, 0, 0}, {0, 1, 0},
+	}
+	expected := [][]float64{{0.5, 0.25, 0.25}, {0.25, 0.5, 0.25}, {0.25, 0.25, 0.5}}
+	for _, opts := range []*ml.LogisticOptions{
+		{Solver: ml.SolverIRLS, Iterations: 100, Tolerance: 1e-12},
+		{Solver: ml.SolverGD, LearnRate: 2, Iterations: 100000, Tolerance: 1e-12},
+	} {
+		l, _, err := ml.FitLogistic(x, y, opts)
+		if err != nil {
+			t.Fatalf("FitLogistic(%s, multinomial) unexpected error: %v", opts.Solver, err)
+		}
+		if p := l.Predict([][]float64{{0, 0}, {1, 0}, {0, 1}}); !ml.MatrixAlmostEquals(expected, p, 1e-6) {
+			t.Errorf("Predict with %s, multinomial: expected %v, actual %v", opts.Solver, expected, p)
+		}
+		for _, row := range append(l.Weights, l.Bias) {
+			if math.Abs(row[0]+row[1]+row[2]) > 1e-9 {
+				t.Errorf("FitLogistic(%s, multinomial): expected parameters summing to 0 over the classes, actual %v", opts.Solver, row)
+			}
+		}
+		if !l.Multinomial() || l.Layer().Activation != ml.ActivationSoftmax {
+			t.Errorf("FitLogistic(%s, multinomial): expected a softmax layer, actual %+v", opts.Solver, l.Layer())
+		}
+	}
+}
+
+func TestFitLogisticPenalty(t *testing.T) {
+	x, y := groups()
+	plain, _, _ := ml.FitLogistic(x, y, &ml.LogisticOptions{Solver: ml.SolverIRLS, Iterations: 100, Tolerance: 1e-12})
+	for _, opts := range []*ml.LogisticOptions{
+		{Solver: ml.SolverIRLS, Penalty: &ml.Penalty{L2: 0.1}, Iterations: 100, Tolerance: 1e-12},
+		{Solver: ml.SolverGD, Penalty: &ml.Penalty{L2: 0.1}, LearnRate: 1, Iterations: 100000, Tolerance: 1e-12},
+		{Solver: ml.SolverGD, Penalty: &ml.Penalty{L1: 0.05}, LearnRate: 0.1, Iterations: 10000},
+	} {
+		l, _, err := ml.FitLogistic(x, y, opts)
+		if err != nil || l.Weights[0][0] <= 0 || l.Weights[0][0] >= plain.Weights[0][0]-0.1 {
+			t.Errorf("FitLogistic(%s, %+v): expected a weight shrunk from %v, actual %v (err %v)", opts.Solver, *opts.Penalty, plain.Weights, l, err)
+		}
+	}
+
+	// the penalized fit sets the gradient of the loss against that of the penalty
+	l2, _, _ := ml.FitLogistic(x, y, &ml.LogisticOptions{Solver: ml.SolverIRLS, Penalty: &ml.Penalty{L2: 0.1}, Iterations: 100, Tolerance: 1e-12})
+	gd, _, _ := ml.FitLogistic(x, y, &ml.LogisticOptions{Solver: ml.SolverGD, Penalty: &ml.Penalty{L2: 0.1}, LearnRate: 1, Iterations: 100000, Tolerance: 1e-12})
+	if math.Abs(l2.Weights[0][0]-gd.Weights[0][0]) > 1e-6 || math.Abs(l2.Bias[0]-gd.Bias[0]) > 1e-6 {
+		t.Errorf("FitLogistic(L2 0.1): expected irls %+v and gd %+v to agree", l2, gd)
+	}
+}
+
+func TestFitLogisticErrors(t *testing.T) {
+	x, y := groups()
+	for _, opts := range []*ml.LogisticOptions{
+		{Solver: "lbfgs", Iterations: 10},
+		{Solver: ml.SolverGD, Iterations: 10},
+		{Solver: ml.SolverIRLS},
+		{Solver: ml.SolverIRLS, Penalty: &ml.Penalty{L1: 0.1}, Iterations: 10},
+		{Solver: ml.SolverIRLS, ClassWeights: []float64{1, 2, 3}, Iterations: 10},
+		{Solver: ml.SolverIRLS, ClassWeights: []float64{1, -1}, Iterations: 10},
+	} {
+		if _, _, err := ml.FitLogistic(x, y, opts); err == nil {
+			t.Errorf("FitLogistic(%+v): expected err != nil", *opts)
+		}
+	}
+	if _, _, err := ml.FitLogistic(x, ml.FilledMatrix(len(x), 1, 2), &ml.LogisticOptions{Solver: ml.SolverIRLS, Iterations: 10}); err == nil {
+		t.Errorf("FitLogistic of a target of 2: expected err != nil")
+	}
+}
+
+func TestLogisticSummarize(t *testing.T) {
+	// the variances of log odds are 1/(n·p·(1-p)) for each group
+	x, y := groups()
+	l, _, _ := ml.FitLogistic(x, y, &ml.LogisticOptions{Solver: ml.SolverIRLS, Iterations: 100, Tolerance: 1e-12})
+	coefficients, err := l.Summarize(x, []string{"group"}, 0.95)
+	if err != nil {
+		t.Fatal("Summarize unexpected error:", err)
+	}
+	stdErrs := []float64{math.Sqrt(4.0 / 3), math.Sqrt(8.0 / 3)}
+	for j, c := range coefficients {
+		if math.Abs(c.StdErr-stdErrs[j]) > 1e-6 || math.Abs(c.T-c.Value/c.StdErr) > 1e-12 {
+			t.Errorf("Summarize: expected std err %v of %s, actual %+v", stdErrs[j], c.Name, c)
+		}
+	}
+	// z = 2·log(3)/1.633 = 1.3455, two-sided p 0.1785, the interval ± 1.96 std errors
+	if c := coefficients[1]; c.Name != "group" || math.Abs(c.P-0.1785) > 1e-4 || math.Abs(c.Upper-c.Value-1.959964*c.StdErr) > 1e-5 {
+		t.Errorf("Summarize: expected p 0.1785 and the 95%% interval of group, actual %+v", c)
+	}
+}
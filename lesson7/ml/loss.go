@@ -0,0 +1,52 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// Loss functions understood by Loss.
+const (
+	LossMSE          = "mse"
+	LossCrossEntropy = "cross_entropy" // categorical, for softmax outputs
+)
+
+// KnownLoss checks if name is one of the loss functions understood by Loss.
+func KnownLoss(name string) bool {
+	switch name {
+	case LossMSE, LossCrossEntropy:
+		return true
+	}
+	return false
+}
+
+// Loss computes the named loss between the predictions and the targets. It returns the mean loss
+// per element (per row for cross_entropy) and the gradient with respect to every prediction of the
+// loss summed over the rows, so the learning rate applies per example as in the lesson7 network.
+// For mse that is the gradient of half the summed squared error. Cross entropy takes the targets
+// one-hot or as a single column of class labels counting from 0.
+func Loss(name string, yHat, y [][]float64) (float64, [][]float64) {
+	switch name {
+	case LossMSE:
+		diff := Sub(yHat, y)
+		return MeanM(Mul(diff, diff)), diff
+	case LossCrossEntropy:
+		y = oneHotTargets(y, len(yHat[0]))
+		total := float64(0)
+		grad := FilledMatrix(len(yHat), len(yHat[0]), 0)
+		for i, row := range yHat {
+			for j, p := range row {
+				if y[i][j] == 0 {
+					continue
+				}
+				// keep log and the gradient finite for saturated predictions
+				p = math.Max(p, 1e-15)
+				total -= y[i][j] * math.Log(p)
+				grad[i][j] = -y[i][j] / p
+			}
+		}
+		return total / float64(len(yHat)), grad
+	default:
+		panic(fmt.Sprintf("ml: unknown loss %q", name))
+	}
+}
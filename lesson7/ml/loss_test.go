@@ -0,0 +1,55 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestLossMSE(t *testing.T) {
+	yHat := [][]float64{{1, 2}, {3, 4}}
+	y := [][]float64{{1, 1}, {5, 4}}
+	expectedLoss := float64(1.25)
+	expectedGrad := [][]float64{{0, 1}, {-2, 0}}
+	actualLoss, actualGrad := ml.Loss(ml.LossMSE, yHat, y)
+	if actualLoss != expectedLoss {
+		t.Errorf("Loss(mse, %v, %v): expected loss %v, actual %v", yHat, y, expectedLoss, actualLoss)
+	}
+	if !ml.MatrixEquals(expectedGrad, actualGrad) {
+		t.Errorf("Loss(mse, %v, %v): expected gradient %v, actual %v", yHat, y, expectedGrad, actualGrad)
+	}
+}
+
+func TestLossCrossEntropy(t *testing.T) {
+	yHat := [][]float64{{0.5, 0.25, 0.25}, {0.1, 0.8, 0.1}}
+	oneHot := [][]float64{{1, 0, 0}, {0, 0, 1}}
+	labels := [][]float64{{0}, {2}}
+	expectedLoss := -(math.Log(0.5) + math.Log(0.1)) / 2
+	expectedGrad := [][]float64{{-2, 0, 0}, {0, 0, -10}}
+
+	for _, y := range [][][]float64{oneHot, labels} {
+		actualLoss, actualGrad := ml.Loss(ml.LossCrossEntropy, yHat, y)
+		if math.Abs(actualLoss-expectedLoss) > 1e-12 {
+			t.Errorf("Loss(cross_entropy, %v, %v): expected loss %v, actual %v", yHat, y, expectedLoss, actualLoss)
+		}
+		if !ml.MatrixAlmostEquals(expectedGrad, actualGrad, 1e-12) {
+			t.Errorf("Loss(cross_entropy, %v, %v): expected gradient %v, actual %v", yHat, y, expectedGrad, actualGrad)
+		}
+	}
+
+	// a certain wrong prediction stays finite
+	loss, grad := ml.Loss(ml.LossCrossEntropy, [][]float64{{1, 0}}, [][]float64{{0, 1}})
+	if math.IsInf(loss, 0) || math.IsInf(grad[0][1], 0) {
+		t.Errorf("Loss(cross_entropy): expected finite loss and gradient, actual %v and %v", loss, grad)
+	}
+}
+
+func TestKnownLoss(t *testing.T) {
+	if !ml.KnownLoss(ml.LossMSE) {
+		t.Errorf("KnownLoss(%v): expected true", ml.LossMSE)
+	}
+	if ml.KnownLoss("hinge") {
+		t.Errorf("KnownLoss(hinge): expected false")
+	}
+}
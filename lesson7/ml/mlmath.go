@@ -162,3 +162,46 @@ func Scale(m [][]float64, scalar float64) [][]float64 {
 
 	return result
 }
+
+// AddRow adds the given row to every row of the matrix and returns a new matrix.
+func AddRow(m [][]float64, row []float64) [][]float64 {
+	result := make([][]float64, len(m))
+
+	for i, r := range m {
+		result[i] = make([]float64, len(r))
+		for j, x := range r {
+			result[i][j] = x + row[j]
+		}
+	}
+
+	return result
+}
+
+// MulRow multiplies every row of the matrix element-wise by the given row and returns a new matrix.
+func MulRow(m [][]float64, row []float64) [][]float64 {
+	result := make([][]float64, len(m))
+
+	for i, r := range m {
+		result[i] = make([]float64, len(r))
+		for j, x := range r {
+			result[i][j] = x * row[j]
+		}
+	}
+
+	return result
+}
+
+// ColumnSums adds up every column of the matrix and returns the sums.
+func ColumnSums(m [][]float64) []float64 {
+	if len(m) == 0 {
+		return []float64{}
+	}
+
+	sums := make([]float64, len(m[0]))
+	for _, row := range m {
+		for j, x := range row {
+			sums[j] += x
+		}
+	}
+	return sums
+}
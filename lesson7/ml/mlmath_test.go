@@ -169,3 +169,32 @@ func TestScale(t *testing.T) {
 		t.Errorf("Scale(%v, %v): expected %v, actual %v", input1, input2, expected, actual)
 	}
 }
+
+func TestAddRow(t *testing.T) {
+	input1 := [][]float64{{5, 1}, {10, 12}}
+	input2 := []float64{1, -2}
+	expected := [][]float64{{6, -1}, {11, 10}}
+	actual := ml.AddRow(input1, input2)
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("AddRow(%v, %v): expected %v, actual %v", input1, input2, expected, actual)
+	}
+}
+
+func TestMulRow(t *testing.T) {
+	input1 := [][]float64{{5, 1}, {10, 12}}
+	input2 := []float64{2, -1}
+	expected := [][]float64{{10, -1}, {20, -12}}
+	actual := ml.MulRow(input1, input2)
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("MulRow(%v, %v): expected %v, actual %v", input1, input2, expected, actual)
+	}
+}
+
+func TestColumnSums(t *testing.T) {
+	input := [][]float64{{1, 2}, {3, 4}, {5, 6}}
+	expected := []float64{9, 12}
+	actual := ml.ColumnSums(input)
+	if !ml.ArrayEquals(expected, actual) {
+		t.Errorf("ColumnSums(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
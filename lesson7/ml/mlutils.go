@@ -1,6 +1,7 @@
 package ml
 
 import (
+	"math"
 	"strconv"
 )
 
@@ -171,3 +172,33 @@ func BinaryMatch(m1, m2 [][]float64) [][]float64 {
 	}
 	return result
 }
+
+// Rows returns a new matrix made of the given rows of the matrix, in the given order.
+func Rows(matrix [][]float64, indices []int) [][]float64 {
+	result := make([][]float64, len(indices))
+	for i, index := range indices {
+		result[i] = matrix[index]
+	}
+	return result
+}
+
+// MatrixAlmostEquals checks if two matrices have the same shape and every pair of elements
+// differs by at most tol.
+func MatrixAlmostEquals(matrix1, matrix2 [][]float64, tol float64) bool {
+	if len(matrix1) != len(matrix2) {
+		return false
+	}
+
+	for i, row := range matrix1 {
+		if len(row) != len(matrix2[i]) {
+			return false
+		}
+		for j, x := range row {
+			if math.Abs(x-matrix2[i][j]) > tol {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -173,3 +173,33 @@ func TestBinaryMatch(t *testing.T) {
 		t.Errorf("BinaryMatch(%v, %v): expected %v, actual %v", input1, input2, expected, actual)
 	}
 }
+
+func TestRows(t *testing.T) {
+	input := [][]float64{{1, 2}, {3, 4}, {5, 6}}
+	indices := []int{2, 0}
+	expected := [][]float64{{5, 6}, {1, 2}}
+	actual := ml.Rows(input, indices)
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Rows(%v, %v): expected %v, actual %v", input, indices, expected, actual)
+	}
+}
+
+func TestMatrixAlmostEquals(t *testing.T) {
+	var tests = []struct {
+		input1   [][]float64 // first matrix
+		input2   [][]float64 // second matrix
+		expected bool        // expected result
+	}{
+		{[][]float64{{1, 2}}, [][]float64{{1.0005, 1.9995}}, true},
+		{[][]float64{{1, 2}}, [][]float64{{1.002, 2}}, false},
+		{[][]float64{{1, 2}}, [][]float64{{1}}, false},
+		{[][]float64{{1, 2}}, [][]float64{{1, 2}, {3, 4}}, false},
+	}
+
+	for _, test := range tests {
+		actual := ml.MatrixAlmostEquals(test.input1, test.input2, 1e-3)
+		if actual != test.expected {
+			t.Errorf("MatrixAlmostEquals(%v, %v, 1e-3): expected %v, actual %v", test.input1, test.input2, test.expected, actual)
+		}
+	}
+}
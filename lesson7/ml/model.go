@@ -0,0 +1,177 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ModelVersion is the latest version of the on-disk model format. Version 2 added the fields that
+// change predictions (Classes, Basis, TargetScaler and TargetTransform), which readers of version 1
+// would ignore, so SaveModel writes version 1 only for models without them.
+const ModelVersion = 2
+
+// Model is everything needed to make predictions with a trained network: its layers and weights,
+// how raw columns are encoded and scaled into inputs, how outputs scale back into targets and some
+// notes about how it was trained.
+type Model struct {
+	Version  int       `json:"version"`
+	Network  Network   `json:"network"`
+	Columns  []Column  `json:"columns,omitempty"`
+	Scaler   *Scaler   `json:"scaler,omitempty"`
+	Features []string  `json:"features,omitempty"`
+	Targets  []string  `json:"targets,omitempty"`
+	Classes  []float64 `json:"classes,omitempty"` // values of the target of a classifier, one per output
+	Basis    *Basis    `json:"basis,omitempty"`   // expanding the encoded columns into the features of a linear model
+
+	TargetScaler    *Scaler      `json:"target_scaler,omitempty"`    // if the network was trained on standardized targets
+	TargetTransform *Transform   `json:"target_transform,omitempty"` // if the network was trained on transformed targets
+	Stats           *LinearStats `json:"stats,omitempty"`            // of the residuals of a linear model, for prediction intervals
+	Training        TrainingInfo `json:"training"`
+}
+
+// TrainingInfo records how a model was trained.
+type TrainingInfo struct {
+	Epochs     int       `json:"epochs"`
+	LearnRate  float64   `json:"learn_rate"`
+	Loss       float64   `json:"loss"`
+	TrainedAt  time.Time `json:"trained_at"`
+	StopReason string    `json:"stop_reason,omitempty"` // one of the Stop constants, if recorded
+}
+
+// Encode turns raw rows, with one value per column of the model, into the features Predict expects.
+// Models without columns take the features as they are, models with a basis expand them.
+func (m *Model) Encode(raw [][]float64) ([][]float64, error) {
+	x := raw
+	if m.Columns != nil {
+		var err error
+		if x, err = Encode(m.Columns, raw); err != nil {
+			return nil, err
+		}
+	}
+	if m.Basis != nil {
+		return m.Basis.Expand(x)
+	}
+	return x, nil
+}
+
+// EncodeTargets turns raw target rows into what the network is trained to output: one-hot rows for
+// a classifier, the targets as they are otherwise. It fails on a class the model does not know.
+func (m *Model) EncodeTargets(raw [][]float64) ([][]float64, error) {
+	if m.Classes == nil {
+		return raw, nil
+	}
+	return Encode([]Column{{Name: m.Targets[0], Categories: m.Classes}}, raw)
+}
+
+// Classify returns the most probable class of every row of predictions of a classifier.
+func (m *Model) Classify(yHat [][]float64) []float64 {
+	classes := make([]float64, len(yHat))
+	for i, label := range Argmax(yHat) {
+		classes[i] = m.Classes[label]
+	}
+	return classes
+}
+
+// Predict scales the features (if the model has a scaler), runs them through the network and
+// scales the outputs back to the units of the targets (if the model has a target scaler), undoing
+// the target transform if there is one. The transformed prediction of a linear model being the
+// mean of the transformed target, the prediction is then its median rather than its mean.
+func (m *Model) Predict(x [][]float64) [][]float64 {
+	return m.InvertTargets(m.Output(x))
+}
+
+// Output is Predict without undoing the target transform, in the units the network was fitted in.
+func (m *Model) Output(x [][]float64) [][]float64 {
+	if m.Scaler != nil {
+		x = m.Scaler.Transform(x)
+	}
+	yHat := m.Network.Predict(x)
+	if m.TargetScaler != nil {
+		yHat = m.TargetScaler.InverseTransform(yHat)
+	}
+	return yHat
+}
+
+// InvertTargets undoes the target transform of the model on every output, or returns them as they are.
+func (m *Model) InvertTargets(yHat [][]float64) [][]float64 {
+	if m.TargetTransform == nil || len(yHat) == 0 {
+		return yHat
+	}
+	columns := T(yHat)
+	for j, column := range columns {
+		columns[j] = m.TargetTransform.Invert(column)
+	}
+	return T(columns)
+}
+
+// SaveModel writes the model to the given path as JSON, replacing any existing file, with the
+// lowest version that can hold it. It refuses a network with parameters that are not finite, as
+// left by a fit that diverged.
+func SaveModel(path string, m *Model) error {
+	if name := notFinite(m.Network.Params()); name != "" {
+		return fmt.Errorf("ml: %s: %s is not finite, the fit diverged", path, name)
+	}
+	m.Version = 1
+	if m.Classes != nil || m.Basis != nil || m.TargetScaler != nil || m.TargetTransform != nil {
+		m.Version = 2
+	}
+	return writeJSON(path, m)
+}
+
+// LoadModel reads a model written by SaveModel and checks that its network is usable.
+func LoadModel(path string) (*Model, error) {
+	m := &Model{}
+	if err := readJSON(path, m); err != nil {
+		return nil, err
+	}
+
+	if m.Version < 1 || m.Version > ModelVersion {
+		return nil, fmt.Errorf("ml: %s: unsupported model version %d", path, m.Version)
+	}
+	if err := m.Network.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return m, nil
+}
+
+// writeJSON encodes v into a temporary file next to path and then renames it into place,
+// so a crash never leaves a half-written file behind.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// readJSON decodes the file at path into v.
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("ml: %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package ml_test
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"."
+)
+
+func TestSaveLoadModel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	model := &ml.Model{
+		Network: ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{0.1, -0.2}, {1.0 / 3, 1e-17}, {-4, 4}}, Activation: ml.ActivationSigmoid},
+			{Type: ml.LayerDense, Weights: [][]float64{{0.7}, {-2.5}}, Bias: []float64{0.123456789}, Activation: ml.ActivationLinear},
+		}},
+		Columns:  []ml.Column{{Name: "gre"}, {Name: "rank", Categories: []float64{1, 2}}},
+		Scaler:   &ml.Scaler{Columns: []int{0}, Mean: []float64{587.7}, Std: []float64{115.37}},
+		Features: []string{"gre", "rank1", "rank2"},
+		Targets:  []string{"admit"},
+		Training: ml.TrainingInfo{
+			Epochs:    100,
+			LearnRate: 0.005,
+			Loss:      0.19,
+			TrainedAt: time.Date(2017, 7, 8, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	path := filepath.Join(dir, "model.json")
+	if err := ml.SaveModel(path, model); err != nil {
+		t.Fatal("SaveModel unexpected error:", err)
+	}
+	loaded, err := ml.LoadModel(path)
+	if err != nil {
+		t.Fatal("LoadModel unexpected error:", err)
+	}
+	if !reflect.DeepEqual(model, loaded) {
+		t.Errorf("LoadModel: expected %+v, actual %+v", model, loaded)
+	}
+
+	// the loaded model predicts exactly the same
+	input := [][]float64{{600, 2}, {400, 1}}
+	encoded, err := loaded.Encode(input)
+	if err != nil {
+		t.Fatal("Encode unexpected error:", err)
+	}
+	expected := model.Predict([][]float64{{600, 0, 1}, {400, 1, 0}})
+	actual := loaded.Predict(encoded)
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Predict(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
+
+func TestSaveModelVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// models with fields that change predictions need a reader of version 2
+	network := ml.Network{Layers: []ml.Layer{{Type: ml.LayerDense, Weights: [][]float64{{1}}, Activation: ml.ActivationLinear}}}
+	var tests = []struct {
+		model   *ml.Model
+		version int
+	}{
+		{&ml.Model{Network: network}, 1},
+		{&ml.Model{Network: network, Stats: &ml.LinearStats{}}, 1},
+		{&ml.Model{Network: network, Classes: []float64{0, 1}}, 2},
+		{&ml.Model{Network: network, Basis: &ml.Basis{Degree: 2}}, 2},
+		{&ml.Model{Network: network, TargetScaler: &ml.Scaler{Columns: []int{0}, Mean: []float64{1}, Std: []float64{2}}}, 2},
+		{&ml.Model{Network: network, TargetTransform: &ml.Transform{Type: ml.TransformLog}}, 2},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, "model.json")
+		if err := ml.SaveModel(path, test.model); err != nil {
+			t.Fatal("SaveModel unexpected error:", err)
+		}
+		loaded, err := ml.LoadModel(path)
+		if err != nil {
+			t.Fatal("LoadModel unexpected error:", err)
+		}
+		if loaded.Version != test.version {
+			t.Errorf("SaveModel(%+v): expected version %v, actual %v", *test.model, test.version, loaded.Version)
+		}
+	}
+}
+
+func TestSaveModelDiverged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "model.json")
+	model := &ml.Model{Network: ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{1}}, Bias: []float64{math.NaN()}, Activation: ml.ActivationLinear},
+	}}}
+	if err := ml.SaveModel(path, model); err == nil {
+		t.Errorf("SaveModel(NaN bias): expected err != nil")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("SaveModel(NaN bias): expected no file, actual %v", err)
+	}
+}
+
+func TestLoadModelErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		name    string // file name
+		content string // file content
+	}{
+		{"garbage.json", "not json"},
+		{"unversioned.json", `{"network": {"layers": [{"type": "dense", "weights": [[1]], "activation": "linear"}]}}`},
+		{"future.json", `{"version": 3, "network": {"layers": [{"type": "dense", "weights": [[1]], "activation": "linear"}]}}`},
+		{"broken.json", `{"version": 1, "network": {"layers": [{"type": "dense", "weights": [[1]], "activation": "nope"}]}}`},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ml.LoadModel(path); err == nil {
+			t.Errorf("LoadModel(%s): expected err != nil", test.name)
+		}
+	}
+
+	if _, err := ml.LoadModel(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("LoadModel(missing.json): expected err != nil")
+	}
+}
+
+func TestModelClasses(t *testing.T) {
+	model := &ml.Model{Targets: []string{"rank"}, Classes: []float64{1, 2, 4}}
+
+	raw := [][]float64{{4}, {1}, {2}}
+	expected := [][]float64{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}}
+	actual, err := model.EncodeTargets(raw)
+	if err != nil || !ml.MatrixEquals(expected, actual) {
+		t.Errorf("EncodeTargets(%v): expected %v, actual %v (err %v)", raw, expected, actual, err)
+	}
+	if _, err := model.EncodeTargets([][]float64{{3}}); err == nil {
+		t.Errorf("EncodeTargets: expected err != nil for an unknown class")
+	}
+
+	yHat := [][]float64{{0.2, 0.1, 0.7}, {0.5, 0.3, 0.2}}
+	if classes := model.Classify(yHat); !ml.ArrayEquals([]float64{4, 1}, classes) {
+		t.Errorf("Classify(%v): expected [4 1], actual %v", yHat, classes)
+	}
+}
+
+func TestModelTargetScaler(t *testing.T) {
+	model := &ml.Model{
+		Network: ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{1, -1}}, Bias: []float64{0, 0.5}, Activation: ml.ActivationLinear},
+		}},
+		TargetScaler: &ml.Scaler{Columns: []int{0, 1}, Mean: []float64{100, 10}, Std: []float64{20, 2}},
+	}
+
+	x := [][]float64{{1}, {-0.5}}
+	expected := [][]float64{{120, 9}, {90, 12}}
+	if actual := model.Predict(x); !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Predict(%v): expected %v, actual %v", x, expected, actual)
+	}
+}
+
+func TestModelBasis(t *testing.T) {
+	// log(y) = 1 + 2·log(x)², so y = e·exp(2·log(x)²)
+	model := &ml.Model{
+		Network: ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{0}, {2}}, Bias: []float64{1}, Activation: ml.ActivationLinear},
+		}},
+		Basis:           &ml.Basis{Transforms: []*ml.Transform{{Type: ml.TransformLog}}, Degree: 2},
+		TargetTransform: &ml.Transform{Type: ml.TransformLog},
+	}
+
+	raw := [][]float64{{1}, {math.E}}
+	x, err := model.Encode(raw)
+	if expected := [][]float64{{0, 0}, {1, 1}}; err != nil || !ml.MatrixAlmostEquals(expected, x, 1e-12) {
+		t.Errorf("Encode(%v): expected %v, actual %v (err %v)", raw, expected, x, err)
+	}
+	if expected, actual := [][]float64{{1}, {3}}, model.Output(x); !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Output(%v): expected %v, actual %v", x, expected, actual)
+	}
+	if expected, actual := [][]float64{{math.E}, {math.Exp(3)}}, model.Predict(x); !ml.MatrixAlmostEquals(expected, actual, 1e-12) {
+		t.Errorf("Predict(%v): expected %v, actual %v", x, expected, actual)
+	}
+}
@@ -0,0 +1,456 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// Layer types understood by Network.
+const (
+	LayerDense      = "dense"
+	LayerDropout    = "dropout"
+	LayerBatchNorm  = "batch_norm"
+	LayerLayerNorm  = "layer_norm"
+	LayerActivation = "activation" // applies Activation alone, such as after a normalization
+)
+
+// Activation functions understood by Layer.
+const (
+	ActivationLinear  = "linear"
+	ActivationSigmoid = "sigmoid"
+	ActivationTanh    = "tanh"
+	ActivationReLU    = "relu"
+	ActivationSoftmax = "softmax" // over every row rather than element-wise
+)
+
+var activations = map[string]func(float64) float64{
+	ActivationLinear:  func(x float64) float64 { return x },
+	ActivationSigmoid: Sigmoid,
+	ActivationTanh:    math.Tanh,
+	ActivationReLU:    func(x float64) float64 { return math.Max(x, 0) },
+}
+
+// activationPrimes holds the derivative of every element-wise activation function.
+var activationPrimes = map[string]func(float64) float64{
+	ActivationLinear:  func(x float64) float64 { return 1 },
+	ActivationSigmoid: SigmoidPrime,
+	ActivationTanh: func(x float64) float64 {
+		tanh := math.Tanh(x)
+		return 1 - tanh*tanh
+	},
+	ActivationReLU: func(x float64) float64 {
+		if x > 0 {
+			return 1
+		}
+		return 0
+	},
+}
+
+// Layer is one step of a network. A dense layer multiplies its input by Weights, adds Bias (if any)
+// and applies Activation to the result; the other types use only the fields that concern them.
+type Layer struct {
+	Type       string      `json:"type"`
+	Weights    [][]float64 `json:"weights,omitempty"`
+	Bias       []float64   `json:"bias,omitempty"`
+	Activation string      `json:"activation,omitempty"`
+	Rate       float64     `json:"rate,omitempty"` // fraction of the units a dropout layer drops
+
+	// learnable scale and shift of the normalization layers, and the statistics batch
+	// normalization uses outside of training
+	Gamma       []float64 `json:"gamma,omitempty"`
+	Beta        []float64 `json:"beta,omitempty"`
+	RunningMean []float64 `json:"running_mean,omitempty"`
+	RunningVar  []float64 `json:"running_var,omitempty"`
+	Momentum    float64   `json:"momentum,omitempty"` // share of the running statistics kept after every batch
+	Epsilon     float64   `json:"epsilon,omitempty"`  // added to the variance
+
+	// regularization of the weights and of the bias, none if nil
+	Penalty     *Penalty `json:"penalty,omitempty"`
+	BiasPenalty *Penalty `json:"bias_penalty,omitempty"`
+
+	// values remembered by Forward for Backward, and the gradients Backward computes
+	input [][]float64
+	z     [][]float64
+	dW    [][]float64
+	dB    [][]float64
+	mask  [][]float64
+
+	xhat       [][]float64
+	variance   []float64
+	batchStats bool
+	dGamma     [][]float64
+	dBeta      [][]float64
+}
+
+// Network is a feed-forward network made of layers applied one after the other. A network is in
+// evaluation mode until Train is called.
+type Network struct {
+	Layers []Layer `json:"layers"`
+
+	rand *rand.Rand // source of the dropout masks, nil in evaluation mode
+}
+
+// Param is a trainable matrix of a network together with the gradient the last backward pass
+// computed for it, penalty included. Value shares memory with the layer, so updating it in place
+// updates the network.
+type Param struct {
+	Name    string
+	Value   [][]float64
+	Grad    [][]float64
+	Penalty *Penalty
+	Decay   bool // whether the optimizer's weight decay applies, true for weights but not biases
+}
+
+// KnownActivation checks if name is one of the activation functions understood by Layer.
+func KnownActivation(name string) bool {
+	_, ok := activations[name]
+	return ok || name == ActivationSoftmax
+}
+
+// NewNetwork returns a network of dense layers with the given sizes, from the number of inputs to
+// the number of outputs. The hidden layers use the activation, the last layer uses output. Weights
+// are drawn from a normal distribution with standard deviation 1/sqrt(inputs of the layer) seeded
+// with seed, and biases start at zero.
+func NewNetwork(sizes []int, activation, output string, seed uint64) *Network {
+	r := rand.New(rand.NewPCG(seed, seed))
+
+	net := &Network{}
+	for i := 1; i < len(sizes); i++ {
+		weights := FilledMatrix(sizes[i-1], sizes[i], 0)
+		for _, row := range weights {
+			for j := range row {
+				row[j] = r.NormFloat64() / math.Sqrt(float64(sizes[i-1]))
+			}
+		}
+
+		act := activation
+		if i == len(sizes)-1 {
+			act = output
+		}
+		net.Layers = append(net.Layers, Layer{
+			Type:       LayerDense,
+			Weights:    weights,
+			Bias:       FilledArray(sizes[i], 0),
+			Activation: act,
+		})
+	}
+	return net
+}
+
+// apply returns a new matrix with f applied to every element of the given matrix.
+func apply(f func(float64) float64, m [][]float64) [][]float64 {
+	result := make([][]float64, len(m))
+	for i, row := range m {
+		result[i] = make([]float64, len(row))
+		for j, x := range row {
+			result[i][j] = f(x)
+		}
+	}
+	return result
+}
+
+// Activate returns a new matrix with the named activation function applied to every element,
+// or to every row for softmax.
+func Activate(name string, m [][]float64) [][]float64 {
+	if name == ActivationSoftmax {
+		return Softmax(m)
+	}
+	f, ok := activations[name]
+	if !ok {
+		panic(fmt.Sprintf("ml: unknown activation %q", name))
+	}
+	return apply(f, m)
+}
+
+// activationBackward returns the gradient with respect to z of the named activation of z, given
+// the gradient with respect to the activation.
+func activationBackward(name string, z, grad [][]float64) [][]float64 {
+	if name != ActivationSoftmax {
+		return Mul(grad, apply(activationPrimes[name], z))
+	}
+
+	// the softmax jacobian is diag(s) - s s^T for every row
+	s := Softmax(z)
+	result := make([][]float64, len(grad))
+	for i, row := range grad {
+		dot := Sum(ArrayProduct(row, s[i]))
+		result[i] = make([]float64, len(row))
+		for j, g := range row {
+			result[i][j] = s[i][j] * (g - dot)
+		}
+	}
+	return result
+}
+
+// Forward computes the output of the layer for the given input.
+func (l *Layer) Forward(x [][]float64) [][]float64 {
+	switch l.Type {
+	case LayerDropout:
+		return x
+	case LayerActivation:
+		return Activate(l.Activation, x)
+	case LayerBatchNorm:
+		return AddRow(MulRow(T(normalizeRows(T(x), l.RunningMean, l.RunningVar, l.Epsilon)), l.Gamma), l.Beta)
+	case LayerLayerNorm:
+		mean, variance := rowStats(x)
+		return AddRow(MulRow(normalizeRows(x, mean, variance, l.Epsilon), l.Gamma), l.Beta)
+	}
+
+	z := Dot(x, l.Weights)
+	if l.Bias != nil {
+		z = AddRow(z, l.Bias)
+	}
+	return Activate(l.Activation, z)
+}
+
+// forward computes the output of the layer like Forward, but remembers what Backward needs.
+// Given a random source, which means training, a dropout layer zeroes every unit with probability
+// Rate and scales the others by 1/(1-Rate), so that the expected output matches Forward, and batch
+// normalization uses the statistics of the batch.
+func (l *Layer) forward(x [][]float64, r *rand.Rand) [][]float64 {
+	switch l.Type {
+	case LayerActivation:
+		l.z = x
+		return Activate(l.Activation, x)
+	case LayerBatchNorm, LayerLayerNorm:
+		return l.normForward(x, r != nil)
+	case LayerDropout:
+		if r == nil {
+			l.mask = nil
+			return x
+		}
+		l.mask = FilledMatrix(len(x), len(x[0]), 0)
+		for _, row := range l.mask {
+			for j := range row {
+				if r.Float64() >= l.Rate {
+					row[j] = 1 / (1 - l.Rate)
+				}
+			}
+		}
+		return Mul(x, l.mask)
+	}
+
+	l.input = x
+	l.z = Dot(x, l.Weights)
+	if l.Bias != nil {
+		l.z = AddRow(l.z, l.Bias)
+	}
+	return Activate(l.Activation, l.z)
+}
+
+// backward takes the gradient of the loss with respect to the layer's output, stores the gradients
+// for the weights and bias and returns the gradient with respect to the layer's input.
+func (l *Layer) backward(grad [][]float64) [][]float64 {
+	switch l.Type {
+	case LayerActivation:
+		return activationBackward(l.Activation, l.z, grad)
+	case LayerBatchNorm, LayerLayerNorm:
+		return l.normBackward(grad)
+	case LayerDropout:
+		if l.mask == nil {
+			return grad
+		}
+		return Mul(grad, l.mask)
+	}
+
+	delta := activationBackward(l.Activation, l.z, grad)
+	l.dW = Dot(T(l.input), delta)
+	if l.Penalty != nil {
+		l.dW = Add(l.dW, l.Penalty.Grad(l.Weights))
+	}
+	if l.Bias != nil {
+		l.dB = [][]float64{ColumnSums(delta)}
+		if l.BiasPenalty != nil {
+			l.dB = Add(l.dB, l.BiasPenalty.Grad([][]float64{l.Bias}))
+		}
+	}
+	return Dot(delta, T(l.Weights))
+}
+
+// Predict runs the input through every layer of the network and returns the output of the last one.
+func (n *Network) Predict(x [][]float64) [][]float64 {
+	for i := range n.Layers {
+		x = n.Layers[i].Forward(x)
+	}
+	return x
+}
+
+// Forward runs the input through every layer like Predict, and remembers the intermediate
+// values needed by Backward. In training mode dropout layers drop units; Predict never does.
+func (n *Network) Forward(x [][]float64) [][]float64 {
+	for i := range n.Layers {
+		x = n.Layers[i].forward(x, n.rand)
+	}
+	return x
+}
+
+// Train puts the network in training mode, drawing the dropout masks of Forward from r.
+func (n *Network) Train(r *rand.Rand) {
+	n.rand = r
+}
+
+// Eval puts the network back in evaluation mode, in which Forward computes the same as Predict.
+func (n *Network) Eval() {
+	n.rand = nil
+}
+
+// Training reports whether the network is in training mode.
+func (n *Network) Training() bool {
+	return n.rand != nil
+}
+
+// Backward propagates the gradient of the loss with respect to the network's output back through
+// the layers of the last Forward call, computing the gradients returned by Params.
+func (n *Network) Backward(grad [][]float64) {
+	for i := len(n.Layers) - 1; i >= 0; i-- {
+		grad = n.Layers[i].backward(grad)
+	}
+}
+
+// Params returns the trainable parameters of the network with their latest gradients.
+func (n *Network) Params() []Param {
+	var params []Param
+	for i := range n.Layers {
+		l := &n.Layers[i]
+		if l.Type == LayerBatchNorm || l.Type == LayerLayerNorm {
+			params = append(params,
+				Param{Name: fmt.Sprintf("%d.gamma", i), Value: [][]float64{l.Gamma}, Grad: l.dGamma},
+				Param{Name: fmt.Sprintf("%d.beta", i), Value: [][]float64{l.Beta}, Grad: l.dBeta})
+		}
+		if l.Type != LayerDense {
+			continue
+		}
+		params = append(params, Param{Name: fmt.Sprintf("%d.weights", i), Value: l.Weights, Grad: l.dW, Penalty: l.Penalty, Decay: true})
+		if l.Bias != nil {
+			params = append(params, Param{Name: fmt.Sprintf("%d.bias", i), Value: [][]float64{l.Bias}, Grad: l.dB, Penalty: l.BiasPenalty})
+		}
+	}
+	return params
+}
+
+// Penalty returns the sum of the penalties of all the parameters, to be added to the loss.
+func (n *Network) Penalty() float64 {
+	total := float64(0)
+	for _, p := range n.Params() {
+		total += p.Penalty.Loss(p.Value)
+	}
+	return total
+}
+
+// Clone returns a deep copy of the network's layers and parameters.
+func (n *Network) Clone() *Network {
+	clone := &Network{Layers: make([]Layer, len(n.Layers))}
+	for i, l := range n.Layers {
+		clone.Layers[i] = Layer{
+			Type:        l.Type,
+			Activation:  l.Activation,
+			Rate:        l.Rate,
+			Gamma:       copyArray(l.Gamma),
+			Beta:        copyArray(l.Beta),
+			RunningMean: copyArray(l.RunningMean),
+			RunningVar:  copyArray(l.RunningVar),
+			Momentum:    l.Momentum,
+			Epsilon:     l.Epsilon,
+		}
+		if l.Weights != nil {
+			clone.Layers[i].Weights = copyMatrix(l.Weights)
+		}
+		if l.Penalty != nil {
+			penalty := *l.Penalty
+			clone.Layers[i].Penalty = &penalty
+		}
+		if l.BiasPenalty != nil {
+			penalty := *l.BiasPenalty
+			clone.Layers[i].BiasPenalty = &penalty
+		}
+		clone.Layers[i].Bias = copyArray(l.Bias)
+	}
+	return clone
+}
+
+// copyArray returns a copy of the array, nil for nil.
+func copyArray(a []float64) []float64 {
+	if a == nil {
+		return nil
+	}
+	return append([]float64(nil), a...)
+}
+
+// NumInputs returns the number of features the network expects.
+func (n *Network) NumInputs() int {
+	for _, l := range n.Layers {
+		switch l.Type {
+		case LayerDense:
+			return len(l.Weights)
+		case LayerBatchNorm, LayerLayerNorm:
+			return len(l.Gamma)
+		}
+	}
+	return 0
+}
+
+// Validate checks that the layers have known types and activations and that their shapes line up.
+func (n *Network) Validate() error {
+	if len(n.Layers) == 0 {
+		return fmt.Errorf("ml: network has no layers")
+	}
+
+	inputs := n.NumInputs()
+	if inputs == 0 {
+		return fmt.Errorf("ml: network has no dense or normalization layers")
+	}
+	for i, l := range n.Layers {
+		switch l.Type {
+		case LayerDense:
+		case LayerDropout:
+			if l.Rate < 0 || l.Rate >= 1 {
+				return fmt.Errorf("ml: layer %d: dropout rate %v is not in [0, 1)", i, l.Rate)
+			}
+			if l.Weights != nil || l.Bias != nil {
+				return fmt.Errorf("ml: layer %d: dropout layer with weights", i)
+			}
+			continue
+		case LayerActivation:
+			if !KnownActivation(l.Activation) {
+				return fmt.Errorf("ml: layer %d: unknown activation %q", i, l.Activation)
+			}
+			continue
+		case LayerBatchNorm, LayerLayerNorm:
+			if err := l.validateNorm(inputs); err != nil {
+				return fmt.Errorf("ml: layer %d: %v", i, err)
+			}
+			continue
+		default:
+			return fmt.Errorf("ml: layer %d: unknown type %q", i, l.Type)
+		}
+
+		if !KnownActivation(l.Activation) {
+			return fmt.Errorf("ml: layer %d: unknown activation %q", i, l.Activation)
+		}
+		if len(l.Weights) != inputs {
+			return fmt.Errorf("ml: layer %d: expected %d weight rows, got %d", i, inputs, len(l.Weights))
+		}
+		if inputs == 0 || len(l.Weights[0]) == 0 {
+			return fmt.Errorf("ml: layer %d: empty weights", i)
+		}
+		outputs := len(l.Weights[0])
+		for _, row := range l.Weights {
+			if len(row) != outputs {
+				return fmt.Errorf("ml: layer %d: ragged weights", i)
+			}
+		}
+		if l.Bias != nil && len(l.Bias) != outputs {
+			return fmt.Errorf("ml: layer %d: expected %d biases, got %d", i, outputs, len(l.Bias))
+		}
+		if err := l.Penalty.Validate(); err != nil {
+			return fmt.Errorf("ml: layer %d: %v", i, err)
+		}
+		if err := l.BiasPenalty.Validate(); err != nil {
+			return fmt.Errorf("ml: layer %d: %v", i, err)
+		}
+		inputs = outputs
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package ml_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"."
+)
+
+func TestNetworkPredict(t *testing.T) {
+	net := ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{1, 0}, {0, 2}}, Activation: ml.ActivationSigmoid},
+		{Type: ml.LayerDense, Weights: [][]float64{{1}, {-1}}, Bias: []float64{3}, Activation: ml.ActivationLinear},
+	}}
+	input := [][]float64{{0, 0}, {1, -1}}
+	expected := [][]float64{
+		{3},
+		{3 + ml.Sigmoid(1) - ml.Sigmoid(-2)},
+	}
+	actual := net.Predict(input)
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Predict(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
+
+func TestNetworkValidate(t *testing.T) {
+	var tests = []struct {
+		layers []ml.Layer // layers of the network
+		valid  bool       // whether the network is expected to be valid
+	}{
+		{[]ml.Layer{{Type: ml.LayerDense, Weights: [][]float64{{1}, {2}}, Activation: ml.ActivationSigmoid}}, true},
+		{[]ml.Layer{}, false},
+		{[]ml.Layer{{Type: "conv", Weights: [][]float64{{1}}, Activation: ml.ActivationSigmoid}}, false},
+		{[]ml.Layer{{Type: ml.LayerDense, Weights: [][]float64{{1}}, Activation: "nope"}}, false},
+		{[]ml.Layer{{Type: ml.LayerDense, Weights: [][]float64{{1, 2}, {3}}, Activation: ml.ActivationLinear}}, false},
+		{[]ml.Layer{{Type: ml.LayerDense, Weights: [][]float64{{1}}, Bias: []float64{1, 2}, Activation: ml.ActivationLinear}}, false},
+		{[]ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{1, 2}}, Activation: ml.ActivationLinear},
+			{Type: ml.LayerDense, Weights: [][]float64{{1}}, Activation: ml.ActivationLinear},
+		}, false},
+		{[]ml.Layer{
+			{Type: ml.LayerDropout, Rate: 0.2},
+			{Type: ml.LayerDense, Weights: [][]float64{{1}, {2}}, Activation: ml.ActivationLinear},
+			{Type: ml.LayerDropout, Rate: 0.5},
+		}, true},
+		{[]ml.Layer{{Type: ml.LayerDropout, Rate: 0.5}}, false},
+		{[]ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{1}}, Activation: ml.ActivationLinear},
+			{Type: ml.LayerDropout, Rate: 1},
+		}, false},
+	}
+
+	for i, test := range tests {
+		net := ml.Network{Layers: test.layers}
+		err := net.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("Validate() #%d: expected valid=%v, got err=%v", i, test.valid, err)
+		}
+	}
+}
+
+// numericalGradients estimates the gradient of the loss with respect to every parameter of the
+// network by central differences, running it with Forward so that the mode of the network applies.
+// Loss reports a mean while its gradient is for a sum, so the estimates are multiplied by scale.
+func numericalGradients(net *ml.Network, loss string, x, y [][]float64, scale float64) [][][]float64 {
+	const h = 1e-6
+	var grads [][][]float64
+	for _, p := range net.Params() {
+		grad := ml.FilledMatrix(len(p.Value), len(p.Value[0]), 0)
+		for i, row := range p.Value {
+			for j := range row {
+				orig := row[j]
+				row[j] = orig + h
+				plus, _ := ml.Loss(loss, net.Forward(x), y)
+				plus = plus*scale + net.Penalty()
+				row[j] = orig - h
+				minus, _ := ml.Loss(loss, net.Forward(x), y)
+				minus = minus*scale + net.Penalty()
+				row[j] = orig
+				grad[i][j] = (plus - minus) / (2 * h)
+			}
+		}
+		grads = append(grads, grad)
+	}
+	return grads
+}
+
+// checkGradients compares the gradients of Backward with numerical estimates.
+func checkGradients(t *testing.T, net *ml.Network, loss string, x, y [][]float64, scale float64) {
+	expected := numericalGradients(net, loss, x, y, scale)
+
+	_, grad := ml.Loss(loss, net.Forward(x), y)
+	net.Backward(grad)
+	for i, p := range net.Params() {
+		if !ml.MatrixAlmostEquals(expected[i], p.Grad, 1e-5) {
+			t.Errorf("Backward: gradient of %s: expected %v, actual %v", p.Name, expected[i], p.Grad)
+		}
+	}
+}
+
+func TestNetworkBackward(t *testing.T) {
+	net := &ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{0.1, -0.3, 0.5}, {0.7, 0.2, -0.4}}, Bias: []float64{0.1, 0, -0.1}, Activation: ml.ActivationSigmoid},
+		{Type: ml.LayerDense, Weights: [][]float64{{0.3, 1}, {-0.6, 0.2}, {0.9, -0.5}}, Bias: []float64{0.05, 0.2}, Activation: ml.ActivationLinear},
+	}}
+	x := [][]float64{{0.5, -1}, {1.5, 2}, {-0.3, 0.8}}
+	y := [][]float64{{1, 0}, {0, 1}, {0.5, 0.5}}
+	// the mse gradient is the one of half the summed squared error
+	for _, activation := range []string{ml.ActivationSigmoid, ml.ActivationTanh, ml.ActivationReLU} {
+		net.Layers[0].Activation = activation
+		checkGradients(t, net, ml.LossMSE, x, y, float64(len(y)*len(y[0]))/2)
+	}
+}
+
+func TestNetworkBackwardPenalty(t *testing.T) {
+	net := &ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{0.1, -0.3, 0.5}, {0.7, 0.2, -0.4}}, Bias: []float64{0.1, 0.3, -0.1}, Activation: ml.ActivationTanh,
+			Penalty: &ml.Penalty{L1: 0.1, L2: 0.2}, BiasPenalty: &ml.Penalty{L2: 0.5}},
+		{Type: ml.LayerDense, Weights: [][]float64{{0.3, 1}, {-0.6, 0.2}, {0.9, -0.5}}, Bias: []float64{0.05, 0.2}, Activation: ml.ActivationLinear,
+			Penalty: &ml.Penalty{L1: 0.3}},
+	}}
+	x := [][]float64{{0.5, -1}, {1.5, 2}, {-0.3, 0.8}}
+	y := [][]float64{{1, 0}, {0, 1}, {0.5, 0.5}}
+	checkGradients(t, net, ml.LossMSE, x, y, float64(len(y)*len(y[0]))/2)
+
+	// 0.1*2.2 + 0.2*1.04/2 + 0.5*0.11/2 + 0.3*3.5
+	expected := 0.22 + 0.104 + 0.0275 + 1.05
+	if actual := net.Penalty(); math.Abs(actual-expected) > 1e-12 {
+		t.Errorf("Penalty: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestNetworkBackwardSoftmax(t *testing.T) {
+	net := &ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{0.1, -0.3, 0.5}, {0.7, 0.2, -0.4}}, Bias: []float64{0.1, 0, -0.1}, Activation: ml.ActivationTanh},
+		{Type: ml.LayerDense, Weights: [][]float64{{0.3, 1, 0.2}, {-0.6, 0.2, 0.4}, {0.9, -0.5, 0}}, Bias: []float64{0.05, 0.2, -0.3}, Activation: ml.ActivationSoftmax},
+	}}
+	x := [][]float64{{0.5, -1}, {1.5, 2}, {-0.3, 0.8}}
+	y := [][]float64{{1, 0, 0}, {0, 0, 1}, {0, 1, 0}}
+	// cross entropy is the mean per row
+	checkGradients(t, net, ml.LossCrossEntropy, x, y, float64(len(y)))
+	checkGradients(t, net, ml.LossMSE, x, y, float64(len(y)*len(y[0]))/2)
+}
+
+func TestActivate(t *testing.T) {
+	input := [][]float64{{-2, 0, 3}}
+	var tests = []struct {
+		activation string      // activation function
+		expected   [][]float64 // expected result
+	}{
+		{ml.ActivationLinear, [][]float64{{-2, 0, 3}}},
+		{ml.ActivationSigmoid, ml.SigmoidM(input)},
+		{ml.ActivationTanh, [][]float64{{math.Tanh(-2), 0, math.Tanh(3)}}},
+		{ml.ActivationReLU, [][]float64{{0, 0, 3}}},
+		{ml.ActivationSoftmax, ml.Softmax(input)},
+	}
+
+	for _, test := range tests {
+		actual := ml.Activate(test.activation, input)
+		if !ml.MatrixEquals(test.expected, actual) {
+			t.Errorf("Activate(%v, %v): expected %v, actual %v", test.activation, input, test.expected, actual)
+		}
+	}
+}
+
+func TestNewNetwork(t *testing.T) {
+	net := ml.NewNetwork([]int{6, 4, 3, 1}, ml.ActivationLinear, ml.ActivationSigmoid, 1)
+	if err := net.Validate(); err != nil {
+		t.Fatal("Validate unexpected error:", err)
+	}
+	if len(net.Layers) != 3 || net.NumInputs() != 6 || len(net.Layers[2].Weights[0]) != 1 {
+		t.Errorf("NewNetwork([6 4 3 1]): unexpected shape %+v", net.Layers)
+	}
+	if net.Layers[0].Activation != ml.ActivationLinear || net.Layers[2].Activation != ml.ActivationSigmoid {
+		t.Errorf("NewNetwork: unexpected activations %v, %v", net.Layers[0].Activation, net.Layers[2].Activation)
+	}
+
+	// the same seed gives the same weights
+	same := ml.NewNetwork([]int{6, 4, 3, 1}, ml.ActivationLinear, ml.ActivationSigmoid, 1)
+	other := ml.NewNetwork([]int{6, 4, 3, 1}, ml.ActivationLinear, ml.ActivationSigmoid, 2)
+	if !ml.MatrixEquals(net.Layers[0].Weights, same.Layers[0].Weights) {
+		t.Errorf("NewNetwork: expected the same weights for the same seed")
+	}
+	if ml.MatrixEquals(net.Layers[0].Weights, other.Layers[0].Weights) {
+		t.Errorf("NewNetwork: expected different weights for different seeds")
+	}
+}
+
+func TestKnownActivation(t *testing.T) {
+	for _, name := range []string{ml.ActivationLinear, ml.ActivationSigmoid, ml.ActivationTanh, ml.ActivationReLU} {
+		if !ml.KnownActivation(name) {
+			t.Errorf("KnownActivation(%v): expected true", name)
+		}
+	}
+	if ml.KnownActivation("swish") {
+		t.Errorf("KnownActivation(swish): expected false")
+	}
+}
+
+func TestNetworkClone(t *testing.T) {
+	net := xorNetwork()
+	clone := net.Clone()
+	if !reflect.DeepEqual(net, clone) {
+		t.Errorf("Clone: expected %+v, actual %+v", net, clone)
+	}
+
+	// changing the clone leaves the original alone
+	clone.Layers[0].Weights[0][0] = 42
+	clone.Layers[1].Bias[0] = 42
+	if net.Layers[0].Weights[0][0] == 42 || net.Layers[1].Bias[0] == 42 {
+		t.Errorf("Clone: the copy shares memory with the original")
+	}
+}
+
+func TestNetworkDropout(t *testing.T) {
+	// the identity behind a dropout layer shows the dropped input
+	net := &ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDropout, Rate: 0.25},
+		{Type: ml.LayerDense, Weights: [][]float64{{1, 0}, {0, 1}}, Activation: ml.ActivationLinear},
+	}}
+	x := ml.FilledMatrix(200, 2, 3)
+
+	// evaluation mode passes everything through
+	if net.Training() || !ml.MatrixEquals(x, net.Forward(x)) || !ml.MatrixEquals(x, net.Predict(x)) {
+		t.Errorf("Forward: expected the input unchanged in evaluation mode")
+	}
+
+	net.Train(rand.New(rand.NewPCG(1, 1)))
+	dropped := net.Forward(x)
+	zeros := 0
+	for _, row := range dropped {
+		for _, v := range row {
+			switch v {
+			case 0:
+				zeros++
+			case 4: // 3 / (1 - 0.25)
+			default:
+				t.Fatalf("Forward: expected 0 or 4 in training mode, actual %v", v)
+			}
+		}
+	}
+	if zeros < 70 || zeros > 130 {
+		t.Errorf("Forward: expected about 100 of 400 units dropped, actual %v", zeros)
+	}
+
+	// the same seed drops the same units
+	net.Train(rand.New(rand.NewPCG(1, 1)))
+	if !ml.MatrixEquals(dropped, net.Forward(x)) {
+		t.Errorf("Forward: expected the same mask from the same seed")
+	}
+
+	// the gradient flows through the kept units only
+	grad := ml.FilledMatrix(200, 2, 1)
+	net.Backward(grad)
+	expected := ml.Dot(ml.T(dropped), grad)
+	if actual := net.Params()[0].Grad; !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Backward: expected %v, actual %v", expected, actual)
+	}
+
+	// Predict ignores the mode
+	if !ml.MatrixEquals(x, net.Predict(x)) {
+		t.Errorf("Predict: expected the input unchanged in training mode")
+	}
+	net.Eval()
+	if net.Training() || !ml.MatrixEquals(x, net.Forward(x)) {
+		t.Errorf("Eval: expected the input unchanged back in evaluation mode")
+	}
+}
@@ -0,0 +1,155 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// NewBatchNorm returns a batch normalization layer for the given number of units, with a scale of
+// one, a shift of zero and running statistics of a standard normal.
+func NewBatchNorm(size int) Layer {
+	return Layer{
+		Type:        LayerBatchNorm,
+		Gamma:       FilledArray(size, 1),
+		Beta:        FilledArray(size, 0),
+		RunningMean: FilledArray(size, 0),
+		RunningVar:  FilledArray(size, 1),
+		Momentum:    0.9,
+		Epsilon:     1e-5,
+	}
+}
+
+// NewLayerNorm returns a layer normalization layer for the given number of units, with a scale of
+// one and a shift of zero.
+func NewLayerNorm(size int) Layer {
+	return Layer{
+		Type:    LayerLayerNorm,
+		Gamma:   FilledArray(size, 1),
+		Beta:    FilledArray(size, 0),
+		Epsilon: 1e-5,
+	}
+}
+
+// rowStats returns the mean and the variance of every row.
+func rowStats(x [][]float64) (mean, variance []float64) {
+	mean = make([]float64, len(x))
+	variance = make([]float64, len(x))
+	for i, row := range x {
+		mean[i] = Mean(row)
+		for _, v := range row {
+			variance[i] += (v - mean[i]) * (v - mean[i])
+		}
+		variance[i] /= float64(len(row))
+	}
+	return mean, variance
+}
+
+// normalizeRows returns (x - mean) / sqrt(variance + epsilon), with one mean and variance per row.
+func normalizeRows(x [][]float64, mean, variance []float64, epsilon float64) [][]float64 {
+	result := make([][]float64, len(x))
+	for i, row := range x {
+		result[i] = make([]float64, len(row))
+		for j, v := range row {
+			result[i][j] = (v - mean[i]) / math.Sqrt(variance[i]+epsilon)
+		}
+	}
+	return result
+}
+
+// normalizeGrad returns the gradient with respect to x of normalizeRows applied with the statistics
+// of x itself, given the gradient with respect to its result xhat.
+func normalizeGrad(grad, xhat [][]float64, variance []float64, epsilon float64) [][]float64 {
+	result := make([][]float64, len(grad))
+	for i, row := range grad {
+		n := float64(len(row))
+		sum, dot := Sum(row), Sum(ArrayProduct(row, xhat[i]))
+		scale := 1 / (n * math.Sqrt(variance[i]+epsilon))
+		result[i] = make([]float64, len(row))
+		for j, g := range row {
+			result[i][j] = scale * (n*g - sum - xhat[i][j]*dot)
+		}
+	}
+	return result
+}
+
+// normForward normalizes x, every column over the batch for batch normalization and every row
+// over its units for layer normalization, then scales by Gamma and shifts by Beta. Batch
+// normalization uses the statistics of the batch and updates the running ones when training, and
+// the running statistics otherwise.
+func (l *Layer) normForward(x [][]float64, training bool) [][]float64 {
+	var xhat [][]float64
+	switch {
+	case l.Type == LayerLayerNorm:
+		mean, variance := rowStats(x)
+		xhat = normalizeRows(x, mean, variance, l.Epsilon)
+		l.variance, l.batchStats = variance, true
+
+	case training:
+		mean, variance := rowStats(T(x))
+		xhat = T(normalizeRows(T(x), mean, variance, l.Epsilon))
+		l.variance, l.batchStats = variance, true
+
+		// the running variance is the unbiased estimate
+		n := float64(len(x))
+		for j := range mean {
+			unbiased := variance[j]
+			if n > 1 {
+				unbiased *= n / (n - 1)
+			}
+			l.RunningMean[j] = l.Momentum*l.RunningMean[j] + (1-l.Momentum)*mean[j]
+			l.RunningVar[j] = l.Momentum*l.RunningVar[j] + (1-l.Momentum)*unbiased
+		}
+
+	default:
+		xhat = T(normalizeRows(T(x), l.RunningMean, l.RunningVar, l.Epsilon))
+		l.variance, l.batchStats = l.RunningVar, false
+	}
+
+	l.xhat = xhat
+	return AddRow(MulRow(xhat, l.Gamma), l.Beta)
+}
+
+// normBackward stores the gradients of Gamma and Beta and returns the gradient with respect to
+// the input of the last normForward.
+func (l *Layer) normBackward(grad [][]float64) [][]float64 {
+	xhat := l.xhat
+	l.dGamma = [][]float64{ColumnSums(Mul(grad, xhat))}
+	l.dBeta = [][]float64{ColumnSums(grad)}
+	dxhat := MulRow(grad, l.Gamma)
+
+	switch {
+	case l.Type == LayerLayerNorm:
+		return normalizeGrad(dxhat, xhat, l.variance, l.Epsilon)
+	case l.batchStats:
+		return T(normalizeGrad(T(dxhat), T(xhat), l.variance, l.Epsilon))
+	default:
+		// the running statistics are constants
+		result := make([][]float64, len(dxhat))
+		for i, row := range dxhat {
+			result[i] = make([]float64, len(row))
+			for j, g := range row {
+				result[i][j] = g / math.Sqrt(l.variance[j]+l.Epsilon)
+			}
+		}
+		return result
+	}
+}
+
+// validateNorm checks a normalization layer for the given number of units.
+func (l *Layer) validateNorm(units int) error {
+	if len(l.Gamma) != units || len(l.Beta) != units {
+		return fmt.Errorf("expected %d scales and shifts, got %d and %d", units, len(l.Gamma), len(l.Beta))
+	}
+	if l.Epsilon <= 0 {
+		return fmt.Errorf("epsilon %v is not positive", l.Epsilon)
+	}
+	if l.Type == LayerBatchNorm {
+		if len(l.RunningMean) != units || len(l.RunningVar) != units {
+			return fmt.Errorf("expected %d running means and variances, got %d and %d", units, len(l.RunningMean), len(l.RunningVar))
+		}
+		if l.Momentum < 0 || l.Momentum >= 1 {
+			return fmt.Errorf("momentum %v is not in [0, 1)", l.Momentum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package ml_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"."
+)
+
+// normNetwork returns a network with both kinds of normalization and non-trivial scales and shifts.
+func normNetwork() *ml.Network {
+	batchNorm := ml.NewBatchNorm(3)
+	batchNorm.Gamma = []float64{1.5, 0.5, -1}
+	batchNorm.Beta = []float64{0.1, -0.2, 0.3}
+	layerNorm := ml.NewLayerNorm(2)
+	layerNorm.Gamma = []float64{0.8, 1.2}
+	layerNorm.Beta = []float64{-0.1, 0.4}
+
+	return &ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{0.1, -0.3, 0.5}, {0.7, 0.2, -0.4}}, Bias: []float64{0.1, 0, -0.1}, Activation: ml.ActivationLinear},
+		batchNorm,
+		{Type: ml.LayerActivation, Activation: ml.ActivationTanh},
+		{Type: ml.LayerDense, Weights: [][]float64{{0.3, 1}, {-0.6, 0.2}, {0.9, -0.5}}, Bias: []float64{0.05, 0.2}, Activation: ml.ActivationLinear},
+		layerNorm,
+	}}
+}
+
+func TestNormBackward(t *testing.T) {
+	x := [][]float64{{0.5, -1}, {1.5, 2}, {-0.3, 0.8}, {1, 0.1}}
+	y := [][]float64{{1, 0}, {0, 1}, {0.5, 0.5}, {-1, 2}}
+	scale := float64(len(y)*len(y[0])) / 2
+
+	// training mode normalizes with the statistics of the batch
+	net := normNetwork()
+	if err := net.Validate(); err != nil {
+		t.Fatal("Validate unexpected error:", err)
+	}
+	net.Train(rand.New(rand.NewPCG(1, 1)))
+	checkGradients(t, net, ml.LossMSE, x, y, scale)
+
+	// evaluation mode with the running statistics
+	net.Eval()
+	checkGradients(t, net, ml.LossMSE, x, y, scale)
+}
+
+func TestBatchNormRunningStats(t *testing.T) {
+	net := &ml.Network{Layers: []ml.Layer{ml.NewBatchNorm(2)}}
+	net.Layers[0].Momentum = 0.5
+	x := [][]float64{{1, 10}, {3, 10}, {5, 16}}
+
+	// the output of training is normalized per column
+	net.Train(rand.New(rand.NewPCG(1, 1)))
+	out := net.Forward(x)
+	for j, column := range ml.T(out) {
+		if math.Abs(ml.Mean(column)) > 1e-9 || math.Abs(ml.Std(column)-1) > 1e-3 {
+			t.Errorf("Forward: expected column %d normalized, actual %v", j, column)
+		}
+	}
+
+	// half the batch mean and unbiased variance, half the initial 0 and 1
+	expectedMean := []float64{1.5, 6}
+	expectedVar := []float64{2.5, 6.5}
+	l := net.Layers[0]
+	if !ml.ArrayEquals(expectedMean, l.RunningMean) || !ml.ArrayEquals(expectedVar, l.RunningVar) {
+		t.Errorf("Forward: expected running mean %v and variance %v, actual %v and %v",
+			expectedMean, expectedVar, l.RunningMean, l.RunningVar)
+	}
+
+	// prediction uses the running statistics
+	expected := (1 - 1.5) / math.Sqrt(2.5+1e-5)
+	if actual := net.Predict(x)[0][0]; math.Abs(actual-expected) > 1e-12 {
+		t.Errorf("Predict: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestLayerNormForward(t *testing.T) {
+	l := ml.NewLayerNorm(4)
+	x := [][]float64{{1, 2, 3, 4}, {-10, 0, 10, 20}}
+	for i, row := range l.Forward(x) {
+		if math.Abs(ml.Mean(row)) > 1e-9 || math.Abs(ml.Std(row)-1) > 1e-3 {
+			t.Errorf("Forward: expected row %d normalized, actual %v", i, row)
+		}
+	}
+}
+
+func TestNormValidate(t *testing.T) {
+	var tests = []struct {
+		layer ml.Layer // layer after a dense layer with 3 units
+		valid bool
+	}{
+		{ml.NewBatchNorm(3), true},
+		{ml.NewLayerNorm(3), true},
+		{ml.NewBatchNorm(2), false},
+		{ml.Layer{Type: ml.LayerLayerNorm, Gamma: []float64{1, 1, 1}, Beta: []float64{0, 0, 0}}, false},
+		{ml.Layer{Type: ml.LayerBatchNorm, Gamma: []float64{1, 1, 1}, Beta: []float64{0, 0, 0}, Epsilon: 1e-5}, false},
+		{ml.Layer{Type: ml.LayerActivation, Activation: ml.ActivationReLU}, true},
+		{ml.Layer{Type: ml.LayerActivation}, false},
+	}
+
+	for i, test := range tests {
+		net := ml.Network{Layers: []ml.Layer{
+			{Type: ml.LayerDense, Weights: [][]float64{{1, 2, 3}}, Activation: ml.ActivationLinear},
+			test.layer,
+		}}
+		if err := net.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate() #%d: expected valid=%v, got err=%v", i, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package ml
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Data types that can be read from and written to .npy files.
+const (
+	NpyFloat32 = "<f4"
+	NpyFloat64 = "<f8"
+)
+
+// npyMagic starts every .npy file.
+const npyMagic = "\x93NUMPY"
+
+// ReadNpy reads a float32 or float64 array in the NumPy .npy format, in either byte order and in
+// either C or Fortran order. Two dimensional arrays become matrices, one dimensional arrays become
+// a matrix with a single row and scalars a 1x1 matrix.
+func ReadNpy(r io.Reader) ([][]float64, error) {
+	br := bufio.NewReader(r)
+
+	// magic string and version
+	preamble := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(br, preamble); err != nil {
+		return nil, fmt.Errorf("ml: npy: %v", err)
+	}
+	if string(preamble[:len(npyMagic)]) != npyMagic {
+		return nil, fmt.Errorf("ml: npy: not a .npy file")
+	}
+
+	// header length: 2 bytes in version 1, 4 bytes afterwards
+	var headerLen int
+	switch major := preamble[len(npyMagic)]; major {
+	case 1:
+		var n uint16
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return nil, fmt.Errorf("ml: npy: %v", err)
+		}
+		headerLen = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+			return nil, fmt.Errorf("ml: npy: %v", err)
+		}
+		headerLen = int(n)
+	default:
+		return nil, fmt.Errorf("ml: npy: unsupported version %d", major)
+	}
+
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("ml: npy: %v", err)
+	}
+	descr, fortran, shape, err := parseNpyHeader(string(header))
+	if err != nil {
+		return nil, err
+	}
+
+	// element type
+	var order binary.ByteOrder
+	switch descr[0] {
+	case '<', '|':
+		order = binary.LittleEndian
+	case '>':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("ml: npy: unsupported dtype %q", descr)
+	}
+	size := 0
+	switch descr[1:] {
+	case "f4":
+		size = 4
+	case "f8":
+		size = 8
+	default:
+		return nil, fmt.Errorf("ml: npy: unsupported dtype %q", descr)
+	}
+
+	// shape as rows and columns
+	numRows, numCols := 1, 1
+	switch len(shape) {
+	case 0:
+	case 1:
+		numCols = shape[0]
+	case 2:
+		numRows, numCols = shape[0], shape[1]
+	default:
+		return nil, fmt.Errorf("ml: npy: %d dimensional arrays are not supported", len(shape))
+	}
+
+	// data
+	data := make([]byte, numRows*numCols*size)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return nil, fmt.Errorf("ml: npy: %v", err)
+	}
+	matrix := FilledMatrix(numRows, numCols, 0)
+	for k := 0; k < numRows*numCols; k++ {
+		var x float64
+		if size == 4 {
+			x = float64(math.Float32frombits(order.Uint32(data[k*4:])))
+		} else {
+			x = math.Float64frombits(order.Uint64(data[k*8:]))
+		}
+		if fortran {
+			matrix[k%numRows][k/numRows] = x
+		} else {
+			matrix[k/numCols][k%numCols] = x
+		}
+	}
+
+	return matrix, nil
+}
+
+// parseNpyHeader extracts the fields of a header such as
+// {'descr': '<f8', 'fortran_order': False, 'shape': (3, 4), }.
+func parseNpyHeader(header string) (descr string, fortran bool, shape []int, err error) {
+	field := func(key string) (string, error) {
+		i := strings.Index(header, "'"+key+"'")
+		if i < 0 {
+			return "", fmt.Errorf("ml: npy: header has no %s", key)
+		}
+		rest := strings.TrimSpace(header[i+len(key)+2:])
+		if !strings.HasPrefix(rest, ":") {
+			return "", fmt.Errorf("ml: npy: malformed header %q", header)
+		}
+		return strings.TrimSpace(rest[1:]), nil
+	}
+
+	// 'descr': '<f8'
+	value, err := field("descr")
+	if err != nil {
+		return "", false, nil, err
+	}
+	if len(value) < 2 || value[0] != '\'' {
+		return "", false, nil, fmt.Errorf("ml: npy: malformed descr in %q", header)
+	}
+	end := strings.IndexByte(value[1:], '\'')
+	if end < 2 {
+		return "", false, nil, fmt.Errorf("ml: npy: malformed descr in %q", header)
+	}
+	descr = value[1 : end+1]
+
+	// 'fortran_order': False
+	value, err = field("fortran_order")
+	if err != nil {
+		return "", false, nil, err
+	}
+	switch {
+	case strings.HasPrefix(value, "True"):
+		fortran = true
+	case strings.HasPrefix(value, "False"):
+	default:
+		return "", false, nil, fmt.Errorf("ml: npy: malformed fortran_order in %q", header)
+	}
+
+	// 'shape': (3, 4)
+	value, err = field("shape")
+	if err != nil {
+		return "", false, nil, err
+	}
+	end = strings.IndexByte(value, ')')
+	if !strings.HasPrefix(value, "(") || end < 0 {
+		return "", false, nil, fmt.Errorf("ml: npy: malformed shape in %q", header)
+	}
+	for _, dim := range strings.Split(value[1:end], ",") {
+		dim = strings.TrimSpace(dim)
+		if dim == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(dim, "L"))
+		if err != nil || n < 0 {
+			return "", false, nil, fmt.Errorf("ml: npy: malformed shape in %q", header)
+		}
+		shape = append(shape, n)
+	}
+
+	return descr, fortran, shape, nil
+}
+
+// WriteNpy writes the matrix as a two dimensional array in the NumPy .npy format, using the
+// given data type (NpyFloat32 or NpyFloat64) and C or Fortran order.
+func WriteNpy(w io.Writer, m [][]float64, dtype string, fortran bool) error {
+	size := 0
+	switch dtype {
+	case NpyFloat32:
+		size = 4
+	case NpyFloat64:
+		size = 8
+	default:
+		return fmt.Errorf("ml: npy: unsupported dtype %q", dtype)
+	}
+
+	numRows, numCols := len(m), 0
+	if numRows > 0 {
+		numCols = len(m[0])
+	}
+
+	// the header is padded with spaces so the data starts on a 64 byte boundary
+	order := "False"
+	if fortran {
+		order = "True"
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': %s, 'shape': (%d, %d), }", dtype, order, numRows, numCols)
+	preambleLen := len(npyMagic) + 4
+	padding := 64 - (preambleLen+len(header)+1)%64
+	header += strings.Repeat(" ", padding%64) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+
+	data := make([]byte, numRows*numCols*size)
+	for i, row := range m {
+		if len(row) != numCols {
+			return fmt.Errorf("ml: npy: ragged matrix")
+		}
+		for j, x := range row {
+			k := i*numCols + j
+			if fortran {
+				k = j*numRows + i
+			}
+			if size == 4 {
+				binary.LittleEndian.PutUint32(data[k*4:], math.Float32bits(float32(x)))
+			} else {
+				binary.LittleEndian.PutUint64(data[k*8:], math.Float64bits(x))
+			}
+		}
+	}
+	buf.Write(data)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// LoadNpy reads the .npy file at the given path.
+func LoadNpy(path string) ([][]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadNpy(f)
+}
+
+// SaveNpy writes the matrix to a .npy file at the given path in C order.
+func SaveNpy(path string, m [][]float64, dtype string) error {
+	var buf bytes.Buffer
+	if err := WriteNpy(&buf, m, dtype, false); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// LoadNpz reads every array of a .npz archive, as written by numpy.savez or numpy.savez_compressed,
+// keyed by name without the .npy extension.
+func LoadNpz(path string) (map[string][][]float64, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	arrays := map[string][][]float64{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		m, err := ReadNpy(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		arrays[strings.TrimSuffix(f.Name, ".npy")] = m
+	}
+
+	return arrays, nil
+}
+
+// SaveNpz writes the matrices to a compressed .npz archive that numpy.load can read.
+func SaveNpz(path string, arrays map[string][][]float64, dtype string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	// sorted so the archive is the same every time
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Deflate})
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if err := WriteNpy(w, arrays[name], dtype, false); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
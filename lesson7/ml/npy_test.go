@@ -0,0 +1,155 @@
+package ml_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"."
+)
+
+// npyFile builds a version 1.0 .npy file the way numpy 1.11 writes it, with the header padded to 16 bytes.
+func npyFile(header string, data []byte) []byte {
+	header += strings.Repeat(" ", 15-(10+len(header))%16) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestReadNpy(t *testing.T) {
+	// np.array([[1, 2, 3], [4, 5, 6]], dtype='<f8')
+	f8 := make([]byte, 6*8)
+	for i, x := range []float64{1, 2, 3, 4, 5, 6} {
+		binary.LittleEndian.PutUint64(f8[i*8:], math.Float64bits(x))
+	}
+
+	// the same array as big endian float32 in Fortran order
+	f4 := make([]byte, 6*4)
+	for i, x := range []float32{1, 4, 2, 5, 3, 6} {
+		binary.BigEndian.PutUint32(f4[i*4:], math.Float32bits(x))
+	}
+
+	var tests = []struct {
+		input    []byte      // file content
+		expected [][]float64 // expected result
+	}{
+		{npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }", f8), [][]float64{{1, 2, 3}, {4, 5, 6}}},
+		{npyFile("{'descr': '>f4', 'fortran_order': True, 'shape': (2, 3), }", f4), [][]float64{{1, 2, 3}, {4, 5, 6}}},
+		{npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (6,), }", f8), [][]float64{{1, 2, 3, 4, 5, 6}}},
+		{npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (), }", f8[:8]), [][]float64{{1}}},
+	}
+
+	for _, test := range tests {
+		actual, err := ml.ReadNpy(bytes.NewReader(test.input))
+		if err != nil {
+			t.Errorf("ReadNpy(%q): unexpected error: %v", test.input[10:40], err)
+			continue
+		}
+		if !ml.MatrixEquals(test.expected, actual) {
+			t.Errorf("ReadNpy(%q): expected %v, actual %v", test.input[10:40], test.expected, actual)
+		}
+	}
+}
+
+func TestReadNpyErrors(t *testing.T) {
+	var tests = [][]byte{
+		[]byte("not a numpy file"),
+		npyFile("{'descr': '<i8', 'fortran_order': False, 'shape': (1,), }", make([]byte, 8)),
+		npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (1, 1, 1), }", make([]byte, 8)),
+		npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (2, 2), }", make([]byte, 8)),
+		npyFile("{'descr': '<f8', 'shape': (1,), }", make([]byte, 8)),
+	}
+
+	for _, input := range tests {
+		if _, err := ml.ReadNpy(bytes.NewReader(input)); err == nil {
+			t.Errorf("ReadNpy(%q): expected err != nil", input)
+		}
+	}
+}
+
+func TestWriteNpyRoundTrip(t *testing.T) {
+	input := [][]float64{{1.5, -2, 1.0 / 3}, {4, 5e-20, 6}}
+
+	for _, dtype := range []string{ml.NpyFloat64, ml.NpyFloat32} {
+		for _, fortran := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := ml.WriteNpy(&buf, input, dtype, fortran); err != nil {
+				t.Fatal("WriteNpy unexpected error:", err)
+			}
+			size := 8
+			if dtype == ml.NpyFloat32 {
+				size = 4
+			}
+			if (buf.Len()-6*size)%64 != 0 {
+				t.Errorf("WriteNpy(%v, %v): data is not aligned, file size %v", dtype, fortran, buf.Len())
+			}
+
+			actual, err := ml.ReadNpy(&buf)
+			if err != nil {
+				t.Fatal("ReadNpy unexpected error:", err)
+			}
+			tol := float64(0)
+			if dtype == ml.NpyFloat32 {
+				tol = 1e-7
+			}
+			if !ml.MatrixAlmostEquals(input, actual, tol) {
+				t.Errorf("WriteNpy(%v, %v): expected %v, actual %v", dtype, fortran, input, actual)
+			}
+		}
+	}
+}
+
+func TestSaveLoadNpz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := map[string][][]float64{
+		"w1": {{0.1, 0.2}, {0.3, 0.4}, {0.5, 0.6}},
+		"w2": {{0.7, 0.8}},
+	}
+	path := filepath.Join(dir, "weights.npz")
+	if err := ml.SaveNpz(path, input, ml.NpyFloat64); err != nil {
+		t.Fatal("SaveNpz unexpected error:", err)
+	}
+	actual, err := ml.LoadNpz(path)
+	if err != nil {
+		t.Fatal("LoadNpz unexpected error:", err)
+	}
+	if !reflect.DeepEqual(input, actual) {
+		t.Errorf("LoadNpz: expected %v, actual %v", input, actual)
+	}
+}
+
+func TestSaveLoadNpy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := [][]float64{{1, 2}, {3, 4}}
+	path := filepath.Join(dir, "m.npy")
+	if err := ml.SaveNpy(path, input, ml.NpyFloat64); err != nil {
+		t.Fatal("SaveNpy unexpected error:", err)
+	}
+	actual, err := ml.LoadNpy(path)
+	if err != nil {
+		t.Fatal("LoadNpy unexpected error:", err)
+	}
+	if !ml.MatrixEquals(input, actual) {
+		t.Errorf("LoadNpy: expected %v, actual %v", input, actual)
+	}
+}
@@ -0,0 +1,114 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// Optimization methods understood by Optimizer.
+const (
+	OptimizerSGD      = "sgd"
+	OptimizerMomentum = "momentum"
+	OptimizerAdam     = "adam"
+)
+
+// Optimizer updates the parameters of a network from their gradients. Everything it remembers
+// between steps is exported, so an optimizer saved in a checkpoint carries on exactly where it left off.
+type Optimizer struct {
+	Method      string  `json:"method"`
+	LearnRate   float64 `json:"learn_rate"`
+	Momentum    float64 `json:"momentum,omitempty"`     // momentum, or beta1 for adam
+	Beta2       float64 `json:"beta2,omitempty"`        // adam only
+	Epsilon     float64 `json:"epsilon,omitempty"`      // adam only
+	WeightDecay float64 `json:"weight_decay,omitempty"` // see Update
+	Steps       int     `json:"steps"`
+
+	// per parameter running averages, keyed by Param.Name
+	Velocity map[string][][]float64 `json:"velocity,omitempty"`
+	Squares  map[string][][]float64 `json:"squares,omitempty"`
+}
+
+// NewOptimizer returns an optimizer using the given method, with the usual defaults for the rest.
+func NewOptimizer(method string, learnRate float64) (*Optimizer, error) {
+	o := &Optimizer{Method: method, LearnRate: learnRate}
+	switch method {
+	case OptimizerSGD:
+	case OptimizerMomentum:
+		o.Momentum = 0.9
+	case OptimizerAdam:
+		o.Momentum = 0.9
+		o.Beta2 = 0.999
+		o.Epsilon = 1e-8
+	default:
+		return nil, fmt.Errorf("ml: unknown optimizer %q", method)
+	}
+	return o, nil
+}
+
+// Update takes one step against the gradient of every parameter, changing the values in place.
+// Parameters with Decay also shrink by LearnRate*WeightDecay of themselves, apart from the
+// gradient, so unlike an L2 penalty the decay is not rescaled by adam.
+func (o *Optimizer) Update(params []Param) {
+	o.Steps++
+	if o.Velocity == nil {
+		o.Velocity = map[string][][]float64{}
+	}
+	if o.Squares == nil && o.Method == OptimizerAdam {
+		o.Squares = map[string][][]float64{}
+	}
+
+	for _, p := range params {
+		if o.WeightDecay != 0 && p.Decay {
+			for _, row := range p.Value {
+				for j := range row {
+					row[j] -= o.LearnRate * o.WeightDecay * row[j]
+				}
+			}
+		}
+
+		switch o.Method {
+		case OptimizerSGD:
+			for i, row := range p.Value {
+				for j := range row {
+					row[j] -= p.Grad[i][j] * o.LearnRate
+				}
+			}
+
+		case OptimizerMomentum:
+			v := o.state(o.Velocity, p)
+			for i, row := range p.Value {
+				for j := range row {
+					v[i][j] = o.Momentum*v[i][j] - o.LearnRate*p.Grad[i][j]
+					row[j] += v[i][j]
+				}
+			}
+
+		case OptimizerAdam:
+			m := o.state(o.Velocity, p)
+			s := o.state(o.Squares, p)
+			corr1 := 1 - math.Pow(o.Momentum, float64(o.Steps))
+			corr2 := 1 - math.Pow(o.Beta2, float64(o.Steps))
+			for i, row := range p.Value {
+				for j := range row {
+					g := p.Grad[i][j]
+					m[i][j] = o.Momentum*m[i][j] + (1-o.Momentum)*g
+					s[i][j] = o.Beta2*s[i][j] + (1-o.Beta2)*g*g
+					row[j] -= o.LearnRate * (m[i][j] / corr1) / (math.Sqrt(s[i][j]/corr2) + o.Epsilon)
+				}
+			}
+
+		default:
+			panic(fmt.Sprintf("ml: unknown optimizer %q", o.Method))
+		}
+	}
+}
+
+// state returns the running average kept for the parameter, creating it filled with zeros if needed.
+func (o *Optimizer) state(states map[string][][]float64, p Param) [][]float64 {
+	s, ok := states[p.Name]
+	if !ok {
+		s = FilledMatrix(len(p.Value), len(p.Value[0]), 0)
+		states[p.Name] = s
+	}
+	return s
+}
@@ -0,0 +1,77 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestOptimizerSGD(t *testing.T) {
+	opt, err := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := [][]float64{{1, 2}}
+	opt.Update([]ml.Param{{Name: "w", Value: value, Grad: [][]float64{{2, -4}}}})
+	expected := [][]float64{{0, 4}}
+	if !ml.MatrixEquals(expected, value) {
+		t.Errorf("Update: expected %v, actual %v", expected, value)
+	}
+}
+
+func TestOptimizerMomentum(t *testing.T) {
+	opt, err := ml.NewOptimizer(ml.OptimizerMomentum, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := [][]float64{{1}}
+	grad := [][]float64{{1}}
+	opt.Update([]ml.Param{{Name: "w", Value: value, Grad: grad}}) // v = -0.1
+	opt.Update([]ml.Param{{Name: "w", Value: value, Grad: grad}}) // v = -0.09 - 0.1
+	expected := [][]float64{{1 - 0.1 - 0.19}}
+	if !ml.MatrixAlmostEquals(expected, value, 1e-12) {
+		t.Errorf("Update: expected %v, actual %v", expected, value)
+	}
+}
+
+func TestOptimizerAdam(t *testing.T) {
+	opt, err := ml.NewOptimizer(ml.OptimizerAdam, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// minimize (w - 3)^2
+	value := [][]float64{{0}}
+	for i := 0; i < 2000; i++ {
+		grad := [][]float64{{2 * (value[0][0] - 3)}}
+		opt.Update([]ml.Param{{Name: "w", Value: value, Grad: grad}})
+	}
+	if math.Abs(value[0][0]-3) > 1e-3 {
+		t.Errorf("Update: expected to converge to 3, actual %v", value[0][0])
+	}
+	if opt.Steps != 2000 {
+		t.Errorf("Steps: expected 2000, actual %v", opt.Steps)
+	}
+}
+
+func TestNewOptimizerUnknown(t *testing.T) {
+	if _, err := ml.NewOptimizer("nope", 0.1); err == nil {
+		t.Errorf("NewOptimizer(nope): expected err != nil")
+	}
+}
+
+func TestOptimizerWeightDecay(t *testing.T) {
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	opt.WeightDecay = 0.1
+	weights := [][]float64{{2}}
+	bias := [][]float64{{2}}
+	opt.Update([]ml.Param{
+		{Name: "w", Value: weights, Grad: [][]float64{{1}}, Decay: true},
+		{Name: "b", Value: bias, Grad: [][]float64{{1}}},
+	})
+	// 2 - 0.5*0.1*2 - 0.5*1 and 2 - 0.5*1
+	if !ml.MatrixAlmostEquals([][]float64{{1.4}}, weights, 1e-12) || !ml.MatrixEquals([][]float64{{1.5}}, bias) {
+		t.Errorf("Update: expected w = 1.4 and b = 1.5, actual %v and %v", weights, bias)
+	}
+}
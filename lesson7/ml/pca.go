@@ -0,0 +1,60 @@
+package ml
+
+import "fmt"
+
+// PCA projects rows onto the directions along which the data it was fitted on varies the most,
+// the principal components, and back.
+type PCA struct {
+	Mean                   []float64   `json:"mean"`
+	Components             [][]float64 `json:"components"`               // unit directions, one row per component
+	ExplainedVariance      []float64   `json:"explained_variance"`       // variance of the data along each component
+	ExplainedVarianceRatio []float64   `json:"explained_variance_ratio"` // its fraction of the total variance
+}
+
+// FitPCA finds the first k principal components of the rows of x from the singular value
+// decomposition of x centered, all min(rows, columns) of them if k is 0.
+func FitPCA(x [][]float64, k int) (*PCA, error) {
+	if len(x) < 2 {
+		return nil, fmt.Errorf("ml: pca needs at least 2 rows, got %d", len(x))
+	}
+	mean := columnMeans(x)
+	_, s, v, err := SVD(AddRow(x, Scale([][]float64{mean}, -1)[0]))
+	if err != nil {
+		return nil, err
+	}
+	if k == 0 {
+		k = len(s)
+	}
+	if k < 0 || k > len(s) {
+		return nil, fmt.Errorf("ml: pca of %d components, expected 1 to %d", k, len(s))
+	}
+
+	p := &PCA{
+		Mean:                   mean,
+		Components:             T(v)[:k],
+		ExplainedVariance:      make([]float64, k),
+		ExplainedVarianceRatio: make([]float64, k),
+	}
+	var total float64
+	for _, sv := range s {
+		total += sv * sv
+	}
+	for i := 0; i < k; i++ {
+		p.ExplainedVariance[i] = s[i] * s[i] / float64(len(x)-1)
+		if total > 0 {
+			p.ExplainedVarianceRatio[i] = s[i] * s[i] / total
+		}
+	}
+	return p, nil
+}
+
+// Transform returns the coordinates of the rows along the components.
+func (p *PCA) Transform(x [][]float64) [][]float64 {
+	return Dot(AddRow(x, Scale([][]float64{p.Mean}, -1)[0]), T(p.Components))
+}
+
+// InverseTransform maps coordinates along the components back to rows in the original space,
+// losing what the dropped components held.
+func (p *PCA) InverseTransform(z [][]float64) [][]float64 {
+	return AddRow(Dot(z, p.Components), p.Mean)
+}
@@ -0,0 +1,63 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestPCA(t *testing.T) {
+	// points on the line y = 2x, plus noise off it
+	x := [][]float64{{1, 2.1}, {2, 3.9}, {3, 6.2}, {4, 7.8}, {5, 10}}
+	pca, err := ml.FitPCA(x, 0)
+	if err != nil {
+		t.Fatal("FitPCA unexpected error:", err)
+	}
+	if !ml.ArrayEquals([]float64{3, 6}, pca.Mean) {
+		t.Errorf("FitPCA(%v): expected mean [3 6], actual %v", x, pca.Mean)
+	}
+	first := pca.Components[0]
+	if math.Abs(first[1]/first[0]-2) > 0.05 || first[0] < 0 {
+		t.Errorf("FitPCA(%v): expected a first component along [1 2], actual %v", x, first)
+	}
+	if ratio := pca.ExplainedVarianceRatio; ratio[0] < 0.99 || math.Abs(ratio[0]+ratio[1]-1) > 1e-12 {
+		t.Errorf("FitPCA(%v): expected ratios summing to 1 mostly on the first, actual %v", x, ratio)
+	}
+
+	// the explained variances are the eigenvalues of the covariance matrix
+	centered := ml.AddRow(x, []float64{-3, -6})
+	values, _, _ := ml.SymmetricEigen(ml.Scale(ml.Dot(ml.T(centered), centered), 1.0/float64(len(x)-1)))
+	if !ml.MatrixAlmostEquals([][]float64{values}, [][]float64{pca.ExplainedVariance}, 1e-12) {
+		t.Errorf("FitPCA(%v): expected explained variance %v, actual %v", x, values, pca.ExplainedVariance)
+	}
+
+	// all the components go back to the data, one gives its projection on the line
+	if back := pca.InverseTransform(pca.Transform(x)); !ml.MatrixAlmostEquals(x, back, 1e-12) {
+		t.Errorf("InverseTransform(Transform(%v)): expected the rows back, actual %v", x, back)
+	}
+	pca1, err := ml.FitPCA(x, 1)
+	if err != nil {
+		t.Fatal("FitPCA unexpected error:", err)
+	}
+	z := pca1.Transform(x)
+	if len(z[0]) != 1 {
+		t.Errorf("Transform: expected a column per component, actual %v", z)
+	}
+	for i, row := range pca1.InverseTransform(z) {
+		// what the projection loses of every row is orthogonal to the first component
+		off := math.Abs(ml.Dot([][]float64{ml.Sub([][]float64{row}, [][]float64{x[i]})[0]}, ml.T([][]float64{first}))[0][0])
+		if off > 1e-12 {
+			t.Errorf("InverseTransform: expected the projection of %v on the first component, actual %v", x[i], row)
+		}
+	}
+
+	for _, k := range []int{-1, 3} {
+		if _, err := ml.FitPCA(x, k); err == nil {
+			t.Errorf("FitPCA(%d): expected err != nil", k)
+		}
+	}
+	if _, err := ml.FitPCA(x[:1], 0); err == nil {
+		t.Errorf("FitPCA: expected err != nil for a single row")
+	}
+}
@@ -0,0 +1,57 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// Penalty regularizes a parameter by adding L1 times the sum of its absolute values and L2 times
+// half the sum of its squares to the loss. Setting both gives the elastic net.
+type Penalty struct {
+	L1 float64 `json:"l1,omitempty"`
+	L2 float64 `json:"l2,omitempty"`
+}
+
+// Loss returns the penalty of the values, 0 for a nil penalty.
+func (p *Penalty) Loss(w [][]float64) float64 {
+	if p == nil {
+		return 0
+	}
+	loss := float64(0)
+	for _, row := range w {
+		for _, v := range row {
+			loss += p.L1*math.Abs(v) + p.L2*v*v/2
+		}
+	}
+	return loss
+}
+
+// Grad returns the gradient of the penalty with respect to the values, taking the subgradient
+// of the L1 term at 0 to be 0.
+func (p *Penalty) Grad(w [][]float64) [][]float64 {
+	grad := make([][]float64, len(w))
+	for i, row := range w {
+		grad[i] = make([]float64, len(row))
+		if p == nil {
+			continue
+		}
+		for j, v := range row {
+			sign := float64(0)
+			if v > 0 {
+				sign = 1
+			} else if v < 0 {
+				sign = -1
+			}
+			grad[i][j] = p.L1*sign + p.L2*v
+		}
+	}
+	return grad
+}
+
+// Validate checks that the penalty is not negative.
+func (p *Penalty) Validate() error {
+	if p != nil && (p.L1 < 0 || p.L2 < 0) {
+		return fmt.Errorf("ml: negative penalty %+v", *p)
+	}
+	return nil
+}
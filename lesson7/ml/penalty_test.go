@@ -0,0 +1,35 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestPenalty(t *testing.T) {
+	w := [][]float64{{1, -2}, {0, 3}}
+	var tests = []struct {
+		penalty *ml.Penalty // penalty to apply
+		loss    float64     // expected loss
+		grad    [][]float64 // expected gradient
+	}{
+		{nil, 0, [][]float64{{0, 0}, {0, 0}}},
+		{&ml.Penalty{L1: 0.5}, 3, [][]float64{{0.5, -0.5}, {0, 0.5}}},
+		{&ml.Penalty{L2: 0.5}, 3.5, [][]float64{{0.5, -1}, {0, 1.5}}},
+		{&ml.Penalty{L1: 0.5, L2: 0.5}, 6.5, [][]float64{{1, -1.5}, {0, 2}}},
+	}
+
+	for _, test := range tests {
+		if loss := test.penalty.Loss(w); math.Abs(loss-test.loss) > 1e-12 {
+			t.Errorf("Loss(%+v): expected %v, actual %v", test.penalty, test.loss, loss)
+		}
+		if grad := test.penalty.Grad(w); !ml.MatrixEquals(test.grad, grad) {
+			t.Errorf("Grad(%+v): expected %v, actual %v", test.penalty, test.grad, grad)
+		}
+	}
+
+	if err := (&ml.Penalty{L1: -1}).Validate(); err == nil {
+		t.Errorf("Validate: expected err != nil for a negative penalty")
+	}
+}
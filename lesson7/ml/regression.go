@@ -0,0 +1,182 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+)
+
+// FitLinear fits y ≈ x·w + b exactly by least squares, with ridge times |w|² added to the squared
+// error. The intercept b is not penalized: x and y are centered before solving for w, then b
+// makes the fit go through their means. Each column of y gets its own column of w and its own b.
+func FitLinear(x, y [][]float64, ridge float64) (w [][]float64, b []float64, err error) {
+	if len(x) == 0 || len(x) != len(y) {
+		return nil, nil, fmt.Errorf("ml: linear fit of %d rows against %d targets", len(x), len(y))
+	}
+	xMean, yMean := columnMeans(x), columnMeans(y)
+	w, err = LeastSquares(AddRow(x, Scale([][]float64{xMean}, -1)[0]), AddRow(y, Scale([][]float64{yMean}, -1)[0]), ridge)
+	if err != nil {
+		return nil, nil, err
+	}
+	b = Sub([][]float64{yMean}, Dot([][]float64{xMean}, w))[0]
+	return w, b, nil
+}
+
+// columnMeans returns the mean of every column of the matrix.
+func columnMeans(m [][]float64) []float64 {
+	means := ColumnSums(m)
+	for j := range means {
+		means[j] /= float64(len(m))
+	}
+	return means
+}
+
+// LinearStats describes the residuals of a linear fit y ≈ x·w + b, enough to put intervals around
+// its predictions under the usual assumptions of independent, normal errors of equal variance.
+type LinearStats struct {
+	Rows     int         `json:"rows"`
+	DoF      int         `json:"dof"`      // rows less the number of coefficients, intercept included
+	Variance float64     `json:"variance"` // of the residuals, their sum of squares over DoF
+	XtXInv   [][]float64 `json:"xtx_inv"`  // inverse of [1 x]ᵀ·[1 x], the intercept first
+}
+
+// NewLinearStats computes the statistics of a least squares fit of x with the given residuals, a
+// column of ŷ - y. It fails if there are no more rows than coefficients, or if x has dependent columns.
+func NewLinearStats(x, residuals [][]float64) (*LinearStats, error) {
+	if len(x) != len(residuals) {
+		return nil, fmt.Errorf("ml: stats of %d rows with %d residuals", len(x), len(residuals))
+	}
+	design := withOnes(x)
+	dof := len(x) - len(design[0])
+	if dof < 1 {
+		return nil, fmt.Errorf("ml: %d rows are too few to fit %d coefficients", len(x), len(design[0]))
+	}
+	xtxInv, err := Inverse(Dot(T(design), design))
+	if err != nil {
+		return nil, err
+	}
+	var sse float64
+	for _, r := range residuals {
+		sse += r[0] * r[0]
+	}
+	return &LinearStats{Rows: len(x), DoF: dof, Variance: sse / float64(dof), XtXInv: xtxInv}, nil
+}
+
+// PredictionInterval returns the half-width of the interval around the prediction of every row of
+// x that holds a new observation with the given probability, such as 0.95.
+func (s *LinearStats) PredictionInterval(x [][]float64, level float64) []float64 {
+	t := StudentTQuantile((1+level)/2, float64(s.DoF))
+	widths := make([]float64, len(x))
+	for i, row := range withOnes(x) {
+		leverage := Dot(Dot([][]float64{row}, s.XtXInv), T([][]float64{row}))[0][0]
+		widths[i] = t * math.Sqrt(s.Variance*(1+leverage))
+	}
+	return widths
+}
+
+// withOnes returns the matrix with a column of ones in front, for the intercept.
+func withOnes(x [][]float64) [][]float64 {
+	result := make([][]float64, len(x))
+	for i, row := range x {
+		result[i] = append([]float64{1}, row...)
+	}
+	return result
+}
+
+// Coefficient is the estimate of a coefficient of a linear fit and how sure it is.
+type Coefficient struct {
+	Name   string
+	Value  float64
+	StdErr float64
+	T      float64 // Value over StdErr
+	P      float64 // two-sided p-value of the coefficient being 0
+	Lower  float64 // of the confidence interval
+	Upper  float64
+}
+
+// RegressionSummary is the inference on a linear fit y ≈ x·w + b of a single target, assuming
+// independent, normal errors of equal variance, and the diagnostics to check those assumptions.
+type RegressionSummary struct {
+	Coefficients []Coefficient // the intercept first
+	Level        float64       // of the confidence intervals
+
+	RSquared    float64
+	AdjRSquared float64
+	F           float64 // of all the coefficients but the intercept being 0
+	FP          float64 // p-value of F
+	DFModel     int
+	DFResid     int
+
+	DurbinWatson float64 // near 2 for uncorrelated residuals, towards 0 or 4 for correlated ones
+	Skewness     float64 // of the residuals, 0 for normal ones
+	Kurtosis     float64 // of the residuals, 3 for normal ones
+	JarqueBera   float64 // of the residuals being normal, from their skewness and kurtosis
+	JarqueBeraP  float64 // p-value of JarqueBera
+}
+
+// SummarizeLinear computes the summary of the fit with weights w and intercept b of the
+// targets y by the features x, with confidence intervals at the given level such as 0.95.
+// The features are named by names, if given. The statistics hold for the least squares solution
+// only: for other coefficients, such as those of gradient descent stopped short, they are wrong.
+func SummarizeLinear(x, y, w [][]float64, b float64, names []string, level float64) (*RegressionSummary, error) {
+	if len(x) != len(y) {
+		return nil, fmt.Errorf("ml: summary of %d rows against %d targets", len(x), len(y))
+	}
+	residuals := Sub(AddRow(Dot(x, w), []float64{b}), y)
+	stats, err := NewLinearStats(x, residuals)
+	if err != nil {
+		return nil, err
+	}
+	n, p := float64(len(x)), len(w)
+	dof := float64(stats.DoF)
+
+	// every coefficient against 0
+	s := &RegressionSummary{Level: level, DFModel: p, DFResid: stats.DoF}
+	t := StudentTQuantile((1+level)/2, dof)
+	values := append([]float64{b}, T(w)[0]...)
+	for i, value := range values {
+		c := Coefficient{Name: "intercept", Value: value, StdErr: math.Sqrt(stats.Variance * stats.XtXInv[i][i])}
+		if i > 0 {
+			c.Name = fmt.Sprintf("x%d", i)
+			if i <= len(names) {
+				c.Name = names[i-1]
+			}
+		}
+		c.T = c.Value / c.StdErr
+		c.P = 2 * StudentTCDF(-math.Abs(c.T), dof)
+		c.Lower, c.Upper = c.Value-t*c.StdErr, c.Value+t*c.StdErr
+		s.Coefficients = append(s.Coefficients, c)
+	}
+
+	// how much of the variance of y the fit explains
+	yMean := Mean(T(y)[0])
+	var sst, sse float64
+	for i, r := range residuals {
+		sst += (y[i][0] - yMean) * (y[i][0] - yMean)
+		sse += r[0] * r[0]
+	}
+	s.RSquared = 1 - sse/sst
+	s.AdjRSquared = 1 - (1-s.RSquared)*(n-1)/dof
+	s.F = (sst - sse) / float64(p) / (sse / dof)
+	s.FP = FSurvival(s.F, float64(p), dof)
+
+	// residual diagnostics
+	var diffs, m2, m3, m4 float64
+	for i, r := range residuals {
+		if i > 0 {
+			diffs += (r[0] - residuals[i-1][0]) * (r[0] - residuals[i-1][0])
+		}
+	}
+	rMean := Mean(T(residuals)[0])
+	for _, r := range residuals {
+		d := r[0] - rMean
+		m2 += d * d / n
+		m3 += d * d * d / n
+		m4 += d * d * d * d / n
+	}
+	s.DurbinWatson = diffs / sse
+	s.Skewness = m3 / math.Pow(m2, 1.5)
+	s.Kurtosis = m4 / (m2 * m2)
+	s.JarqueBera = n / 6 * (s.Skewness*s.Skewness + (s.Kurtosis-3)*(s.Kurtosis-3)/4)
+	s.JarqueBeraP = math.Exp(-s.JarqueBera / 2) // the chi-squared distribution with 2 degrees of freedom
+	return s, nil
+}
@@ -0,0 +1,125 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestFitLinear(t *testing.T) {
+	// y = 1 + 2a - b exactly
+	x := [][]float64{{0, 0}, {1, 0}, {0, 1}, {2, 3}, {5, 1}}
+	y := [][]float64{{1}, {3}, {0}, {2}, {10}}
+	w, b, err := ml.FitLinear(x, y, 0)
+	if err != nil || !ml.MatrixAlmostEquals([][]float64{{2}, {-1}}, w, 1e-12) || !ml.MatrixAlmostEquals([][]float64{{1}}, [][]float64{b}, 1e-12) {
+		t.Errorf("FitLinear(%v, %v): expected w [[2] [-1]] b [1], actual w %v b %v (err %v)", x, y, w, b, err)
+	}
+
+	// ridge shrinks the slope but not the intercept: sum of x² 2, of xy 4, so w = 4/(2+2)
+	x = [][]float64{{-1}, {0}, {1}}
+	y = [][]float64{{3}, {5}, {7}}
+	w, b, err = ml.FitLinear(x, y, 2)
+	if err != nil || !ml.MatrixAlmostEquals([][]float64{{1}}, w, 1e-12) || !ml.MatrixAlmostEquals([][]float64{{5}}, [][]float64{b}, 1e-12) {
+		t.Errorf("FitLinear(%v, %v, 2): expected w [[1]] b [5], actual w %v b %v (err %v)", x, y, w, b, err)
+	}
+
+	if _, _, err := ml.FitLinear([][]float64{{1}, {1}}, [][]float64{{1}, {2}}, 0); err != ml.ErrSingular {
+		t.Errorf("FitLinear: expected ErrSingular for a constant column, actual %v", err)
+	}
+	if _, _, err := ml.FitLinear(nil, nil, 0); err == nil {
+		t.Errorf("FitLinear: expected err != nil for no rows")
+	}
+}
+
+func TestLinearStats(t *testing.T) {
+	x := [][]float64{{1}, {2}, {3}, {4}, {5}}
+	y := [][]float64{{1.1}, {1.9}, {3.2}, {3.8}, {5.0}}
+	w, b, err := ml.FitLinear(x, y, 0)
+	if err != nil {
+		t.Fatal("FitLinear unexpected error:", err)
+	}
+	residuals := ml.Sub(ml.AddRow(ml.Dot(x, w), b), y)
+	stats, err := ml.NewLinearStats(x, residuals)
+	if err != nil {
+		t.Fatal("NewLinearStats unexpected error:", err)
+	}
+
+	var sse float64
+	for _, r := range residuals {
+		sse += r[0] * r[0]
+	}
+	if stats.Rows != 5 || stats.DoF != 3 || math.Abs(stats.Variance-sse/3) > 1e-12 {
+		t.Errorf("NewLinearStats: expected 5 rows, 3 dof and variance %v, actual %+v", sse/3, stats)
+	}
+
+	// the textbook interval of a simple regression: t·s·√(1 + 1/n + (x0 - x̄)²/Sxx), x̄ = 3, Sxx = 10
+	x0 := [][]float64{{3}, {6}, {-1}}
+	widths := stats.PredictionInterval(x0, 0.95)
+	for i, row := range x0 {
+		expected := 3.182446305284263 * math.Sqrt(sse/3*(1+1.0/5+(row[0]-3)*(row[0]-3)/10))
+		if math.Abs(widths[i]-expected) > 1e-9 {
+			t.Errorf("PredictionInterval(%v): expected %v, actual %v", row, expected, widths[i])
+		}
+	}
+
+	if _, err := ml.NewLinearStats(x[:2], residuals[:2]); err == nil {
+		t.Errorf("NewLinearStats: expected err != nil for as many rows as coefficients")
+	}
+	if _, err := ml.NewLinearStats([][]float64{{1}, {1}, {1}}, residuals[:3]); err != ml.ErrSingular {
+		t.Errorf("NewLinearStats: expected ErrSingular for a constant column, actual %v", err)
+	}
+}
+
+func TestSummarizeLinear(t *testing.T) {
+	x := [][]float64{{1}, {2}, {3}, {4}, {5}}
+	y := [][]float64{{1.1}, {1.9}, {3.2}, {3.8}, {5.0}}
+	w, b, err := ml.FitLinear(x, y, 0)
+	if err != nil {
+		t.Fatal("FitLinear unexpected error:", err)
+	}
+	s, err := ml.SummarizeLinear(x, y, w, b[0], []string{"hours"}, 0.95)
+	if err != nil {
+		t.Fatal("SummarizeLinear unexpected error:", err)
+	}
+
+	// by the formulas of a simple regression: se(slope) = s/√Sxx, se(intercept) = s·√(1/n + x̄²/Sxx)
+	var tests = []struct {
+		name     string
+		actual   float64
+		expected float64
+	}{
+		{"intercept", s.Coefficients[0].Value, 0.09},
+		{"intercept std err", s.Coefficients[0].StdErr, 0.18266545011760354},
+		{"slope", s.Coefficients[1].Value, 0.97},
+		{"slope std err", s.Coefficients[1].StdErr, 0.05507570547286105},
+		{"slope t", s.Coefficients[1].T, 0.97 / 0.05507570547286105},
+		{"slope p", s.Coefficients[1].P, 2 * ml.StudentTCDF(-0.97/0.05507570547286105, 3)},
+		{"slope lower", s.Coefficients[1].Lower, 0.97 - 3.182446305284263*0.05507570547286105},
+		{"slope upper", s.Coefficients[1].Upper, 0.97 + 3.182446305284263*0.05507570547286105},
+		{"r²", s.RSquared, 0.990421052631579},
+		{"adjusted r²", s.AdjRSquared, 1 - (1-0.990421052631579)*4/3},
+		{"F", s.F, 310.1868131868129},
+		{"F p", s.FP, s.Coefficients[1].P}, // the same test with a single feature
+		{"Durbin-Watson", s.DurbinWatson, 3.6},
+		{"skewness", s.Skewness, -0.0953034835707112},
+		{"kurtosis", s.Kurtosis, 1.6521796884434232},
+		{"Jarque-Bera", s.JarqueBera, 0.3860313767015248},
+		{"Jarque-Bera p", s.JarqueBeraP, math.Exp(-0.3860313767015248 / 2)},
+	}
+	for _, test := range tests {
+		if math.Abs(test.actual-test.expected) > 1e-9 {
+			t.Errorf("SummarizeLinear: expected %s %v, actual %v", test.name, test.expected, test.actual)
+		}
+	}
+	if s.Coefficients[1].Name != "hours" || s.DFModel != 1 || s.DFResid != 3 {
+		t.Errorf("SummarizeLinear: expected hours with 1 and 3 degrees of freedom, actual %+v", s)
+	}
+	if p := s.Coefficients[1].P; p > 0.001 {
+		t.Errorf("SummarizeLinear: expected a significant slope, actual p %v", p)
+	}
+
+	if _, err := ml.SummarizeLinear(x[:2], y[:2], w, b[0], nil, 0.95); err == nil {
+		t.Errorf("SummarizeLinear: expected err != nil for too few rows")
+	}
+}
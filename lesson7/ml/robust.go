@@ -0,0 +1,228 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// HuberDelta is the usual threshold of the Huber loss, in robust standard deviations of the
+// residuals: it keeps 95% of the efficiency of least squares on normal errors.
+const HuberDelta = 1.345
+
+// The iteratively reweighted least squares of FitHuber and FitLAD stop when no coefficient moves
+// by more than irlsTolerance relative to its size, or after irlsIterations refits.
+const (
+	irlsIterations = 200
+	irlsTolerance  = 1e-10
+)
+
+// FitHuber fits y ≈ x·w + b minimizing the Huber loss of the residuals, which is squared up to
+// delta robust standard deviations of the residuals (their median absolute deviation over 0.6745)
+// and linear beyond, so that outliers pull on the fit with a bounded force. y is a single column.
+func FitHuber(x, y [][]float64, delta float64) (w [][]float64, b float64, err error) {
+	if delta <= 0 {
+		return nil, 0, fmt.Errorf("ml: Huber delta %v is not positive", delta)
+	}
+	return irls(x, y, func(r []float64) []float64 {
+		threshold := delta * mad(r) / 0.6745
+		weights := make([]float64, len(r))
+		for i, ri := range r {
+			weights[i] = 1
+			if math.Abs(ri) > threshold {
+				weights[i] = threshold / math.Abs(ri)
+			}
+		}
+		return weights
+	})
+}
+
+// FitLAD fits y ≈ x·w + b minimizing the sum of the absolute residuals, the least absolute
+// deviations fit going through the median of y rather than its mean. y is a single column.
+func FitLAD(x, y [][]float64) (w [][]float64, b float64, err error) {
+	return irls(x, y, func(r []float64) []float64 {
+		weights := make([]float64, len(r))
+		for i, ri := range r {
+			weights[i] = 1 / math.Max(math.Abs(ri), 1e-8)
+		}
+		return weights
+	})
+}
+
+// irls starts from the least squares fit, then refits with the weights of the residuals of the
+// last fit until the coefficients settle.
+func irls(x, y [][]float64, weigh func(residuals []float64) []float64) (w [][]float64, b float64, err error) {
+	if err := checkColumn(x, y); err != nil {
+		return nil, 0, err
+	}
+	w, intercepts, err := FitLinear(x, y, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	b = intercepts[0]
+	for i := 0; i < irlsIterations; i++ {
+		r := residuals(x, y, w, b)
+		if mad(r) == 0 {
+			break // most of the points are on the line already
+		}
+		newW, newB, err := fitWeighted(x, y, weigh(r))
+		if err != nil {
+			return nil, 0, err
+		}
+		settled := math.Abs(newB-b) <= irlsTolerance*(1+math.Abs(b))
+		for j := range w {
+			settled = settled && math.Abs(newW[j][0]-w[j][0]) <= irlsTolerance*(1+math.Abs(w[j][0]))
+		}
+		w, b = newW, newB
+		if settled {
+			break
+		}
+	}
+	return w, b, nil
+}
+
+// fitWeighted fits y ≈ x·w + b by least squares with every squared residual counting as much as
+// its weight.
+func fitWeighted(x, y [][]float64, weights []float64) (w [][]float64, b float64, err error) {
+	var total float64
+	xMean, yMean := make([]float64, len(x[0])), 0.0
+	for i, row := range x {
+		total += weights[i]
+		for j, v := range row {
+			xMean[j] += weights[i] * v
+		}
+		yMean += weights[i] * y[i][0]
+	}
+	for j := range xMean {
+		xMean[j] /= total
+	}
+	yMean /= total
+
+	xs, ys := make([][]float64, len(x)), make([][]float64, len(x))
+	for i, row := range x {
+		root := math.Sqrt(weights[i])
+		xs[i] = make([]float64, len(row))
+		for j, v := range row {
+			xs[i][j] = root * (v - xMean[j])
+		}
+		ys[i] = []float64{root * (y[i][0] - yMean)}
+	}
+	if w, err = LeastSquares(xs, ys, 0); err != nil {
+		return nil, 0, err
+	}
+	return w, yMean - Dot([][]float64{xMean}, w)[0][0], nil
+}
+
+// RANSAC fits lines to random samples of the rows, keeps the one with the most inliers (rows
+// within Threshold of it) and refits them by least squares, ignoring the outliers altogether.
+type RANSAC struct {
+	MinSamples int     // rows of every sample, the number of features plus one if 0
+	Threshold  float64 // largest absolute residual of an inlier, the median absolute deviation of y if 0
+	Iterations int     // samples to try, 100 if 0
+	Seed       uint64
+}
+
+// Fit returns the coefficients of the fit to the inliers and which rows they are. y is a single
+// column. It fails if no sample can be fitted.
+func (r *RANSAC) Fit(x, y [][]float64) (w [][]float64, b float64, inliers []bool, err error) {
+	if err := checkColumn(x, y); err != nil {
+		return nil, 0, nil, err
+	}
+	minSamples, threshold, iterations := r.MinSamples, r.Threshold, r.Iterations
+	if minSamples == 0 {
+		minSamples = len(x[0]) + 1
+	}
+	if minSamples <= len(x[0]) || minSamples > len(x) {
+		return nil, 0, nil, fmt.Errorf("ml: RANSAC samples of %d rows of %d for %d features", minSamples, len(x), len(x[0]))
+	}
+	if threshold == 0 {
+		threshold = mad(T(y)[0])
+	}
+	if iterations == 0 {
+		iterations = 100
+	}
+
+	rng := rand.New(rand.NewPCG(r.Seed, r.Seed))
+	bestCount, bestSSE := 0, math.Inf(1)
+	for i := 0; i < iterations; i++ {
+		perm := rng.Perm(len(x))[:minSamples]
+		xs, ys := make([][]float64, minSamples), make([][]float64, minSamples)
+		for k, row := range perm {
+			xs[k], ys[k] = x[row], y[row]
+		}
+		sw, sb, err := FitLinear(xs, ys, 0)
+		if err != nil {
+			continue // the sample doesn't pin down a line
+		}
+		var count int
+		var sse float64
+		in := make([]bool, len(x))
+		for k, res := range residuals(x, y, sw, sb[0]) {
+			if math.Abs(res) <= threshold {
+				in[k] = true
+				count++
+				sse += res * res
+			}
+		}
+		if count > bestCount || count == bestCount && sse < bestSSE {
+			bestCount, bestSSE, inliers = count, sse, in
+		}
+	}
+	if inliers == nil {
+		return nil, 0, nil, fmt.Errorf("ml: no RANSAC sample could be fitted")
+	}
+
+	var xs, ys [][]float64
+	for k, in := range inliers {
+		if in {
+			xs, ys = append(xs, x[k]), append(ys, y[k])
+		}
+	}
+	w, intercepts, err := FitLinear(xs, ys, 0)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return w, intercepts[0], inliers, nil
+}
+
+// checkColumn checks that x and y have the same rows, y a single column of them.
+func checkColumn(x, y [][]float64) error {
+	if len(x) == 0 || len(x) != len(y) {
+		return fmt.Errorf("ml: linear fit of %d rows against %d targets", len(x), len(y))
+	}
+	if len(y[0]) != 1 {
+		return fmt.Errorf("ml: robust fit of %d target columns, expected 1", len(y[0]))
+	}
+	return nil
+}
+
+// residuals returns y - (x·w + b) of every row, y being a single column.
+func residuals(x, y, w [][]float64, b float64) []float64 {
+	r := make([]float64, len(x))
+	for i, row := range Dot(x, w) {
+		r[i] = y[i][0] - row[0] - b
+	}
+	return r
+}
+
+// mad returns the median absolute deviation of the values from their median.
+func mad(xs []float64) float64 {
+	m := median(xs)
+	deviations := make([]float64, len(xs))
+	for i, x := range xs {
+		deviations[i] = math.Abs(x - m)
+	}
+	return median(deviations)
+}
+
+// median returns the middle value, or the mean of the two middle values.
+func median(xs []float64) float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,92 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+// lineWithOutliers returns points on y = 1 + 2x, with a little noise, and two far off it.
+func lineWithOutliers() (x, y [][]float64) {
+	noise := []float64{0.1, -0.1, 0.05, 0, -0.05, 0.1, -0.1, 0, 0.05, -0.05}
+	for i, e := range noise {
+		x = append(x, []float64{float64(i)})
+		y = append(y, []float64{1 + 2*float64(i) + e})
+	}
+	y[3][0], y[8][0] = 40, -30
+	return x, y
+}
+
+func TestFitHuber(t *testing.T) {
+	x, y := lineWithOutliers()
+	w, b, err := ml.FitHuber(x, y, ml.HuberDelta)
+	if err != nil {
+		t.Fatal("FitHuber unexpected error:", err)
+	}
+	if math.Abs(w[0][0]-2) > 0.05 || math.Abs(b-1) > 0.2 {
+		t.Errorf("FitHuber(%v, %v): expected about w=2 b=1, actual w=%v b=%v", x, y, w, b)
+	}
+
+	// least squares is dragged off
+	lsW, _, _ := ml.FitLinear(x, y, 0)
+	if math.Abs(lsW[0][0]-2) < 0.5 {
+		t.Errorf("FitLinear(%v, %v): expected a slope far from 2, actual %v", x, y, lsW)
+	}
+
+	if _, _, err := ml.FitHuber(x, y, 0); err == nil {
+		t.Errorf("FitHuber(delta 0): expected err != nil")
+	}
+	if _, _, err := ml.FitHuber(x, ml.FilledMatrix(len(x), 2, 1), ml.HuberDelta); err == nil {
+		t.Errorf("FitHuber of 2 target columns: expected err != nil")
+	}
+}
+
+func TestFitLAD(t *testing.T) {
+	x, y := lineWithOutliers()
+	w, b, err := ml.FitLAD(x, y)
+	if err != nil {
+		t.Fatal("FitLAD unexpected error:", err)
+	}
+	if math.Abs(w[0][0]-2) > 0.05 || math.Abs(b-1) > 0.2 {
+		t.Errorf("FitLAD(%v, %v): expected about w=2 b=1, actual w=%v b=%v", x, y, w, b)
+	}
+
+	// with a binary feature the fit goes through the medians of both groups
+	groups := [][]float64{{0}, {0}, {0}, {1}, {1}, {1}}
+	values := [][]float64{{1}, {2}, {30}, {5}, {6}, {-40}}
+	w, b, err = ml.FitLAD(groups, values)
+	if err != nil || math.Abs(b-2) > 1e-3 || math.Abs(w[0][0]-3) > 1e-3 {
+		t.Errorf("FitLAD(%v, %v): expected w=3 b=2, actual w=%v b=%v (err %v)", groups, values, w, b, err)
+	}
+}
+
+func TestRANSAC(t *testing.T) {
+	x, y := lineWithOutliers()
+	ransac := &ml.RANSAC{Threshold: 0.5, Seed: 1}
+	w, b, inliers, err := ransac.Fit(x, y)
+	if err != nil {
+		t.Fatal("RANSAC unexpected error:", err)
+	}
+	if math.Abs(w[0][0]-2) > 0.05 || math.Abs(b-1) > 0.2 {
+		t.Errorf("RANSAC(%v, %v): expected about w=2 b=1, actual w=%v b=%v", x, y, w, b)
+	}
+	for i, in := range inliers {
+		if in == (i == 3 || i == 8) {
+			t.Errorf("RANSAC(%v, %v): expected all inliers but rows 3 and 8, actual %v", x, y, inliers)
+			break
+		}
+	}
+
+	// the threshold defaults to the median absolute deviation of y
+	if _, _, inliers, err := (&ml.RANSAC{Seed: 2}).Fit(x, y); err != nil || inliers[3] || inliers[8] {
+		t.Errorf("RANSAC(%v, %v) with the default threshold: expected rows 3 and 8 out, actual %v (err %v)", x, y, inliers, err)
+	}
+
+	if _, _, _, err := (&ml.RANSAC{MinSamples: 1}).Fit(x, y); err == nil {
+		t.Errorf("RANSAC(MinSamples 1): expected err != nil for a sample too small to fit a line")
+	}
+	if _, _, _, err := (&ml.RANSAC{MinSamples: 11}).Fit(x, y); err == nil {
+		t.Errorf("RANSAC(MinSamples 11): expected err != nil for a sample larger than the data")
+	}
+}
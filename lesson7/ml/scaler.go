@@ -0,0 +1,64 @@
+package ml
+
+// Scaler standardizes selected columns of a matrix using the mean and standard deviation
+// seen when it was fitted, so that new data can be transformed the same way.
+type Scaler struct {
+	Columns []int     `json:"columns"`
+	Mean    []float64 `json:"mean"`
+	Std     []float64 `json:"std"`
+}
+
+// FitScaler computes the mean and standard deviation of the given columns (all of them if none are given).
+// A constant column gets a standard deviation of 1, so that it is only centered rather than divided by 0.
+func FitScaler(m [][]float64, columns ...int) *Scaler {
+	cols := T(m)
+	if len(columns) == 0 {
+		for i := range cols {
+			columns = append(columns, i)
+		}
+	}
+
+	s := &Scaler{
+		Columns: columns,
+		Mean:    make([]float64, len(columns)),
+		Std:     make([]float64, len(columns)),
+	}
+	for i, c := range columns {
+		s.Mean[i] = Mean(cols[c])
+		if s.Std[i] = Std(cols[c]); s.Std[i] == 0 {
+			s.Std[i] = 1
+		}
+	}
+	return s
+}
+
+// Transform returns a new matrix with the scaler's columns standardized, the rest are copied as they are.
+func (s *Scaler) Transform(m [][]float64) [][]float64 {
+	result := copyMatrix(m)
+	for _, row := range result {
+		for i, c := range s.Columns {
+			row[c] = (row[c] - s.Mean[i]) / s.Std[i]
+		}
+	}
+	return result
+}
+
+// InverseTransform undoes Transform and returns a new matrix in the original units.
+func (s *Scaler) InverseTransform(m [][]float64) [][]float64 {
+	result := copyMatrix(m)
+	for _, row := range result {
+		for i, c := range s.Columns {
+			row[c] = row[c]*s.Std[i] + s.Mean[i]
+		}
+	}
+	return result
+}
+
+// copyMatrix returns a deep copy of the matrix.
+func copyMatrix(m [][]float64) [][]float64 {
+	result := make([][]float64, len(m))
+	for i, row := range m {
+		result[i] = append([]float64(nil), row...)
+	}
+	return result
+}
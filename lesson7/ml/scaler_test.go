@@ -0,0 +1,56 @@
+package ml_test
+
+import (
+	"testing"
+
+	"."
+)
+
+func TestScaler(t *testing.T) {
+	input := [][]float64{{1, 10, 5}, {2, 20, 5}, {3, 30, 5}}
+	scaler := ml.FitScaler(input, 0, 1)
+
+	// the scaled columns must match Standardize
+	actual := ml.T(scaler.Transform(input))
+	cols := ml.T(input)
+	expected := [][]float64{ml.Standardize(cols[0]), ml.Standardize(cols[1]), cols[2]}
+	if !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Transform(%v): expected %v, actual %v", input, expected, actual)
+	}
+
+	// the input must be left untouched
+	if input[0][0] != 1 {
+		t.Errorf("Transform modified its input: %v", input)
+	}
+
+	// the inverse gets back the original values
+	inverse := scaler.InverseTransform(scaler.Transform(input))
+	if !ml.MatrixEquals(input, inverse) {
+		t.Errorf("InverseTransform: expected %v, actual %v", input, inverse)
+	}
+}
+
+func TestFitScalerAllColumns(t *testing.T) {
+	input := [][]float64{{1, 4}, {3, 8}}
+	scaler := ml.FitScaler(input)
+	expected := []float64{2, 6}
+	if !ml.ArrayEquals(expected, scaler.Mean) {
+		t.Errorf("FitScaler(%v).Mean: expected %v, actual %v", input, expected, scaler.Mean)
+	}
+	expected = []float64{1, 2}
+	if !ml.ArrayEquals(expected, scaler.Std) {
+		t.Errorf("FitScaler(%v).Std: expected %v, actual %v", input, expected, scaler.Std)
+	}
+}
+
+func TestFitScalerConstantColumn(t *testing.T) {
+	input := [][]float64{{1, 5}, {3, 5}}
+	scaler := ml.FitScaler(input)
+	if !ml.ArrayEquals([]float64{1, 1}, scaler.Std) {
+		t.Errorf("FitScaler(%v).Std: expected [1 1], actual %v", input, scaler.Std)
+	}
+	expected := [][]float64{{-1, 0}, {1, 0}}
+	if actual := scaler.Transform(input); !ml.MatrixEquals(expected, actual) {
+		t.Errorf("Transform(%v): expected %v, actual %v", input, expected, actual)
+	}
+}
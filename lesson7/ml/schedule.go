@@ -0,0 +1,182 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Learning rate schedules understood by Schedule.
+const (
+	ScheduleConstant    = "constant"
+	ScheduleStep        = "step"
+	ScheduleExponential = "exponential"
+	ScheduleCosine      = "cosine"
+	ScheduleOneCycle    = "one_cycle"
+)
+
+// Schedule gives the learning rate of every epoch. Every type can start with Warmup epochs of
+// linear warmup up to Rate, so a constant schedule with a warmup is plain linear warmup.
+type Schedule struct {
+	Type    string  `json:"type"`
+	Rate    float64 `json:"rate"`               // the base, or peak, learning rate
+	Warmup  int     `json:"warmup,omitempty"`   // epochs rising linearly from Rate/Warmup to Rate
+	Step    int     `json:"step,omitempty"`     // epochs between decays (step), or of the first cycle (cosine)
+	Gamma   float64 `json:"gamma,omitempty"`    // decay factor per Step epochs (step) or per epoch (exponential)
+	Mult    float64 `json:"mult,omitempty"`     // growth of every cosine cycle over the one before, 1 if 0
+	MinRate float64 `json:"min_rate,omitempty"` // the rate at the end of a cosine cycle or of the one cycle
+	Epochs  int     `json:"epochs,omitempty"`   // length of the one cycle, warmup included
+}
+
+// LearnRate returns the learning rate of the given epoch, counting from 0.
+func (s *Schedule) LearnRate(epoch int) float64 {
+	if epoch < s.Warmup {
+		return s.Rate * float64(epoch+1) / float64(s.Warmup)
+	}
+	epoch -= s.Warmup
+
+	switch s.Type {
+	case ScheduleStep:
+		return s.Rate * math.Pow(s.Gamma, float64(epoch/s.Step))
+	case ScheduleExponential:
+		return s.Rate * math.Pow(s.Gamma, float64(epoch))
+	case ScheduleCosine:
+		// find the restart the epoch falls in
+		length, mult := float64(s.Step), s.Mult
+		if mult == 0 {
+			mult = 1
+		}
+		t := float64(epoch)
+		for t >= length {
+			t -= length
+			length *= mult
+		}
+		return s.anneal(t / length)
+	case ScheduleOneCycle:
+		if epoch >= s.Epochs-s.Warmup {
+			return s.MinRate
+		}
+		return s.anneal(float64(epoch) / float64(s.Epochs-s.Warmup))
+	default:
+		return s.Rate
+	}
+}
+
+// anneal goes from Rate down to MinRate along half a cosine as progress goes from 0 to 1.
+func (s *Schedule) anneal(progress float64) float64 {
+	return s.MinRate + (s.Rate-s.MinRate)*(1+math.Cos(math.Pi*progress))/2
+}
+
+// Validate checks that the schedule has the settings its type needs.
+func (s *Schedule) Validate() error {
+	if s.Rate <= 0 {
+		return fmt.Errorf("ml: schedule rate %v is not positive", s.Rate)
+	}
+	if s.Warmup < 0 {
+		return fmt.Errorf("ml: schedule warmup %d is negative", s.Warmup)
+	}
+
+	switch s.Type {
+	case ScheduleConstant:
+	case ScheduleStep, ScheduleExponential:
+		if s.Type == ScheduleStep && s.Step <= 0 {
+			return fmt.Errorf("ml: %s schedule needs a positive step, got %d", s.Type, s.Step)
+		}
+		if s.Gamma <= 0 || s.Gamma > 1 {
+			return fmt.Errorf("ml: %s schedule gamma %v is not in (0, 1]", s.Type, s.Gamma)
+		}
+	case ScheduleCosine, ScheduleOneCycle:
+		if s.Type == ScheduleCosine && s.Step <= 0 {
+			return fmt.Errorf("ml: %s schedule needs a positive step, got %d", s.Type, s.Step)
+		}
+		if s.Type == ScheduleCosine && s.Mult != 0 && s.Mult < 1 {
+			return fmt.Errorf("ml: %s schedule mult %v is less than 1", s.Type, s.Mult)
+		}
+		if s.Type == ScheduleOneCycle && s.Epochs <= s.Warmup {
+			return fmt.Errorf("ml: %s schedule needs more epochs than its warmup, got %d", s.Type, s.Epochs)
+		}
+		if s.MinRate < 0 || s.MinRate > s.Rate {
+			return fmt.Errorf("ml: %s schedule min rate %v is not in [0, %v]", s.Type, s.MinRate, s.Rate)
+		}
+	default:
+		return fmt.Errorf("ml: unknown schedule %q", s.Type)
+	}
+	return nil
+}
+
+// ReduceOnPlateau is a callback that multiplies the learning rate by Factor once the Monitor
+// metric has not improved by more than MinDelta for Patience epochs, never going below MinRate.
+// With a Schedule on the trainer it lowers the schedule's base rate instead. It is a
+// StatefulCallback, so that its patience survives a checkpoint.
+type ReduceOnPlateau struct {
+	Monitor  string  // such as "val_loss"
+	Mode     string  // ModeMin or ModeMax
+	Factor   float64 // between 0 and 1
+	Patience int
+	MinDelta float64
+	MinRate  float64
+
+	best    float64
+	hasBest bool
+	wait    int
+}
+
+// OnEpochEnd compares the metric with the best so far and lowers the rate when patience runs out.
+func (r *ReduceOnPlateau) OnEpochEnd(t *Trainer, metrics Metrics) error {
+	value, ok := metrics[r.Monitor]
+	if !ok {
+		return fmt.Errorf("ml: reduce on plateau: no metric %q", r.Monitor)
+	}
+	better, err := improves(r.Mode, value, r.best, r.MinDelta)
+	if err != nil {
+		return err
+	}
+
+	if !r.hasBest || better {
+		r.best, r.hasBest, r.wait = value, true, 0
+		return nil
+	}
+
+	r.wait++
+	if r.wait >= r.Patience {
+		rate := &t.Opt.LearnRate
+		if t.Schedule != nil {
+			rate = &t.Schedule.Rate
+		}
+		*rate = math.Max(*rate*r.Factor, r.MinRate)
+		r.wait = 0
+	}
+	return nil
+}
+
+// OnTrainEnd does nothing.
+func (r *ReduceOnPlateau) OnTrainEnd(t *Trainer) error {
+	return nil
+}
+
+// plateauState is what ReduceOnPlateau keeps in a checkpoint.
+type plateauState struct {
+	Best    float64 `json:"best"`
+	HasBest bool    `json:"has_best"`
+	Wait    int     `json:"wait"`
+}
+
+// StateName returns the name of the state in a checkpoint.
+func (r *ReduceOnPlateau) StateName() string {
+	return "reduce_on_plateau"
+}
+
+// State returns the best value so far and the epochs waited since.
+func (r *ReduceOnPlateau) State() (json.RawMessage, error) {
+	return json.Marshal(plateauState{r.best, r.hasBest, r.wait})
+}
+
+// SetState restores a state returned by State.
+func (r *ReduceOnPlateau) SetState(state json.RawMessage) error {
+	var s plateauState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return fmt.Errorf("ml: reduce on plateau state: %v", err)
+	}
+	r.best, r.hasBest, r.wait = s.Best, s.HasBest, s.Wait
+	return nil
+}
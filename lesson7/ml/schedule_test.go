@@ -0,0 +1,128 @@
+package ml_test
+
+import (
+	"math"
+	"testing"
+
+	"."
+)
+
+func TestScheduleLearnRate(t *testing.T) {
+	constant := &ml.Schedule{Type: ml.ScheduleConstant, Rate: 0.1, Warmup: 4}
+	step := &ml.Schedule{Type: ml.ScheduleStep, Rate: 1, Step: 10, Gamma: 0.5}
+	exponential := &ml.Schedule{Type: ml.ScheduleExponential, Rate: 1, Gamma: 0.9}
+	cosine := &ml.Schedule{Type: ml.ScheduleCosine, Rate: 1, Step: 10, Mult: 2}
+	oneCycle := &ml.Schedule{Type: ml.ScheduleOneCycle, Rate: 1, Warmup: 2, Epochs: 12, MinRate: 0.1}
+
+	var tests = []struct {
+		schedule *ml.Schedule
+		epoch    int
+		expected float64
+	}{
+		{constant, 0, 0.025},
+		{constant, 3, 0.1},
+		{constant, 10, 0.1},
+		{step, 0, 1},
+		{step, 9, 1},
+		{step, 10, 0.5},
+		{step, 25, 0.25},
+		{exponential, 0, 1},
+		{exponential, 2, 0.81},
+		{cosine, 0, 1},
+		{cosine, 5, 0.5},
+		{cosine, 10, 1}, // restart
+		{cosine, 20, 0.5},
+		{cosine, 30, 1},
+		{oneCycle, 0, 0.5},
+		{oneCycle, 1, 1},
+		{oneCycle, 2, 1},
+		{oneCycle, 7, 0.55},
+		{oneCycle, 12, 0.1},
+		{oneCycle, 20, 0.1},
+	}
+
+	for _, test := range tests {
+		actual := test.schedule.LearnRate(test.epoch)
+		if math.Abs(actual-test.expected) > 1e-12 {
+			t.Errorf("LearnRate(%s, %v): expected %v, actual %v", test.schedule.Type, test.epoch, test.expected, actual)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	var tests = []struct {
+		schedule ml.Schedule
+		valid    bool
+	}{
+		{ml.Schedule{Type: ml.ScheduleConstant, Rate: 0.1}, true},
+		{ml.Schedule{Type: ml.ScheduleConstant, Rate: 0}, false},
+		{ml.Schedule{Type: "linear", Rate: 0.1}, false},
+		{ml.Schedule{Type: ml.ScheduleStep, Rate: 0.1, Step: 10, Gamma: 0.5}, true},
+		{ml.Schedule{Type: ml.ScheduleStep, Rate: 0.1, Gamma: 0.5}, false},
+		{ml.Schedule{Type: ml.ScheduleExponential, Rate: 0.1, Gamma: 2}, false},
+		{ml.Schedule{Type: ml.ScheduleCosine, Rate: 0.1, Step: 10}, true},
+		{ml.Schedule{Type: ml.ScheduleCosine, Rate: 0.1, Step: 10, Mult: 0.5}, false},
+		{ml.Schedule{Type: ml.ScheduleCosine, Rate: 0.1, Step: 10, MinRate: 1}, false},
+		{ml.Schedule{Type: ml.ScheduleOneCycle, Rate: 0.1, Epochs: 100, Warmup: 30}, true},
+		{ml.Schedule{Type: ml.ScheduleOneCycle, Rate: 0.1, Epochs: 30, Warmup: 30}, false},
+	}
+
+	for _, test := range tests {
+		err := test.schedule.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("Validate(%+v): expected valid %v, actual error %v", test.schedule, test.valid, err)
+		}
+	}
+}
+
+func TestTrainerSchedule(t *testing.T) {
+	x, y := xorData()
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	trainer.Schedule = &ml.Schedule{Type: ml.ScheduleStep, Rate: 0.5, Step: 2, Gamma: 0.1}
+
+	history := &ml.History{}
+	if err := trainer.Fit(x, y, nil, nil, 5, history); err != nil {
+		t.Fatal("Fit unexpected error:", err)
+	}
+	expected := []float64{0.5, 0.5, 0.05, 0.05, 0.005}
+	for i, rate := range history.Values("lr") {
+		if math.Abs(rate-expected[i]) > 1e-12 {
+			t.Errorf("Fit: expected lr %v in epoch %d, actual %v", expected[i], i+1, rate)
+		}
+	}
+}
+
+func TestReduceOnPlateau(t *testing.T) {
+	x, y := xorData()
+	values := []float64{5, 4, 4, 4, 4, 3, 3, 3, 3, 3, 3, 3}
+	expected := []float64{0.5, 0.5, 0.5, 0.25, 0.25, 0.25, 0.25, 0.125, 0.125, 0.1, 0.1, 0.1}
+
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(xorNetwork(), opt, 1)
+	plateau := &ml.ReduceOnPlateau{Monitor: "val_loss", Mode: ml.ModeMin, Factor: 0.5, Patience: 2, MinRate: 0.1}
+	rates := &recordRate{}
+	feed := &epochMetrics{values, []ml.Callback{plateau, rates}}
+	if err := trainer.Fit(x, y, nil, nil, len(values), feed); err != nil {
+		t.Fatal("Fit unexpected error:", err)
+	}
+	for i, rate := range rates.rates {
+		if rate != expected[i] {
+			t.Errorf("ReduceOnPlateau(%v): expected rate %v after epoch %d, actual %v", values, expected[i], i+1, rate)
+		}
+	}
+}
+
+// recordRate is a callback that keeps the learning rate after every epoch.
+type recordRate struct {
+	rates []float64
+}
+
+func (r *recordRate) OnEpochEnd(t *ml.Trainer, metrics ml.Metrics) error {
+	r.rates = append(r.rates, t.Opt.LearnRate)
+	return nil
+}
+
+func (r *recordRate) OnTrainEnd(t *ml.Trainer) error {
+	return nil
+}
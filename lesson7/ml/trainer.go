@@ -0,0 +1,207 @@
+package ml
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Trainer fits a network to a dataset with an optimizer, one epoch at a time. It owns the random
+// source used to shuffle the batches, so that training can be checkpointed and resumed exactly.
+type Trainer struct {
+	Net       *Network
+	Opt       *Optimizer
+	Loss      string
+	BatchSize int                   // rows per gradient step, 0 uses the whole training set
+	Epoch     int                   // number of epochs completed so far
+	Metrics   map[string]MetricFunc // measured on the validation data by Fit, as val_<name>
+	Stop      bool                  // set by a callback to end Fit after the current epoch
+	Schedule  *Schedule             // sets the optimizer's learning rate at the start of every epoch
+
+	Convergence *Convergence // stops Fit once the epochs converge, checked after the callbacks
+	Result      FitResult    // why the last Fit stopped, its Steps being the epochs it ran
+
+	src  *rand.PCG
+	rand *rand.Rand
+
+	callbacks []Callback                 // of the current Fit, for Checkpoint
+	states    map[string]json.RawMessage // of the callbacks, from Restore for the next Fit
+}
+
+// NewTrainer returns a trainer using the mean squared error loss on full batches,
+// with its random source seeded from seed.
+func NewTrainer(net *Network, opt *Optimizer, seed uint64) *Trainer {
+	src := rand.NewPCG(seed, seed)
+	return &Trainer{
+		Net:  net,
+		Opt:  opt,
+		Loss: LossMSE,
+		src:  src,
+		rand: rand.New(src),
+	}
+}
+
+// TrainEpoch makes one pass over the training set, updating the network after every batch,
+// and returns the mean loss of the batches measured before their updates, penalties included.
+// The network is in training mode meanwhile, with dropout masks drawn from the trainer's random source.
+func (t *Trainer) TrainEpoch(x, y [][]float64) float64 {
+	if t.Schedule != nil {
+		t.Opt.LearnRate = t.Schedule.LearnRate(t.Epoch)
+	}
+	t.Net.Train(t.rand)
+	defer t.Net.Eval()
+
+	batches := [][]int{nil} // nil means all rows, in order
+	if t.BatchSize > 0 && t.BatchSize < len(x) {
+		batches = nil
+		perm := t.rand.Perm(len(x))
+		for start := 0; start < len(perm); start += t.BatchSize {
+			end := start + t.BatchSize
+			if end > len(perm) {
+				end = len(perm)
+			}
+			batches = append(batches, perm[start:end])
+		}
+	}
+
+	total := float64(0)
+	for _, rows := range batches {
+		xb, yb := x, y
+		if rows != nil {
+			xb, yb = Rows(x, rows), Rows(y, rows)
+		}
+
+		yHat := t.Net.Forward(xb)
+		loss, grad := Loss(t.Loss, yHat, yb)
+		total += loss + t.Net.Penalty()
+		t.Net.Backward(grad)
+		t.Opt.Update(t.Net.Params())
+	}
+
+	t.Epoch++
+	return total / float64(len(batches))
+}
+
+// Fit trains until Epoch reaches epochs (so a restored trainer carries on where it stopped), a
+// callback sets Stop, the Convergence says to or, even without one, the losses or the parameters
+// stop being finite numbers. After every epoch it measures "loss", the mean batch loss of the
+// epoch, and when there is validation data "val_loss" and val_<name> for every metric, "lr" when
+// there is a schedule, and passes them to the callbacks. Result records why it stopped; a
+// callback setting Stop may set its Reason too. A StatefulCallback first gets back its state
+// from a restored checkpoint.
+func (t *Trainer) Fit(x, y, xVal, yVal [][]float64, epochs int, callbacks ...Callback) error {
+	t.callbacks = callbacks
+	for _, callback := range callbacks {
+		s, ok := callback.(StatefulCallback)
+		if !ok {
+			continue
+		}
+		if state, ok := t.states[s.StateName()]; ok {
+			if err := s.SetState(state); err != nil {
+				return err
+			}
+		}
+	}
+	t.states = nil
+
+	t.Stop = false
+	t.Result = FitResult{}
+	if t.Convergence != nil {
+		t.Convergence.Start()
+	}
+	start, startEpoch := time.Now(), t.Epoch
+	var loss float64
+	for t.Epoch < epochs && !t.Stop {
+		var before *Network
+		if t.Convergence.NeedsParamChange() {
+			before = t.Net.Clone()
+		}
+		loss = t.TrainEpoch(x, y)
+		metrics := Metrics{"loss": loss}
+		if t.Schedule != nil {
+			metrics["lr"] = t.Opt.LearnRate
+		}
+		if len(xVal) > 0 {
+			yHat := t.Net.Predict(xVal)
+			metrics["val_loss"], _ = Loss(t.Loss, yHat, yVal)
+			for name, f := range t.Metrics {
+				metrics["val_"+name] = f(yHat, yVal)
+			}
+		}
+		if !finite(loss) || !finite(metrics["val_loss"]) || notFinite(t.Net.Params()) != "" {
+			// before the callbacks, which would checkpoint or log what is left of the network
+			t.Stop, t.Result.Reason = true, StopDiverged
+			break
+		}
+
+		for _, c := range callbacks {
+			if err := c.OnEpochEnd(t, metrics); err != nil {
+				return err
+			}
+		}
+		if t.Stop || t.Convergence == nil {
+			continue
+		}
+		var change float64
+		if before != nil {
+			change = paramChange(before.Params(), t.Net.Params())
+		}
+		if reason := t.Convergence.Check(loss, gradNorm(t.Net.Params()), change); reason != "" {
+			t.Stop, t.Result.Reason = true, reason
+		}
+	}
+
+	switch {
+	case t.Result.Reason != "":
+	case t.Stop:
+		t.Result.Reason = StopCallback
+	default:
+		t.Result.Reason = StopMaxSteps
+	}
+	t.Result.Steps, t.Result.Loss, t.Result.Elapsed = t.Epoch-startEpoch, loss, time.Since(start)
+
+	for _, c := range callbacks {
+		if err := c.OnTrainEnd(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the loss of the network on the given data without changing it, leaving out
+// the penalties.
+func (t *Trainer) Evaluate(x, y [][]float64) float64 {
+	loss, _ := Loss(t.Loss, t.Net.Predict(x), y)
+	return loss
+}
+
+// gradNorm returns the norm of the gradients of all the parameters together, as left by the last batch.
+func gradNorm(params []Param) float64 {
+	var sum float64
+	for _, p := range params {
+		if p.Grad != nil {
+			sum += math.Pow(Norm(p.Grad), 2)
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// paramChange returns the RelativeChange of all the parameters together.
+func paramChange(old, new []Param) float64 {
+	var before, after [][]float64
+	for i, p := range old {
+		before, after = append(before, p.Value...), append(after, new[i].Value...)
+	}
+	return RelativeChange(before, after)
+}
+
+// RandState returns the state of the trainer's random source.
+func (t *Trainer) RandState() ([]byte, error) {
+	return t.src.MarshalBinary()
+}
+
+// SetRandState restores a state returned by RandState.
+func (t *Trainer) SetRandState(state []byte) error {
+	return t.src.UnmarshalBinary(state)
+}
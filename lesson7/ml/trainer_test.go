@@ -0,0 +1,85 @@
+package ml_test
+
+import (
+	"testing"
+
+	"."
+)
+
+// xorData returns the xor truth table.
+func xorData() ([][]float64, [][]float64) {
+	x := [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}, {0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	y := [][]float64{{0}, {1}, {1}, {0}, {0}, {1}, {1}, {0}}
+	return x, y
+}
+
+// xorNetwork returns a small network with fixed, asymmetric initial weights.
+func xorNetwork() *ml.Network {
+	return &ml.Network{Layers: []ml.Layer{
+		{Type: ml.LayerDense, Weights: [][]float64{{0.5, -0.4, 0.3}, {-0.2, 0.6, 0.1}}, Bias: []float64{0, 0, 0}, Activation: ml.ActivationSigmoid},
+		{Type: ml.LayerDense, Weights: [][]float64{{0.4}, {-0.5}, {0.2}}, Bias: []float64{0}, Activation: ml.ActivationSigmoid},
+	}}
+}
+
+func TestTrainerFullBatch(t *testing.T) {
+	x, y := xorData()
+	net := xorNetwork()
+	opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+	trainer := ml.NewTrainer(net, opt, 1)
+
+	initial := trainer.Evaluate(x, y)
+	for trainer.Epoch < 3000 {
+		trainer.TrainEpoch(x, y)
+	}
+	final := trainer.Evaluate(x, y)
+	if final >= initial/10 {
+		t.Errorf("TrainEpoch: expected the loss to drop from %v, actual %v", initial, final)
+	}
+}
+
+func TestTrainerMiniBatchSeed(t *testing.T) {
+	x, y := xorData()
+
+	// same seed, same weights
+	train := func(seed uint64) [][]float64 {
+		net := xorNetwork()
+		opt, _ := ml.NewOptimizer(ml.OptimizerMomentum, 0.1)
+		trainer := ml.NewTrainer(net, opt, seed)
+		trainer.BatchSize = 3
+		for trainer.Epoch < 20 {
+			trainer.TrainEpoch(x, y)
+		}
+		return net.Layers[0].Weights
+	}
+
+	w1, w2, w3 := train(7), train(7), train(8)
+	if !ml.MatrixEquals(w1, w2) {
+		t.Errorf("TrainEpoch: expected equal weights for the same seed, actual %v and %v", w1, w2)
+	}
+	if ml.MatrixEquals(w1, w3) {
+		t.Errorf("TrainEpoch: expected different weights for different seeds, actual %v", w1)
+	}
+}
+
+func TestTrainerDropout(t *testing.T) {
+	x, y := xorData()
+	newTrainer := func() *ml.Trainer {
+		net := xorNetwork()
+		net.Layers = append([]ml.Layer{{Type: ml.LayerDropout, Rate: 0.2}}, net.Layers...)
+		opt, _ := ml.NewOptimizer(ml.OptimizerSGD, 0.5)
+		return ml.NewTrainer(net, opt, 7)
+	}
+
+	// the masks come from the trainer's seed, so training is repeatable
+	t1, t2 := newTrainer(), newTrainer()
+	for i := 0; i < 10; i++ {
+		t1.TrainEpoch(x, y)
+		t2.TrainEpoch(x, y)
+	}
+	if !ml.MatrixEquals(t1.Net.Layers[1].Weights, t2.Net.Layers[1].Weights) {
+		t.Errorf("TrainEpoch: expected the same weights from the same seed")
+	}
+	if t1.Net.Training() {
+		t.Errorf("TrainEpoch: expected the network back in evaluation mode")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"./ml"
+)
+
+func pca(args []string) error {
+	fs := flag.NewFlagSet("pca", flag.ExitOnError)
+	dataPath := fs.String("data", "binary.csv", "csv file with a header row")
+	target := fs.String("target", "admit", "column left out of the components and copied to -out, none if empty")
+	features := fs.String("features", "", "comma separated columns to decompose, all but the target if empty")
+	categorical := fs.String("categorical", "", "comma separated feature columns to one-hot encode")
+	standardize := fs.Bool("standardize", true, "standardize the features before decomposing them")
+	numComponents := fs.Int("components", 0, "number of principal components to keep, all if 0")
+	outPath := fs.String("out", "", "csv file to write the components of every row to, - for stdout, followed by the target")
+	fs.Parse(args)
+
+	header, records, err := readCSV(*dataPath)
+	if err != nil {
+		return err
+	}
+	featureNames := splitList(*features)
+	if featureNames == nil {
+		for _, name := range header {
+			if name != *target {
+				featureNames = append(featureNames, name)
+			}
+		}
+	}
+	raw, err := selectColumns(header, records, featureNames)
+	if err != nil {
+		return err
+	}
+
+	// encode and scale the features the way train does
+	columns := make([]ml.Column, len(featureNames))
+	rawColumns := ml.T(raw)
+	for i, name := range featureNames {
+		columns[i].Name = name
+		if contains(splitList(*categorical), name) {
+			columns[i].Categories = ml.Categories(rawColumns[i])
+		}
+	}
+	x, err := ml.Encode(columns, raw)
+	if err != nil {
+		return err
+	}
+	if *standardize {
+		x = ml.FitScaler(x).Transform(x)
+	}
+
+	p, err := ml.FitPCA(x, *numComponents)
+	if err != nil {
+		return err
+	}
+
+	// how much every component explains and what it is made of
+	names := ml.FeatureNames(columns)
+	fmt.Printf("%-10s %12s %10s %10s", "component", "variance", "ratio", "cumulative")
+	for _, name := range names {
+		fmt.Printf(" %10s", name)
+	}
+	fmt.Println()
+	var cumulative float64
+	for i, component := range p.Components {
+		cumulative += p.ExplainedVarianceRatio[i]
+		fmt.Printf("%-10s %12.6g %10.4f %10.4f", componentName(i), p.ExplainedVariance[i], p.ExplainedVarianceRatio[i], cumulative)
+		for _, loading := range component {
+			fmt.Printf(" %10.4f", loading)
+		}
+		fmt.Println()
+	}
+
+	if *outPath == "" {
+		return nil
+	}
+	return writeComponents(*outPath, p.Transform(x), header, records, *target)
+}
+
+// writeComponents writes the components of every row as csv, with the target column if there is one.
+func writeComponents(path string, z [][]float64, header []string, records [][]float64, target string) error {
+	var targets [][]float64
+	if target != "" {
+		var err error
+		if targets, err = selectColumns(header, records, []string{target}); err != nil {
+			return err
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+	names := make([]string, len(z[0]))
+	for i := range names {
+		names[i] = componentName(i)
+	}
+	if targets != nil {
+		names = append(names, target)
+	}
+	w.Write(names)
+	for i, row := range z {
+		if targets != nil {
+			row = append(row, targets[i]...)
+		}
+		record := make([]string, len(row))
+		for j, v := range row {
+			record[j] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// componentName names the i-th principal component, counting from pc1.
+func componentName(i int) string {
+	return "pc" + strconv.Itoa(i+1)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"./ml"
+)
+
+func evaluate(args []string) error {
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	modelPath := fs.String("model", modelFile, "model saved by train")
+	dataPath := fs.String("data", "binary.csv", "csv file with a header row, holding the model's columns and targets")
+	fs.Parse(args)
+
+	model, err := ml.LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	header, records, err := readCSV(*dataPath)
+	if err != nil {
+		return err
+	}
+	x, err := modelInputs(model, header, records)
+	if err != nil {
+		return err
+	}
+	y, err := selectColumns(header, records, model.Targets)
+	if err != nil {
+		return err
+	}
+	if y, err = model.EncodeTargets(y); err != nil {
+		return err
+	}
+
+	yHat := model.Predict(x)
+	lossName := ml.LossMSE
+	if model.Classes != nil {
+		lossName = ml.LossCrossEntropy
+	}
+	loss, _ := ml.Loss(lossName, yHat, y)
+	fmt.Printf("Loss: %v\n", loss)
+	binary, err := isBinary(&model.Network)
+	if err != nil {
+		return err
+	}
+	switch {
+	case model.Classes != nil:
+		fmt.Printf("Prediction accuracy: %v\n", ml.CategoricalAccuracy(yHat, y))
+	case binary:
+		fmt.Printf("Prediction accuracy: %v\n", ml.BinaryAccuracy(yHat, y))
+	}
+
+	return nil
+}
+
+func predict(args []string) error {
+	fs := flag.NewFlagSet("predict", flag.ExitOnError)
+	modelPath := fs.String("model", modelFile, "model saved by train")
+	dataPath := fs.String("data", "-", "csv file with a header row holding the model's columns, - for stdin")
+	outPath := fs.String("out", "-", "csv file to write the predictions to, - for stdout")
+	fs.Parse(args)
+
+	model, err := ml.LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	header, records, err := readCSV(*dataPath)
+	if err != nil {
+		return err
+	}
+	x, err := modelInputs(model, header, records)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	// one column per target, or the class followed by the probability of every class
+	w := csv.NewWriter(out)
+	yHat := model.Predict(x)
+	if model.Classes != nil {
+		target := ml.Column{Name: model.Targets[0], Categories: model.Classes}
+		w.Write(append(model.Targets, ml.FeatureNames([]ml.Column{target})...))
+		for i, class := range model.Classify(yHat) {
+			yHat[i] = append([]float64{class}, yHat[i]...)
+		}
+	} else {
+		w.Write(model.Targets)
+	}
+	for _, row := range yHat {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// modelInputs picks the columns the model was trained on from the records and encodes them.
+func modelInputs(model *ml.Model, header []string, records [][]float64) ([][]float64, error) {
+	names := model.Features
+	if model.Columns != nil {
+		names = nil
+		for _, c := range model.Columns {
+			names = append(names, c.Name)
+		}
+	}
+
+	raw, err := selectColumns(header, records, names)
+	if err != nil {
+		return nil, err
+	}
+	return model.Encode(raw)
+}
+
+// isBinary checks if the network predicts a single probability: its last dense layer has one
+// output, and the last activation, skipping any dropout after it, is a sigmoid. It fails if the
+// network has no dense layer to tell the width of its output.
+func isBinary(net *ml.Network) (bool, error) {
+	outputs := 0
+	for _, l := range net.Layers {
+		if l.Type == ml.LayerDense && len(l.Weights) > 0 {
+			outputs = len(l.Weights[0])
+		}
+	}
+	if outputs == 0 {
+		return false, fmt.Errorf("network has no dense layer to give its outputs")
+	}
+
+	// a normalization after the last activation means the outputs are no probabilities
+	var activation string
+	for i := len(net.Layers) - 1; i >= 0 && activation == ""; i-- {
+		switch l := net.Layers[i]; l.Type {
+		case ml.LayerDense, ml.LayerActivation:
+			activation = l.Activation
+		case ml.LayerBatchNorm, ml.LayerLayerNorm:
+			return false, nil
+		}
+	}
+	return activation == ml.ActivationSigmoid && outputs == 1, nil
+}
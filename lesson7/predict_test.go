@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"./ml"
+)
+
+func TestIsBinary(t *testing.T) {
+	dense := func(outputs int, activation string) ml.Layer {
+		return ml.Layer{Type: ml.LayerDense, Weights: [][]float64{make([]float64, outputs)}, Activation: activation}
+	}
+	tests := []struct {
+		name     string
+		layers   []ml.Layer
+		expected bool
+	}{
+		{"sigmoid output", []ml.Layer{dense(1, ml.ActivationSigmoid)}, true},
+		{"two outputs", []ml.Layer{dense(2, ml.ActivationSigmoid)}, false},
+		{"linear output", []ml.Layer{dense(1, ml.ActivationLinear)}, false},
+		{"activation layer", []ml.Layer{dense(1, ml.ActivationLinear), {Type: ml.LayerActivation, Activation: ml.ActivationSigmoid}}, true},
+		{"dropout last", []ml.Layer{dense(1, ml.ActivationSigmoid), {Type: ml.LayerDropout, Rate: 0.5}}, true},
+		{"normalization last", []ml.Layer{dense(1, ml.ActivationSigmoid), {Type: ml.LayerLayerNorm, Gamma: []float64{1}}}, false},
+	}
+	for _, test := range tests {
+		actual, err := isBinary(&ml.Network{Layers: test.layers})
+		if err != nil {
+			t.Errorf("isBinary(%s) unexpected error: %v", test.name, err)
+		} else if actual != test.expected {
+			t.Errorf("isBinary(%s): expected %v, actual %v", test.name, test.expected, actual)
+		}
+	}
+
+	// without a dense layer the width of the output is unknown
+	net := &ml.Network{Layers: []ml.Layer{{Type: ml.LayerBatchNorm, Gamma: []float64{1}}, {Type: ml.LayerActivation, Activation: ml.ActivationSigmoid}}}
+	if _, err := isBinary(net); err == nil {
+		t.Errorf("isBinary(%v): expected err != nil", net.Layers)
+	}
+}
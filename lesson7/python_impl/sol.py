@@ -70,6 +70,9 @@ for e in range(epochs):
             print("Train loss: ", loss)
         last_loss = loss
 
+# Save the weights so the Go network can load them with -init
+np.savez('weights.npz', w1=weights_input_hidden, w2=weights_hidden_output)
+
 # Calculate accuracy on test data
 hidden = sigmoid(np.dot(features_test, weights_input_hidden))
 out = sigmoid(np.dot(hidden, weights_hidden_output))
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"./ml"
+)
+
+// modelFile is where the trained model is saved by default.
+const modelFile = "model.json"
+
+func train(args []string) error {
+	cfg, resume, initWeights, saveWeights, err := trainConfig(args)
+	if err != nil {
+		return err
+	}
+	return run(cfg, resume, initWeights, saveWeights)
+}
+
+// trainConfig parses the arguments of train into the config of the run, the flags given
+// overriding the config file if any, along with the flags that are not part of the config.
+func trainConfig(args []string) (cfg *Config, resume bool, initWeights, saveWeights string, err error) {
+	defaults := defaultConfig()
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	configFile := fs.String("config", "", "JSON file describing the run, the flags below override it")
+	dataPath := fs.String("data", defaults.Dataset.Path, "csv file with a header row to train on")
+	target := fs.String("target", defaults.Dataset.Target, "column to predict")
+	features := fs.String("features", "", "comma separated columns to use as features, all but the target if empty")
+	categorical := fs.String("categorical", strings.Join(defaults.Preprocessing.Categorical, ","), "comma separated feature columns to one-hot encode")
+	hidden := fs.String("hidden", "4", "comma separated sizes of the hidden layers")
+	activation := fs.String("activation", ml.ActivationSigmoid, "activation of the hidden layers")
+	output := fs.String("output", ml.ActivationSigmoid, "activation of the output layer")
+	norm := fs.String("norm", "", "normalize every hidden layer before its activation: batch_norm or layer_norm")
+	dropout := fs.Float64("dropout", 0, "fraction of the units of every hidden layer dropped while training")
+	l1 := fs.Float64("l1", 0, "L1 penalty on the weights of every layer")
+	l2 := fs.Float64("l2", 0, "L2 penalty on the weights of every layer")
+	weightDecay := fs.Float64("weight-decay", 0, "weight decay of the optimizer, applied to the weights apart from the gradient")
+	optimizer := fs.String("optimizer", defaults.Optimizer.Method, "optimizer: sgd, momentum or adam")
+	schedule := fs.String("schedule", "", "learning rate schedule: constant, step, exponential, cosine or one_cycle")
+	warmup := fs.Int("warmup", 0, "epochs of linear learning rate warmup")
+	decayStep := fs.Int("decay-step", 0, "epochs between step decays, or of the first cosine cycle")
+	gamma := fs.Float64("gamma", 0, "decay factor of the step and exponential schedules")
+	minRate := fs.Float64("min-lr", 0, "learning rate at the end of a cosine or one_cycle schedule")
+	plateau := fs.Int("plateau", 0, "halve the learning rate after this many epochs without a lower val_loss")
+	numEpochs := fs.Int("epochs", defaults.Training.Epochs, "number of passes over the training set")
+	learnRate := fs.Float64("lr", defaults.Optimizer.LearnRate, "learning rate")
+	batchSize := fs.Int("batch", defaults.Training.BatchSize, "rows per gradient step, 0 uses the whole training set")
+	seed := fs.Uint64("seed", defaults.Training.Seed, "seed for the initial weights and the batch order")
+	modelPath := fs.String("model", defaults.Model, "file to save the trained model to, its config goes next to it")
+	testFrac := fs.Float64("test", defaults.Dataset.Test, "fraction of the rows, taken from the end, held out to measure accuracy")
+	checkpointDir := fs.String("checkpoints", "", "directory to save training checkpoints to")
+	checkpointEvery := fs.Int("checkpoint-every", defaults.Checkpoints.Every, "epochs between checkpoints")
+	checkpointKeep := fs.Int("checkpoint-keep", defaults.Checkpoints.Keep, "number of recent checkpoints to keep, 0 keeps all")
+	patience := fs.Int("patience", 10, "stop after this many epochs without improvement of -monitor")
+	monitor := fs.String("monitor", "val_loss", "metric watched by early stopping: loss, val_loss or val_accuracy")
+	minDelta := fs.Float64("min-delta", 0, "smallest change of -monitor that counts as an improvement")
+	restoreBest := fs.Bool("restore-best", true, "keep the weights of the best epoch when stopping early")
+	gradNorm := fs.Float64("grad-norm", 0, "stop once the norm of the gradient is at most this")
+	lossChange := fs.Float64("loss-change", 0, "stop once the training loss changes by at most this fraction from one epoch to the next")
+	paramChange := fs.Float64("param-change", 0, "stop once the weights change by at most this fraction of their norm in an epoch")
+	timeLimit := fs.Duration("time-limit", 0, "stop once training has taken this long, such as 10m")
+	convergencePatience := fs.Int("convergence-patience", 0, "epochs in a row the loss or weight change has to stay small, 1 if 0")
+	resumeFlag := fs.Bool("resume", false, "continue from the latest checkpoint in -checkpoints")
+	initFlag := fs.String("init", "", "start from the w1, w2, ... (and b1, b2, ...) arrays of a NumPy .npz file")
+	loss := fs.String("loss", defaults.Loss, "loss to minimize: mse, or cross_entropy to classify the target with a softmax output")
+	logPath := fs.String("log", "", "file to write the metrics of every epoch to, as csv if it ends in .csv, JSON Lines otherwise")
+	saveFlag := fs.String("save-npz", "", "also save the trained weights to a NumPy .npz file")
+	fs.Parse(args)
+
+	// start from the config file, if any
+	cfg = defaults
+	if *configFile != "" {
+		if cfg, err = loadConfig(*configFile); err != nil {
+			return nil, false, "", "", err
+		}
+	}
+
+	// and apply the flags that were given
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "data":
+			cfg.Dataset.Path = *dataPath
+		case "target":
+			cfg.Dataset.Target = *target
+		case "features":
+			cfg.Dataset.Features = splitList(*features)
+		case "categorical":
+			cfg.Preprocessing.Categorical = splitList(*categorical)
+		case "hidden":
+			var sizes []int
+			for _, s := range splitList(*hidden) {
+				size, e := strconv.Atoi(s)
+				if e != nil {
+					err = fmt.Errorf("bad hidden layer size %q", s)
+				}
+				sizes = append(sizes, size)
+			}
+			if e := setHidden(cfg, sizes); e != nil {
+				err = e
+			}
+		case "optimizer":
+			cfg.Optimizer.Method = *optimizer
+		case "weight-decay":
+			cfg.Optimizer.WeightDecay = *weightDecay
+		case "schedule", "warmup", "decay-step", "gamma", "min-lr":
+			if cfg.Optimizer.Schedule == nil {
+				cfg.Optimizer.Schedule = &ScheduleConfig{Type: ml.ScheduleConstant}
+			}
+			s := cfg.Optimizer.Schedule
+			switch f.Name {
+			case "schedule":
+				s.Type = *schedule
+			case "warmup":
+				s.Warmup = *warmup
+			case "decay-step":
+				s.Step = *decayStep
+			case "gamma":
+				s.Gamma = *gamma
+			case "min-lr":
+				s.MinRate = *minRate
+			}
+		case "plateau":
+			cfg.Optimizer.Plateau = &PlateauConfig{Monitor: "val_" + metricLoss, Factor: 0.5, Patience: *plateau}
+		case "epochs":
+			cfg.Training.Epochs = *numEpochs
+		case "lr":
+			cfg.Optimizer.LearnRate = *learnRate
+		case "batch":
+			cfg.Training.BatchSize = *batchSize
+		case "seed":
+			cfg.Training.Seed = *seed
+		case "model":
+			cfg.Model = *modelPath
+		case "loss":
+			cfg.Loss = *loss
+		case "log":
+			cfg.Log = *logPath
+		case "test":
+			cfg.Dataset.Test = *testFrac
+		case "checkpoints":
+			cfg.Checkpoints.Dir = *checkpointDir
+		case "checkpoint-every":
+			cfg.Checkpoints.Every = *checkpointEvery
+		case "checkpoint-keep":
+			cfg.Checkpoints.Keep = *checkpointKeep
+		case "patience", "monitor", "min-delta", "restore-best":
+			if cfg.EarlyStopping == nil {
+				cfg.EarlyStopping = &EarlyStoppingConfig{Monitor: *monitor, Patience: *patience, RestoreBest: *restoreBest}
+			}
+			switch f.Name {
+			case "patience":
+				cfg.EarlyStopping.Patience = *patience
+			case "monitor":
+				cfg.EarlyStopping.Monitor = *monitor
+			case "min-delta":
+				cfg.EarlyStopping.MinDelta = *minDelta
+			case "restore-best":
+				cfg.EarlyStopping.RestoreBest = *restoreBest
+			}
+		case "grad-norm", "loss-change", "param-change", "time-limit", "convergence-patience":
+			if cfg.Convergence == nil {
+				cfg.Convergence = &ConvergenceConfig{}
+			}
+			switch f.Name {
+			case "grad-norm":
+				cfg.Convergence.GradNorm = *gradNorm
+			case "loss-change":
+				cfg.Convergence.LossChange = *lossChange
+			case "param-change":
+				cfg.Convergence.ParamChange = *paramChange
+			case "time-limit":
+				cfg.Convergence.TimeLimit = timeLimit.String()
+			case "convergence-patience":
+				cfg.Convergence.Patience = *convergencePatience
+			}
+		}
+	})
+	if err != nil {
+		return nil, false, "", "", err
+	}
+
+	// activations, penalties, dropout and normalization go last, so they apply to the layers set by
+	// -hidden (flags are visited in alphabetical order)
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "activation":
+			for i := 0; i < len(cfg.Layers)-1; i++ {
+				if cfg.Layers[i].dense() || cfg.Layers[i].Type == ml.LayerActivation {
+					cfg.Layers[i].Activation = *activation
+				}
+			}
+		case "output":
+			if len(cfg.Layers) > 0 {
+				cfg.Layers[len(cfg.Layers)-1].Activation = *output
+			}
+		case "l1", "l2":
+			for i := range cfg.Layers {
+				if !cfg.Layers[i].dense() {
+					continue
+				}
+				if cfg.Layers[i].Penalty == nil {
+					cfg.Layers[i].Penalty = &ml.Penalty{}
+				}
+				if f.Name == "l1" {
+					cfg.Layers[i].Penalty.L1 = *l1
+				} else {
+					cfg.Layers[i].Penalty.L2 = *l2
+				}
+			}
+		case "dropout":
+			// replace any dropout layers with one after every hidden layer
+			var layers []LayerConfig
+			for i, l := range cfg.Layers {
+				if l.Type == ml.LayerDropout {
+					continue
+				}
+				layers = append(layers, l)
+				if i != len(cfg.Layers)-1 && *dropout > 0 {
+					layers = append(layers, LayerConfig{Type: ml.LayerDropout, Rate: *dropout})
+				}
+			}
+			cfg.Layers = layers
+		case "norm":
+			// split every hidden dense layer into a linear one, the normalization and the activation
+			var layers []LayerConfig
+			for i, l := range cfg.Layers {
+				if !l.dense() || i == len(cfg.Layers)-1 {
+					layers = append(layers, l)
+					continue
+				}
+				activation := l.Activation
+				l.Activation = ml.ActivationLinear
+				layers = append(layers, l, LayerConfig{Type: *norm}, LayerConfig{Type: ml.LayerActivation, Activation: activation})
+			}
+			cfg.Layers = layers
+		}
+	})
+
+	if err := cfg.validate(); err != nil {
+		return nil, false, "", "", err
+	}
+	if *resumeFlag && cfg.Checkpoints.Dir == "" {
+		return nil, false, "", "", fmt.Errorf("-resume needs a checkpoint directory")
+	}
+	return cfg, *resumeFlag, *initFlag, *saveFlag, nil
+}
+
+// setHidden gives the config hidden dense layers of the given sizes. The i-th size goes to the
+// i-th hidden dense layer of the config, which keeps its activation, penalties and the dropout,
+// normalization and activation layers after it; any sizes beyond those repeat the last hidden
+// layer, or make sigmoid layers if there is none. The output layer stays as it is.
+func setHidden(cfg *Config, sizes []int) error {
+	if len(cfg.Layers) == 0 {
+		return fmt.Errorf("-hidden needs the output layer of the config, which has no layers")
+	}
+	output := cfg.Layers[len(cfg.Layers)-1]
+
+	// group every hidden dense layer with the layers that follow it
+	var lead []LayerConfig
+	var groups [][]LayerConfig
+	for _, l := range cfg.Layers[:len(cfg.Layers)-1] {
+		switch {
+		case l.dense():
+			groups = append(groups, []LayerConfig{l})
+		case groups == nil:
+			lead = append(lead, l)
+		default:
+			groups[len(groups)-1] = append(groups[len(groups)-1], l)
+		}
+	}
+	if groups == nil {
+		groups = [][]LayerConfig{{{Activation: ml.ActivationSigmoid}}}
+	}
+
+	layers := append([]LayerConfig(nil), lead...)
+	for i, size := range sizes {
+		group := groups[len(groups)-1]
+		if i < len(groups) {
+			group = groups[i]
+		}
+		dense := group[0]
+		dense.Size = size
+		if dense.Penalty != nil {
+			penalty := *dense.Penalty
+			dense.Penalty = &penalty
+		}
+		if dense.BiasPenalty != nil {
+			penalty := *dense.BiasPenalty
+			dense.BiasPenalty = &penalty
+		}
+		layers = append(append(layers, dense), group[1:]...)
+	}
+	cfg.Layers = append(layers, output)
+	return nil
+}
+
+// run trains a network as described by the config, resuming from the latest checkpoint if asked to,
+// and saves the model and the config next to each other.
+func run(cfg *Config, resume bool, initWeights, saveWeights string) error {
+	// read data from the csv
+	header, records, err := readCSV(cfg.Dataset.Path)
+	if err != nil {
+		return err
+	}
+	featureNames := cfg.Dataset.Features
+	if featureNames == nil {
+		for _, name := range header {
+			if name != cfg.Dataset.Target {
+				featureNames = append(featureNames, name)
+			}
+		}
+	}
+	raw, err := selectColumns(header, records, featureNames)
+	if err != nil {
+		return err
+	}
+	targets, err := selectColumns(header, records, []string{cfg.Dataset.Target})
+	if err != nil {
+		return err
+	}
+
+	// a classifier has an output per class of the target
+	var classes []float64
+	if cfg.classifier() {
+		target := ml.Column{Name: cfg.Dataset.Target, Categories: ml.Categories(ml.T(targets)[0])}
+		if targets, err = ml.Encode([]ml.Column{target}, targets); err != nil {
+			return err
+		}
+		classes = target.Categories
+	}
+
+	// split categorical columns into dummy features
+	columns := make([]ml.Column, len(featureNames))
+	rawColumns := ml.T(raw)
+	for i, name := range featureNames {
+		columns[i].Name = name
+		if contains(cfg.Preprocessing.Categorical, name) {
+			columns[i].Categories = ml.Categories(rawColumns[i])
+		}
+	}
+	for _, name := range cfg.Preprocessing.Categorical {
+		if !contains(featureNames, name) {
+			return fmt.Errorf("categorical column %q is not in %s", name, cfg.Dataset.Path)
+		}
+	}
+	inputs, err := ml.Encode(columns, raw)
+	if err != nil {
+		return err
+	}
+
+	// standardize the numeric features
+	var scaler *ml.Scaler
+	if cfg.Preprocessing.Standardize {
+		if numeric := numericFeatures(columns); len(numeric) > 0 {
+			scaler = ml.FitScaler(inputs, numeric...)
+			inputs = scaler.Transform(inputs)
+		}
+	}
+
+	// split dataset
+	xTrain, xTest := ml.SplitMatrix(inputs, float32(1-cfg.Dataset.Test))
+	yTrain, yTest := ml.SplitMatrix(targets, float32(1-cfg.Dataset.Test))
+
+	// keep part of the training set aside to pick the best checkpoint
+	xTrain, xVal := ml.SplitMatrix(xTrain, float32(1-cfg.Dataset.Validation))
+	yTrain, yVal := ml.SplitMatrix(yTrain, float32(1-cfg.Dataset.Validation))
+
+	if len(xTrain) == 0 {
+		return fmt.Errorf("%s: not enough rows to train on", cfg.Dataset.Path)
+	}
+
+	// initialize weights
+	net, err := buildNetwork(cfg, len(xTrain[0]), len(yTrain[0]))
+	if err != nil {
+		return err
+	}
+
+	// record what the defaults resolved to
+	cfg.Dataset.Features = featureNames
+	cfg.Layers[len(cfg.Layers)-1].Size = len(yTrain[0])
+	if initWeights != "" {
+		if err := loadWeights(initWeights, net); err != nil {
+			return err
+		}
+	}
+	opt, err := ml.NewOptimizer(cfg.Optimizer.Method, cfg.Optimizer.LearnRate)
+	if err != nil {
+		return err
+	}
+	if cfg.Optimizer.Momentum != 0 {
+		opt.Momentum = cfg.Optimizer.Momentum
+	}
+	opt.WeightDecay = cfg.Optimizer.WeightDecay
+	trainer := ml.NewTrainer(net, opt, cfg.Training.Seed)
+	trainer.Loss = cfg.Loss
+	trainer.BatchSize = cfg.Training.BatchSize
+	trainer.Schedule = cfg.schedule()
+	trainer.Convergence = cfg.convergence()
+
+	// pick up where the last run stopped
+	var checkpoints *ml.Checkpointer
+	if cfg.Checkpoints.Dir != "" {
+		checkpoints = &ml.Checkpointer{Dir: cfg.Checkpoints.Dir, Every: cfg.Checkpoints.Every, Keep: cfg.Checkpoints.Keep}
+	}
+	if resume {
+		path, err := ml.LatestCheckpoint(cfg.Checkpoints.Dir)
+		if err != nil {
+			return err
+		}
+		ckpt, err := ml.LoadCheckpoint(path)
+		if err != nil {
+			return err
+		}
+		if err := trainer.Restore(ckpt); err != nil {
+			return err
+		}
+		fmt.Printf("Resuming from %v at epoch %v\n", path, trainer.Epoch)
+	}
+
+	// measure and log every epoch
+	printEvery := cfg.Training.Epochs / 10
+	if printEvery == 0 {
+		printEvery = 1
+	}
+	callbacks := []ml.Callback{&ml.ProgressLogger{W: os.Stdout, Every: printEvery}}
+	if trainer.Metrics, err = metricFuncs(cfg, net); err != nil {
+		return err
+	}
+	if p := cfg.Optimizer.Plateau; p != nil {
+		callbacks = append(callbacks, &ml.ReduceOnPlateau{
+			Monitor:  p.Monitor,
+			Mode:     ml.ModeMin,
+			Factor:   p.Factor,
+			Patience: p.Patience,
+			MinDelta: p.MinDelta,
+			MinRate:  p.MinRate,
+		})
+	}
+	var stopping *ml.EarlyStopping
+	if e := cfg.EarlyStopping; e != nil {
+		stopping = &ml.EarlyStopping{
+			Monitor:     e.Monitor,
+			Mode:        e.mode(),
+			Patience:    e.Patience,
+			MinDelta:    e.MinDelta,
+			RestoreBest: e.RestoreBest,
+		}
+		callbacks = append(callbacks, stopping)
+	}
+	if cfg.Log != "" {
+		f, columns, err := openLog(cfg.Log, resume)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if strings.HasSuffix(cfg.Log, ".csv") {
+			callbacks = append(callbacks, &ml.CSVLogger{W: f, Columns: columns})
+		} else {
+			callbacks = append(callbacks, &ml.JSONLLogger{W: f})
+		}
+	}
+	if checkpoints != nil {
+		// last, so that the checkpoints hold the state the other callbacks reached in the epoch
+		callbacks = append(callbacks, checkpoints)
+	}
+
+	// start training
+	if err := trainer.Fit(xTrain, yTrain, xVal, yVal, cfg.Training.Epochs, callbacks...); err != nil {
+		return err
+	}
+	if trainer.Result.Reason == ml.StopDiverged {
+		return fmt.Errorf("training diverged in epoch %v, lower the learning rate", trainer.Epoch)
+	}
+	if stopping != nil && stopping.StoppedEpoch != 0 {
+		fmt.Printf("Stopped early at epoch %v, best %v %v at epoch %v\n",
+			stopping.StoppedEpoch, stopping.Monitor, stopping.Best, stopping.BestEpoch)
+	}
+	if r := trainer.Result; trainer.Convergence != nil || r.Reason != ml.StopMaxSteps {
+		fmt.Printf("Stopped by %v after %v epochs in %v\n", r.Reason, r.Steps, r.Elapsed.Round(time.Millisecond))
+	}
+
+	// save the trained model and how it was trained
+	model := &ml.Model{
+		Network:  *net,
+		Columns:  columns,
+		Scaler:   scaler,
+		Features: ml.FeatureNames(columns),
+		Targets:  []string{cfg.Dataset.Target},
+		Classes:  classes,
+		Training: ml.TrainingInfo{
+			Epochs:     trainer.Epoch,
+			LearnRate:  cfg.Optimizer.LearnRate,
+			Loss:       trainer.Evaluate(xTrain, yTrain),
+			TrainedAt:  time.Now().UTC(),
+			StopReason: trainer.Result.Reason,
+		},
+	}
+	if err := ml.SaveModel(cfg.Model, model); err != nil {
+		return err
+	}
+	if err := cfg.save(configPath(cfg.Model)); err != nil {
+		return err
+	}
+	fmt.Printf("Model saved to %v, config to %v\n", cfg.Model, configPath(cfg.Model))
+	if saveWeights != "" {
+		if err := saveNpz(saveWeights, net); err != nil {
+			return err
+		}
+	}
+
+	// report the metrics on test data (already standardized, so skip the scaler)
+	if len(xTest) > 0 {
+		yHat := net.Predict(xTest)
+		metrics, err := metricFuncs(cfg, net)
+		if err != nil {
+			return err
+		}
+		for _, metric := range cfg.Metrics {
+			switch f, ok := metrics[metric]; {
+			case metric == metricLoss:
+				fmt.Printf("Test loss: %v\n", trainer.Evaluate(xTest, yTest))
+			case metric == metricAccuracy && ok:
+				fmt.Printf("Prediction accuracy: %v\n", f(yHat, yTest))
+			case ok:
+				fmt.Printf("Top-%d accuracy: %v\n", topK(metric), f(yHat, yTest))
+			}
+		}
+	}
+
+	return nil
+}
+
+// metricFuncs returns the functions of the metrics of the config that apply to the network,
+// keyed by name. The loss is measured by the trainer.
+func metricFuncs(cfg *Config, net *ml.Network) (map[string]ml.MetricFunc, error) {
+	binary, err := isBinary(net)
+	if err != nil {
+		return nil, err
+	}
+	funcs := map[string]ml.MetricFunc{}
+	for _, name := range cfg.Metrics {
+		switch {
+		case name == metricAccuracy && cfg.classifier():
+			funcs[name] = ml.CategoricalAccuracy
+		case name == metricAccuracy && binary:
+			funcs[name] = ml.BinaryAccuracy
+		case topK(name) > 0 && cfg.classifier():
+			funcs[name] = ml.TopKAccuracy(topK(name))
+		}
+	}
+	return funcs, nil
+}
+
+// buildNetwork creates the layers of the config for the given number of inputs and outputs.
+func buildNetwork(cfg *Config, numInputs, numOutputs int) (*ml.Network, error) {
+	sizes := []int{numInputs}
+	for _, l := range cfg.Layers {
+		if l.dense() {
+			sizes = append(sizes, l.Size)
+		}
+	}
+	if last := len(sizes) - 1; sizes[last] == 0 {
+		sizes[last] = numOutputs
+	} else if sizes[last] != numOutputs {
+		return nil, fmt.Errorf("output layer has %d units for %d targets", sizes[last], numOutputs)
+	}
+
+	// draw the dense layers, then put the other layers between them
+	dense := ml.NewNetwork(sizes, ml.ActivationLinear, ml.ActivationLinear, cfg.Training.Seed).Layers
+	net := &ml.Network{}
+	units := numInputs
+	for _, l := range cfg.Layers {
+		switch l.Type {
+		case ml.LayerDropout:
+			net.Layers = append(net.Layers, ml.Layer{Type: ml.LayerDropout, Rate: l.Rate})
+		case ml.LayerBatchNorm:
+			net.Layers = append(net.Layers, ml.NewBatchNorm(units))
+		case ml.LayerLayerNorm:
+			net.Layers = append(net.Layers, ml.NewLayerNorm(units))
+		case ml.LayerActivation:
+			net.Layers = append(net.Layers, ml.Layer{Type: ml.LayerActivation, Activation: l.Activation})
+		default:
+			layer := dense[0]
+			dense = dense[1:]
+			layer.Activation = l.Activation
+			layer.Penalty = l.Penalty
+			layer.BiasPenalty = l.BiasPenalty
+			net.Layers = append(net.Layers, layer)
+			units = len(layer.Weights[0])
+		}
+	}
+	return net, net.Validate()
+}
+
+// loadWeights replaces the weights of the dense layers of the network with the w1, w2, ... arrays
+// of a NumPy .npz file, as saved by python_impl/sol.py. Layers without a b1, b2, ... array lose
+// their bias, like the networks of python_impl.
+func loadWeights(path string, net *ml.Network) error {
+	arrays, err := ml.LoadNpz(path)
+	if err != nil {
+		return err
+	}
+
+	k := 0 // numbers the dense layers
+	for i := range net.Layers {
+		if net.Layers[i].Type != ml.LayerDense {
+			continue
+		}
+		k++
+		name := fmt.Sprintf("w%d", k)
+		w, ok := arrays[name]
+		if !ok {
+			return fmt.Errorf("%s: no %s array", path, name)
+		}
+
+		// numpy keeps the output weights as a vector, which reads as a single row
+		want := net.Layers[i].Weights
+		if len(w) == 1 && len(want) != 1 {
+			w = ml.T(w)
+		}
+		if len(w) != len(want) || len(w[0]) != len(want[0]) {
+			return fmt.Errorf("%s: %s is %dx%d, expected %dx%d", path, name, len(w), len(w[0]), len(want), len(want[0]))
+		}
+		net.Layers[i].Weights = w
+
+		net.Layers[i].Bias = nil
+		if b, ok := arrays[fmt.Sprintf("b%d", k)]; ok {
+			if len(b) != 1 || len(b[0]) != len(w[0]) {
+				return fmt.Errorf("%s: b%d does not match %s", path, k, name)
+			}
+			net.Layers[i].Bias = b[0]
+		}
+	}
+
+	return nil
+}
+
+// saveNpz saves the weights and biases of the dense layers of the network as w1, b1, w2, b2, ...
+// in a NumPy .npz file.
+func saveNpz(path string, net *ml.Network) error {
+	arrays := map[string][][]float64{}
+	k := 0
+	for _, l := range net.Layers {
+		if l.Type != ml.LayerDense {
+			continue
+		}
+		k++
+		arrays[fmt.Sprintf("w%d", k)] = l.Weights
+		if l.Bias != nil {
+			arrays[fmt.Sprintf("b%d", k)] = [][]float64{l.Bias}
+		}
+	}
+	return ml.SaveNpz(path, arrays, ml.NpyFloat64)
+}
+
+// openLog opens the training log for writing. When resuming it appends to the log, so that the
+// epochs before the checkpoint stay in it, and returns the columns of the header of a csv log if
+// it has one already.
+func openLog(path string, resume bool) (f *os.File, columns []string, err error) {
+	if !resume {
+		f, err = os.Create(path)
+		return f, nil, err
+	}
+	if strings.HasSuffix(path, ".csv") {
+		if columns, err = logColumns(path); err != nil {
+			return nil, nil, err
+		}
+	}
+	f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	return f, columns, err
+}
+
+// logColumns returns the metric columns of the header of a csv log, or nil if there is no log or
+// it is empty.
+func logColumns(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := csv.NewReader(f).Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(header) == 0 || header[0] != "epoch" {
+		return nil, fmt.Errorf("%s: expected a header starting with epoch, actual %q", path, header)
+	}
+	return header[1:], nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"./ml"
+)
+
+func TestOpenLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a new run replaces the log, a resumed one appends to it
+	path := filepath.Join(dir, "log.csv")
+	write := func(resume bool, epoch int) {
+		f, columns, err := openLog(path, resume)
+		if err != nil {
+			t.Fatalf("openLog(resume %v) unexpected error: %v", resume, err)
+		}
+		defer f.Close()
+		logger := &ml.CSVLogger{W: f, Columns: columns}
+		if err := logger.OnEpochEnd(&ml.Trainer{Epoch: epoch}, ml.Metrics{"loss": 0.5, "lr": 0.1}); err != nil {
+			t.Fatal("OnEpochEnd unexpected error:", err)
+		}
+	}
+	write(false, 1)
+	write(false, 1)
+	write(true, 2)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "epoch,loss,lr\n1,0.5,0.1\n2,0.5,0.1\n"; string(data) != expected {
+		t.Errorf("openLog: expected %q, actual %q", expected, data)
+	}
+
+	// a missing or empty log gets a header
+	for _, name := range []string{"missing.csv", "empty.csv"} {
+		path := filepath.Join(dir, name)
+		if name == "empty.csv" {
+			if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, columns, err := openLog(path, true)
+		if err != nil || columns != nil {
+			t.Errorf("openLog(%s): expected no columns, actual %v (err %v)", name, columns, err)
+		}
+		if f != nil {
+			f.Close()
+		}
+	}
+
+	// a csv log without an epoch column is not one to append to
+	path = filepath.Join(dir, "other.csv")
+	if err := ioutil.WriteFile(path, []byte("loss,lr\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openLog(path, true); err == nil {
+		t.Errorf("openLog(other.csv): expected err != nil")
+	}
+
+	// a jsonl log has no header to read
+	path = filepath.Join(dir, "log.jsonl")
+	if err := ioutil.WriteFile(path, []byte(`{"epoch":1}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, columns, err := openLog(path, true)
+	if err != nil || columns != nil {
+		t.Errorf("openLog(log.jsonl): expected no columns, actual %v (err %v)", columns, err)
+	}
+	f.Close()
+	if info, err := os.Stat(path); err != nil || info.Size() != int64(len(`{"epoch":1}`+"\n")) {
+		t.Errorf("openLog(log.jsonl): expected the log kept, actual %v (err %v)", info, err)
+	}
+}
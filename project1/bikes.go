@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"../lesson7/ml"
+)
+
+// The bike-sharing data is prepared as in the original notebook of the project: categorical fields
+// become dummy features, a few fields are dropped and the quantitative ones are standardized.
+var (
+	targetFields = []string{"cnt", "casual", "registered"}
+	dummyFields  = []string{"season", "weathersit", "mnth", "hr", "weekday"}
+	dropFields   = []string{"instant", "dteday", "atemp", "workingday"}
+	quantFields  = []string{"temp", "hum", "windspeed"}
+)
+
+func main() {
+	data := flag.String("data", "Bike-Sharing-Dataset/hour.csv", "csv file of daily or hourly rides")
+	hidden := flag.Int("hidden", 10, "number of hidden units")
+	epochs := flag.Int("epochs", 100, "number of training epochs")
+	learnRate := flag.Float64("lr", 0.005, "learning rate")
+	batchSize := flag.Int("batch", 128, "rows per gradient step")
+	seed := flag.Uint64("seed", 42, "random seed for weights and batches")
+	testDays := flag.Int("test-days", 21, "last days held out to test the network")
+	valDays := flag.Int("val-days", 60, "last days before the test set held out for validation")
+	modelPath := flag.String("model", "bikes.json", "where to save the trained model")
+	flag.Parse()
+
+	if err := run(*data, *hidden, *epochs, *learnRate, *batchSize, *seed, *testDays, *valDays, *modelPath); err != nil {
+		fmt.Fprintln(os.Stderr, "bikes:", err)
+		os.Exit(1)
+	}
+}
+
+func run(data string, hidden, epochs int, learnRate float64, batchSize int, seed uint64, testDays, valDays int, modelPath string) error {
+	header, records, err := readRides(data)
+	if err != nil {
+		return err
+	}
+	columns, inputs, targets, quant, err := prepareRides(header, records)
+	if err != nil {
+		return fmt.Errorf("%s: %v", data, err)
+	}
+
+	// standardize the quantitative features and all the targets
+	scaler := ml.FitScaler(inputs, quant...)
+	inputs = scaler.Transform(inputs)
+	targetScaler := ml.FitScaler(targets)
+	targets = targetScaler.Transform(targets)
+
+	// test on the last days and validate on the days before them
+	split, testSplit, err := splitDays(len(inputs), rowsPerDay(header), testDays, valDays)
+	if err != nil {
+		return fmt.Errorf("%s: %v", data, err)
+	}
+	xTrain, xVal, xTest := inputs[:split], inputs[split:testSplit], inputs[testSplit:]
+	yTrain, yVal, yTest := targets[:split], targets[split:testSplit], targets[testSplit:]
+
+	// train a regression network with an output per target
+	net := ml.NewNetwork([]int{len(xTrain[0]), hidden, len(targetFields)}, ml.ActivationSigmoid, ml.ActivationLinear, seed)
+	opt, err := ml.NewOptimizer(ml.OptimizerSGD, learnRate)
+	if err != nil {
+		return err
+	}
+	trainer := ml.NewTrainer(net, opt, seed)
+	trainer.BatchSize = batchSize
+	printEvery := epochs / 10
+	if printEvery == 0 {
+		printEvery = 1
+	}
+	if err := trainer.Fit(xTrain, yTrain, xVal, yVal, epochs, &ml.ProgressLogger{W: os.Stdout, Every: printEvery}); err != nil {
+		return err
+	}
+
+	model := &ml.Model{
+		Network:      *net,
+		Columns:      columns,
+		Scaler:       scaler,
+		Features:     ml.FeatureNames(columns),
+		Targets:      targetFields,
+		TargetScaler: targetScaler,
+		Training: ml.TrainingInfo{
+			Epochs:    trainer.Epoch,
+			LearnRate: learnRate,
+			Loss:      trainer.Evaluate(xTrain, yTrain),
+			TrainedAt: time.Now().UTC(),
+		},
+	}
+	if err := ml.SaveModel(modelPath, model); err != nil {
+		return err
+	}
+	fmt.Printf("Model saved to %v\n", modelPath)
+
+	// report the error of every target, standardized and in riders
+	yHat := net.Predict(xTest)
+	riders, ridersHat := targetScaler.InverseTransform(yTest), targetScaler.InverseTransform(yHat)
+	fmt.Printf("Test loss: %v\n", trainer.Evaluate(xTest, yTest))
+	fmt.Printf("%-12s %10s %10s %10s\n", "target", "mse", "rmse", "mae")
+	for j, name := range targetFields {
+		var mse, sse, sae float64
+		for i := range yTest {
+			mse += math.Pow(yHat[i][j]-yTest[i][j], 2)
+			sse += math.Pow(ridersHat[i][j]-riders[i][j], 2)
+			sae += math.Abs(ridersHat[i][j] - riders[i][j])
+		}
+		n := float64(len(yTest))
+		fmt.Printf("%-12s %10.4f %10.2f %10.2f\n", name, mse/n, math.Sqrt(sse/n), sae/n)
+	}
+
+	return nil
+}
+
+// prepareRides splits the fields into features and targets, dummy encoding the categorical
+// features, and returns the feature columns, the encoded features, the targets and the indices
+// of the encoded quantitative features, which are left to standardize.
+func prepareRides(header []string, records [][]float64) (columns []ml.Column, inputs, targets [][]float64, quant []int, err error) {
+	var featureIndices []int
+	for i, name := range header {
+		if contains(targetFields, name) {
+			continue
+		}
+		c := ml.Column{Name: name}
+		if contains(dummyFields, name) {
+			c.Categories = ml.Categories(ml.T(records)[i])
+		}
+		columns = append(columns, c)
+		featureIndices = append(featureIndices, i)
+	}
+	targetIndices, err := indices(header, targetFields)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if inputs, err = ml.Encode(columns, selectColumns(records, featureIndices)); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	index := 0
+	for _, c := range columns {
+		if contains(quantFields, c.Name) {
+			quant = append(quant, index)
+		}
+		if c.Categories == nil {
+			index++
+		} else {
+			index += len(c.Categories)
+		}
+	}
+	return columns, inputs, selectColumns(records, targetIndices), quant, nil
+}
+
+// rowsPerDay returns the rows of a day: hourly data has a row per hour of the day.
+func rowsPerDay(header []string) int {
+	if contains(header, "hr") {
+		return 24
+	}
+	return 1
+}
+
+// splitDays returns where the validation rows and the test rows start, holding out the last
+// testDays days for testing and the valDays days before them for validation.
+func splitDays(rows, rowsPerDay, testDays, valDays int) (split, testSplit int, err error) {
+	numTest, numVal := testDays*rowsPerDay, valDays*rowsPerDay
+	if numTest+numVal >= rows {
+		return 0, 0, fmt.Errorf("not enough days to hold out %d for testing and %d for validation", testDays, valDays)
+	}
+	return rows - numTest - numVal, rows - numTest, nil
+}
+
+// readRides reads the rides csv, skipping the fields the network doesn't use (the date being
+// the only one that isn't a number), and returns the header and the records of the rest.
+func readRides(path string) ([]string, [][]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	rawRecords, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rawRecords) < 2 {
+		return nil, nil, fmt.Errorf("%s: no rides", path)
+	}
+
+	// keep the fields that aren't dropped
+	var header []string
+	var keep []int
+	for i, name := range rawRecords[0] {
+		if !contains(dropFields, name) {
+			header = append(header, name)
+			keep = append(keep, i)
+		}
+	}
+	kept := make([][]string, len(rawRecords)-1)
+	for i, raw := range rawRecords[1:] {
+		kept[i] = make([]string, len(keep))
+		for j, k := range keep {
+			if k >= len(raw) {
+				return nil, nil, fmt.Errorf("%s: line %d: missing field %q", path, i+2, header[j])
+			}
+			kept[i][j] = raw[k]
+		}
+	}
+
+	records, err := ml.MatrixParseFloat(kept)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return header, records, nil
+}
+
+// indices returns where each of the names is in the header.
+func indices(header, names []string) ([]int, error) {
+	result := make([]int, len(names))
+	for i, name := range names {
+		result[i] = -1
+		for j, h := range header {
+			if h == name {
+				result[i] = j
+			}
+		}
+		if result[i] < 0 {
+			return nil, fmt.Errorf("no column %q in %v", name, header)
+		}
+	}
+	return result, nil
+}
+
+// selectColumns returns the given columns of the records, in the given order.
+func selectColumns(records [][]float64, indices []int) [][]float64 {
+	result := make([][]float64, len(records))
+	for i, record := range records {
+		result[i] = make([]float64, len(indices))
+		for j, index := range indices {
+			result[i][j] = record[index]
+		}
+	}
+	return result
+}
+
+// contains checks if the list has the given item.
+func contains(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
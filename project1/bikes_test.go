@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"../lesson7/ml"
+)
+
+// dayRides is the start of day.csv.
+const dayRides = `instant,dteday,season,yr,mnth,holiday,weekday,workingday,weathersit,temp,atemp,hum,windspeed,casual,registered,cnt
+1,2011-01-01,1,0,1,0,6,0,2,0.344167,0.363625,0.805833,0.160446,331,654,985
+2,2011-01-02,1,0,1,0,0,0,2,0.363478,0.353739,0.696087,0.248539,131,670,801
+3,2011-01-03,1,0,1,0,1,1,1,0.196364,0.189405,0.437273,0.248309,120,1229,1349
+4,2011-01-04,1,0,1,0,2,1,1,0.2,0.212122,0.590435,0.160296,108,1454,1562
+5,2011-01-05,1,0,1,0,3,1,1,0.226957,0.22927,0.436957,0.1869,82,1518,1600
+6,2011-01-06,1,0,1,0,4,1,1,0.204348,0.233209,0.518261,0.0895652,88,1518,1606
+7,2011-01-07,1,0,1,0,5,1,2,0.196522,0.208839,0.498696,0.168726,148,1362,1510
+`
+
+func TestPrepareRides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bikes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "day.csv")
+	if err := ioutil.WriteFile(path, []byte(dayRides), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the dropped fields are gone, the date among them
+	header, records, err := readRides(path)
+	if err != nil {
+		t.Fatal("readRides unexpected error:", err)
+	}
+	expectedHeader := []string{"season", "yr", "mnth", "holiday", "weekday", "weathersit", "temp", "hum", "windspeed", "casual", "registered", "cnt"}
+	if !reflect.DeepEqual(expectedHeader, header) || len(records) != 7 {
+		t.Errorf("readRides: expected 7 records of %v, actual %d of %v", expectedHeader, len(records), header)
+	}
+
+	columns, inputs, targets, quant, err := prepareRides(header, records)
+	if err != nil {
+		t.Fatal("prepareRides unexpected error:", err)
+	}
+
+	// season, month, weekday and weather are dummies of the values seen, the rest single features
+	names := ml.FeatureNames(columns)
+	if len(names) != 16 || len(inputs[0]) != 16 {
+		t.Fatalf("prepareRides: expected 16 features, actual %v and %d inputs", names, len(inputs[0]))
+	}
+	for _, c := range columns {
+		if expected := map[string]int{"season": 1, "mnth": 1, "weekday": 7, "weathersit": 2}[c.Name]; len(c.Categories) != expected {
+			t.Errorf("prepareRides: expected %d categories of %s, actual %v", expected, c.Name, c.Categories)
+		}
+	}
+	if row := inputs[0]; row[4+6] != 1 || ml.Sum(row[4:11]) != 1 || row[12] != 1 || row[11] != 0 {
+		t.Errorf("prepareRides: expected weekday 6 and weather 2 in the dummies of %v, actual %v", names, row)
+	}
+
+	// the scaled columns are the quantitative ones
+	var quantNames []string
+	for _, i := range quant {
+		quantNames = append(quantNames, names[i])
+	}
+	if !reflect.DeepEqual([]string{"temp", "hum", "windspeed"}, quantNames) {
+		t.Errorf("prepareRides: expected to scale temp, hum and windspeed, actual %v", quantNames)
+	}
+	if !ml.ArrayEquals([]float64{985, 331, 654}, targets[0]) {
+		t.Errorf("prepareRides: expected targets cnt, casual and registered [985 331 654], actual %v", targets[0])
+	}
+
+	if _, _, _, _, err := prepareRides(header[:len(header)-1], ml.T(ml.T(records)[:len(header)-1])); err == nil || !strings.Contains(err.Error(), "cnt") {
+		t.Errorf("prepareRides: expected an error about the missing cnt, actual %v", err)
+	}
+}
+
+func TestSplitDays(t *testing.T) {
+	tests := []struct {
+		header           []string
+		rows, test, val  int
+		split, testSplit int
+	}{
+		{[]string{"season", "cnt"}, 731, 21, 60, 650, 710},
+		{[]string{"season", "hr", "cnt"}, 24 * 100, 2, 3, 24 * 95, 24 * 98},
+	}
+	for _, test := range tests {
+		split, testSplit, err := splitDays(test.rows, rowsPerDay(test.header), test.test, test.val)
+		if err != nil {
+			t.Errorf("splitDays(%v) unexpected error: %v", test.header, err)
+		} else if split != test.split || testSplit != test.testSplit {
+			t.Errorf("splitDays(%v, %d rows): expected %d and %d, actual %d and %d", test.header, test.rows, test.split, test.testSplit, split, testSplit)
+		}
+	}
+	if _, _, err := splitDays(80, 1, 21, 60); err == nil {
+		t.Errorf("splitDays: expected err != nil for 81 days held out of 80")
+	}
+}